@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	_ "github.com/lib/pq"
+
+	"GoFaas/internal/autoscaler"
+	"GoFaas/internal/config"
+	"GoFaas/internal/core/registry"
+	"GoFaas/internal/messaging"
+	"GoFaas/internal/observability/logging"
+	"GoFaas/internal/storage/metadata"
+	"GoFaas/pkg/types"
+)
+
+// autoscaler runs worker processes on this host, as many as the backlog of
+// the execution queues calls for. The workers get the autoscaler's
+// environment, so WORKER_* settings apply to them.
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize logger
+	logger := logging.NewSimpleLogger()
+	logger.Info("Starting FaaS Autoscaler")
+
+	// Initialize database
+	db, err := sql.Open("postgres", cfg.Database.GetDSN())
+	if err != nil {
+		logger.Error("Failed to connect to database", logging.F("error", err))
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		logger.Error("Failed to ping database", logging.F("error", err))
+		os.Exit(1)
+	}
+	logger.Info("Database connection established")
+
+	// Initialize Redis
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	defer redisClient.Close()
+
+	ctx := context.Background()
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		logger.Error("Failed to connect to Redis", logging.F("error", err))
+		os.Exit(1)
+	}
+	logger.Info("Redis connection established")
+
+	// Initialize repositories
+	metadataRepo := metadata.NewPostgresRepository(db)
+
+	// Initialize message queue
+	var queue messaging.Queue
+	switch cfg.Queue.Type {
+	case "redis":
+		queue = messaging.NewRedisQueue(redisClient, "faas", cfg.Queue.VisibilityTimeout)
+	case "redis-streams":
+		queue = messaging.NewRedisStreamQueue(redisClient, "faas", "autoscaler", cfg.Queue.VisibilityTimeout)
+	case "postgres":
+		pgQueue, err := messaging.NewPostgresQueue(db, cfg.Database.GetDSN(), "autoscaler", cfg.Queue.VisibilityTimeout)
+		if err != nil {
+			logger.Error("Failed to initialize PostgreSQL queue", logging.F("error", err))
+			os.Exit(1)
+		}
+		defer pgQueue.Close()
+		queue = pgQueue
+	default:
+		logger.Error("Unknown queue type", logging.F("type", cfg.Queue.Type))
+		os.Exit(1)
+	}
+
+	// Start workers as child processes
+	hostname, _ := os.Hostname()
+	provider := autoscaler.NewLocalProvider(autoscaler.LocalConfig{
+		Command:  cfg.Autoscaler.WorkerCommand,
+		IDPrefix: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}, logger)
+
+	// Count the queues of the placements the started workers satisfy. A
+	// worker that falls back to the simple runtime does not serve those
+	// requiring containers, though they are still counted.
+	runtimeType := types.WorkerRuntimeSimple
+	if cfg.Worker.UseContainer {
+		runtimeType = types.WorkerRuntimeContainer
+	}
+
+	scaler := autoscaler.NewAutoscaler(provider, queue, metadataRepo, registry.NewService(metadataRepo, logger), autoscaler.Config{
+		MinWorkers:        cfg.Autoscaler.MinWorkers,
+		MaxWorkers:        cfg.Autoscaler.MaxWorkers,
+		SlotsPerWorker:    cfg.Worker.Slots,
+		Runtime:           runtimeType,
+		Labels:            cfg.Worker.Labels,
+		Interval:          cfg.Autoscaler.Interval,
+		ScaleUpCooldown:   cfg.Autoscaler.ScaleUpCooldown,
+		ScaleDownCooldown: cfg.Autoscaler.ScaleDownCooldown,
+		MaxMessageAge:     cfg.Autoscaler.MaxMessageAge,
+		StartupTimeout:    cfg.Autoscaler.StartupTimeout,
+	}, logger)
+	scaler.Start()
+
+	// Wait for interrupt signal
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	<-sigCh
+
+	logger.Info("Shutting down gracefully...")
+
+	scaler.Stop()
+
+	// Stop the workers, letting executions in progress finish; they give
+	// up on them at their drain deadline
+	shutdownCtx := context.Background()
+	if cfg.Worker.DrainTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, cfg.Worker.DrainTimeout+30*time.Second)
+		defer cancel()
+	}
+	provider.Shutdown(shutdownCtx)
+
+	logger.Info("Autoscaler stopped")
+}
@@ -3,20 +3,28 @@ package main
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // Schedule timezones must resolve without a system zoneinfo
 
 	"github.com/go-redis/redis/v8"
 	_ "github.com/lib/pq"
 
 	"GoFaas/internal/api/controller"
 	"GoFaas/internal/api/middleware"
+	"GoFaas/internal/build"
 	"GoFaas/internal/config"
+	"GoFaas/internal/core/deadletter"
 	"GoFaas/internal/core/function"
 	"GoFaas/internal/core/invocation"
+	"GoFaas/internal/core/queuestats"
+	"GoFaas/internal/core/registry"
+	"GoFaas/internal/core/schedule"
+	"GoFaas/internal/core/version"
 	"GoFaas/internal/messaging"
 	"GoFaas/internal/observability/logging"
 	functionStorage "GoFaas/internal/storage/function"
@@ -75,16 +83,83 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Initialize function builder
+	builder, err := build.NewLocalBuilder(build.LocalConfig{
+		WorkDir: cfg.Build.WorkDir,
+		Timeout: cfg.Build.Timeout,
+		GOOS:    cfg.Build.GOOS,
+		GOARCH:  cfg.Build.GOARCH,
+	})
+	if err != nil {
+		logger.Error("Failed to initialize function builder", logging.F("error", err))
+		os.Exit(1)
+	}
+
 	// Initialize message queue
-	queue := messaging.NewRedisQueue(redisClient, "faas")
+	var queue messaging.Queue
+	switch cfg.Queue.Type {
+	case "redis":
+		queue = messaging.NewRedisQueue(redisClient, "faas", cfg.Queue.VisibilityTimeout)
+	case "redis-streams":
+		queue = messaging.NewRedisStreamQueue(redisClient, "faas", "controller", cfg.Queue.VisibilityTimeout)
+	case "postgres":
+		pgQueue, err := messaging.NewPostgresQueue(db, cfg.Database.GetDSN(), "controller", cfg.Queue.VisibilityTimeout)
+		if err != nil {
+			logger.Error("Failed to initialize PostgreSQL queue", logging.F("error", err))
+			os.Exit(1)
+		}
+		defer pgQueue.Close()
+		queue = pgQueue
+	default:
+		logger.Error("Unknown queue type", logging.F("type", cfg.Queue.Type))
+		os.Exit(1)
+	}
+	notifier := messaging.NewRedisNotifier(redisClient, "faas")
 
 	// Initialize services
-	functionService := function.NewService(metadataRepo, funcStorage, logger)
-	invocationService := invocation.NewService(metadataRepo, metadataRepo, queue, logger)
+	functionService := function.NewService(metadataRepo, funcStorage, builder, messaging.NewRedisSemaphore(redisClient, "faas", cfg.Queue.VisibilityTimeout), notifier, cfg.Build.Timeout, logger)
+	versionService := version.NewService(metadataRepo, metadataRepo, funcStorage, logger)
+	invocationService := invocation.NewService(metadataRepo, metadataRepo, metadataRepo, metadataRepo, queue, notifier, cfg.Invocation.IdempotencyKeyTTL, logger)
+	scheduleService := schedule.NewService(metadataRepo, metadataRepo, logger)
+	deadLetterService := deadletter.NewService(queue, invocationService, logger)
+	queueStatsService := queuestats.NewService(queue, invocationService, logger)
+	registryService := registry.NewService(metadataRepo, logger)
+
+	// Run deploy-time builds, restarting those a stopped process abandoned
+	functionService.Start()
+	defer functionService.Stop()
+
+	// Roll back canaries whose failure rate crosses the threshold
+	canaryMonitor := version.NewCanaryMonitor(metadataRepo, metadataRepo, version.CanaryConfig{
+		Interval:         cfg.Canary.CheckInterval,
+		Window:           cfg.Canary.Window,
+		FailureThreshold: cfg.Canary.FailureThreshold,
+		MinInvocations:   cfg.Canary.MinInvocations,
+	}, logger)
+	canaryMonitor.Start()
+	defer canaryMonitor.Stop()
+
+	// Fire cron schedules; controllers elect a single leader through Redis
+	if cfg.Scheduler.Enabled {
+		hostname, _ := os.Hostname()
+		lease := messaging.NewRedisLease(redisClient, "faas", "scheduler",
+			fmt.Sprintf("%s-%d", hostname, os.Getpid()), cfg.Scheduler.LeaseTTL)
+
+		scheduler := schedule.NewScheduler(metadataRepo, invocationService, lease, schedule.SchedulerConfig{
+			PollInterval: cfg.Scheduler.PollInterval,
+		}, logger)
+		scheduler.Start()
+		defer scheduler.Stop()
+	}
 
 	// Initialize HTTP handlers
 	functionHandler := controller.NewFunctionHandler(functionService, logger)
-	invocationHandler := controller.NewInvocationHandler(invocationService, logger)
+	invocationHandler := controller.NewInvocationHandler(invocationService, cfg.Invocation.SyncMaxWait, logger)
+	versionHandler := controller.NewVersionHandler(versionService, logger)
+	scheduleHandler := controller.NewScheduleHandler(scheduleService, logger)
+	deadLetterHandler := controller.NewDeadLetterHandler(deadLetterService, logger)
+	queueHandler := controller.NewQueueHandler(queueStatsService, logger)
+	workerHandler := controller.NewWorkerHandler(registryService, logger)
 
 	// Initialize auth middleware and handlers
 	authMiddleware := middleware.NewAuthMiddleware(middleware.AuthConfig{
@@ -100,6 +175,11 @@ func main() {
 		Addr:              cfg.Server.Addr,
 		FunctionHandler:   functionHandler,
 		InvocationHandler: invocationHandler,
+		VersionHandler:    versionHandler,
+		ScheduleHandler:   scheduleHandler,
+		DeadLetterHandler: deadLetterHandler,
+		QueueHandler:      queueHandler,
+		WorkerHandler:     workerHandler,
 		AuthHandler:       authHandler,
 		AuthMiddleware:    authMiddleware,
 		AuthzMiddleware:   authzMiddleware,
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // Schedule timezones must resolve without a system zoneinfo
+
+	"GoFaas/internal/api/controller"
+	"GoFaas/internal/api/middleware"
+	"GoFaas/internal/build"
+	"GoFaas/internal/config"
+	"GoFaas/internal/core/callback"
+	"GoFaas/internal/core/deadletter"
+	"GoFaas/internal/core/function"
+	"GoFaas/internal/core/invocation"
+	"GoFaas/internal/core/queuestats"
+	"GoFaas/internal/core/registry"
+	"GoFaas/internal/core/schedule"
+	"GoFaas/internal/core/version"
+	"GoFaas/internal/messaging"
+	"GoFaas/internal/observability/logging"
+	functionStorage "GoFaas/internal/storage/function"
+	"GoFaas/internal/storage/metadata"
+	"GoFaas/internal/worker"
+	"GoFaas/internal/worker/runtime"
+	"GoFaas/pkg/types"
+)
+
+// faas-dev runs the controller and a worker in one process, keeping
+// metadata and queues in memory and running functions with the simple
+// runtime. It needs neither PostgreSQL nor Redis; all state is lost when it
+// exits.
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize logger
+	logger := logging.NewSimpleLogger()
+	logger.Info("Starting FaaS in development mode", logging.F("worker_id", cfg.Worker.ID))
+
+	dev, err := newDevServer(cfg, logger)
+	if err != nil {
+		logger.Error("Failed to initialize development server", logging.F("error", err))
+		os.Exit(1)
+	}
+
+	// Start background services and the worker
+	dev.start()
+
+	go func() {
+		if err := dev.server.Start(); err != nil {
+			logger.Error("Server error", logging.F("error", err))
+			os.Exit(1)
+		}
+	}()
+
+	// Wait for interrupt signal
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	<-sigCh
+
+	logger.Info("Shutting down gracefully...")
+
+	// Graceful shutdown with timeout
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelShutdown()
+
+	if err := dev.server.Stop(shutdownCtx); err != nil {
+		logger.Error("Failed to stop server gracefully", logging.F("error", err))
+	}
+
+	dev.stop()
+
+	logger.Info("Development server stopped")
+}
+
+// devServer holds the components faas-dev runs in one process
+type devServer struct {
+	functionService *function.Service
+	canaryMonitor   *version.CanaryMonitor
+	scheduler       *schedule.Scheduler // Nil if scheduling is disabled
+	worker          *worker.Worker
+	dispatcher      *callback.Dispatcher
+	server          *controller.Server
+	logger          logging.Logger
+
+	cancelWorker context.CancelFunc
+	workerDone   chan struct{}
+}
+
+// newDevServer wires the controller and a worker to in-memory metadata,
+// queues and notifications
+func newDevServer(cfg *config.Config, logger logging.Logger) (*devServer, error) {
+	// Initialize in-memory metadata, queue and notifications
+	metadataRepo := metadata.NewMemoryRepository()
+	queue := messaging.NewMemoryQueue(cfg.Queue.VisibilityTimeout)
+	notifier := messaging.NewMemoryNotifier()
+	concurrency := messaging.NewMemorySemaphore(cfg.Queue.VisibilityTimeout)
+
+	// Initialize function storage
+	funcStorage, err := functionStorage.NewLocalStorage(cfg.Storage.BaseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize function storage: %w", err)
+	}
+
+	// Initialize function builder
+	builder, err := build.NewLocalBuilder(build.LocalConfig{
+		WorkDir: cfg.Build.WorkDir,
+		Timeout: cfg.Build.Timeout,
+		GOOS:    cfg.Build.GOOS,
+		GOARCH:  cfg.Build.GOARCH,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize function builder: %w", err)
+	}
+
+	// Initialize runtime
+	rt, err := runtime.NewSimpleRuntime(cfg.Worker.WorkDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize runtime: %w", err)
+	}
+
+	// Initialize services
+	functionService := function.NewService(metadataRepo, funcStorage, builder, concurrency, notifier, cfg.Build.Timeout, logger)
+	versionService := version.NewService(metadataRepo, metadataRepo, funcStorage, logger)
+	invocationService := invocation.NewService(metadataRepo, metadataRepo, metadataRepo, metadataRepo, queue, notifier, cfg.Invocation.IdempotencyKeyTTL, logger)
+	scheduleService := schedule.NewService(metadataRepo, metadataRepo, logger)
+	deadLetterService := deadletter.NewService(queue, invocationService, logger)
+	queueStatsService := queuestats.NewService(queue, invocationService, logger)
+	registryService := registry.NewService(metadataRepo, logger)
+
+	// Roll back canaries whose failure rate crosses the threshold
+	canaryMonitor := version.NewCanaryMonitor(metadataRepo, metadataRepo, version.CanaryConfig{
+		Interval:         cfg.Canary.CheckInterval,
+		Window:           cfg.Canary.Window,
+		FailureThreshold: cfg.Canary.FailureThreshold,
+		MinInvocations:   cfg.Canary.MinInvocations,
+	}, logger)
+
+	// Fire cron schedules; the only process is always the leader
+	var scheduler *schedule.Scheduler
+	if cfg.Scheduler.Enabled {
+		scheduler = schedule.NewScheduler(metadataRepo, invocationService, messaging.NewMemoryLease(), schedule.SchedulerConfig{
+			PollInterval: cfg.Scheduler.PollInterval,
+		}, logger)
+	}
+
+	// Initialize worker
+	w := worker.NewWorker(worker.Config{
+		ID:             cfg.Worker.ID,
+		Queue:          queue,
+		Notifier:       notifier,
+		Concurrency:    concurrency,
+		Registry:       metadataRepo,
+		FunctionRepo:   metadataRepo,
+		InvocationRepo: metadataRepo,
+		VersionRepo:    metadataRepo,
+		FunctionStore:  funcStorage,
+		Runtime:        rt,
+		InvocationSvc:  invocationService,
+		Logger:         logger,
+		RuntimeType:    types.WorkerRuntimeSimple,
+		Labels:         cfg.Worker.Labels,
+
+		LeaseRenewInterval: cfg.Queue.VisibilityTimeout / 3,
+		ReapInterval:       cfg.Queue.ReapInterval,
+		LaneWeights: map[types.Priority]int{
+			types.PriorityHigh:   cfg.Worker.HighPriorityWeight,
+			types.PriorityNormal: cfg.Worker.NormalPriorityWeight,
+			types.PriorityLow:    cfg.Worker.LowPriorityWeight,
+		},
+		Slots:        cfg.Worker.Slots,
+		CPUBudget:    cfg.Worker.CPUBudget,
+		MemoryBytes:  int64(cfg.Worker.MemoryBudgetMB) * 1024 * 1024,
+		DrainTimeout: cfg.Worker.DrainTimeout,
+
+		ConcurrencyDeferDelay: cfg.Worker.ConcurrencyDeferDelay,
+	})
+
+	// Deliver finished invocations to their callback URLs
+	dispatcher := callback.NewDispatcher(queue, metadataRepo, metadataRepo, callback.DispatcherConfig{
+		ID:             cfg.Worker.ID,
+		Timeout:        cfg.Callback.Timeout,
+		MaxAttempts:    cfg.Callback.MaxAttempts,
+		InitialBackoff: cfg.Callback.InitialBackoff,
+		MaxBackoff:     cfg.Callback.MaxBackoff,
+		ReapInterval:   cfg.Queue.ReapInterval,
+	}, logger)
+
+	// Initialize HTTP handlers
+	functionHandler := controller.NewFunctionHandler(functionService, logger)
+	invocationHandler := controller.NewInvocationHandler(invocationService, cfg.Invocation.SyncMaxWait, logger)
+	versionHandler := controller.NewVersionHandler(versionService, logger)
+	scheduleHandler := controller.NewScheduleHandler(scheduleService, logger)
+	deadLetterHandler := controller.NewDeadLetterHandler(deadLetterService, logger)
+	queueHandler := controller.NewQueueHandler(queueStatsService, logger)
+	workerHandler := controller.NewWorkerHandler(registryService, logger)
+
+	// Initialize auth middleware and handlers
+	authMiddleware := middleware.NewAuthMiddleware(middleware.AuthConfig{
+		JWTSecret:     "change-me-in-production", // TODO: Load from config
+		TokenDuration: 24 * time.Hour,
+		Logger:        logger,
+	})
+	authHandler := controller.NewAuthHandler(authMiddleware, logger)
+	authzMiddleware := middleware.NewAuthzMiddleware(logger)
+
+	// Initialize HTTP server without Redis, so requests are not rate limited
+	server := controller.NewServer(controller.Config{
+		Addr:              cfg.Server.Addr,
+		FunctionHandler:   functionHandler,
+		InvocationHandler: invocationHandler,
+		VersionHandler:    versionHandler,
+		ScheduleHandler:   scheduleHandler,
+		DeadLetterHandler: deadLetterHandler,
+		QueueHandler:      queueHandler,
+		WorkerHandler:     workerHandler,
+		AuthHandler:       authHandler,
+		AuthMiddleware:    authMiddleware,
+		AuthzMiddleware:   authzMiddleware,
+		Logger:            logger,
+	})
+
+	return &devServer{
+		functionService: functionService,
+		canaryMonitor:   canaryMonitor,
+		scheduler:       scheduler,
+		worker:          w,
+		dispatcher:      dispatcher,
+		server:          server,
+		logger:          logger,
+		workerDone:      make(chan struct{}),
+	}, nil
+}
+
+// start runs the background services and the worker. The HTTP server is
+// started separately.
+func (d *devServer) start() {
+	// Run deploy-time builds, restarting those a stopped process abandoned
+	d.functionService.Start()
+	d.canaryMonitor.Start()
+	if d.scheduler != nil {
+		d.scheduler.Start()
+	}
+	d.dispatcher.Start()
+
+	workerCtx, cancel := context.WithCancel(context.Background())
+	d.cancelWorker = cancel
+
+	go func() {
+		defer close(d.workerDone)
+		if err := d.worker.Start(workerCtx); err != nil {
+			d.logger.Error("Worker error", logging.F("error", err))
+		}
+	}()
+}
+
+// stop stops the worker, letting executions in progress finish, and then
+// the background services
+func (d *devServer) stop() {
+	d.worker.Stop()
+	d.cancelWorker()
+	<-d.workerDone
+	d.dispatcher.Stop()
+	if d.scheduler != nil {
+		d.scheduler.Stop()
+	}
+	d.canaryMonitor.Stop()
+	d.functionService.Stop()
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"testing"
+	"time"
+
+	"GoFaas/internal/config"
+	"GoFaas/internal/observability/logging"
+	"GoFaas/pkg/types"
+)
+
+// apiClient calls the development server's API as one logged-in user
+type apiClient struct {
+	t     *testing.T
+	url   string
+	token string
+}
+
+// do sends a JSON request and decodes the data of the response into out
+func (c *apiClient) do(method, path string, body, out interface{}) int {
+	c.t.Helper()
+
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			c.t.Fatalf("encode request: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, c.url+path, &reqBody)
+	if err != nil {
+		c.t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	envelope := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		c.t.Fatalf("%s %s: decode response: %v", method, path, err)
+	}
+	if out != nil && len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, out); err != nil {
+			c.t.Fatalf("%s %s: decode data: %v", method, path, err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+// startDevServer runs faas-dev's wiring against temporary directories and
+// returns a client logged in to its API
+func startDevServer(t *testing.T) *apiClient {
+	t.Helper()
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	cfg.Storage.BaseDir = t.TempDir()
+	cfg.Build.WorkDir = t.TempDir()
+	cfg.Worker.WorkDir = t.TempDir()
+	cfg.Worker.Slots = 2
+	cfg.Worker.MemoryBudgetMB = 0
+	cfg.Scheduler.Enabled = false
+
+	dev, err := newDevServer(cfg, logging.NewSimpleLogger())
+	if err != nil {
+		t.Fatalf("newDevServer: %v", err)
+	}
+	dev.start()
+	t.Cleanup(dev.stop)
+
+	api := httptest.NewServer(dev.server.Handler())
+	t.Cleanup(api.Close)
+
+	client := &apiClient{t: t, url: api.URL}
+	var login struct {
+		Token string `json:"token"`
+	}
+	if code := client.do("POST", "/auth/login", map[string]string{"username": "dev", "password": "dev"}, &login); code != http.StatusOK {
+		t.Fatalf("login returned %d", code)
+	}
+	client.token = login.Token
+
+	return client
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		runtime     types.RuntimeType
+		interpreter string
+		code        string
+		wantStatus  types.ExecutionStatus
+		wantResult  string
+		wantError   string
+	}{
+		{
+			name:        "python result",
+			runtime:     types.RuntimePython,
+			interpreter: "python3",
+			code:        "def handler(event, context):\n    return {'message': 'Hello, ' + event['name'] + '!'}\n",
+			wantStatus:  types.StatusCompleted,
+			wantResult:  `{"message":"Hello, dev!"}`,
+		},
+		{
+			name:        "node result",
+			runtime:     types.RuntimeNodeJS,
+			interpreter: "node",
+			code:        "exports.handler = async (event) => ({message: `Hello, ${event.name}!`});\n",
+			wantStatus:  types.StatusCompleted,
+			wantResult:  `{"message":"Hello, dev!"}`,
+		},
+		{
+			name:        "python error",
+			runtime:     types.RuntimePython,
+			interpreter: "python3",
+			code:        "def handler(event, context):\n    raise ValueError('bad input')\n",
+			wantStatus:  types.StatusFailed,
+			wantError:   "ValueError",
+		},
+	}
+
+	client := startDevServer(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := exec.LookPath(tt.interpreter); err != nil {
+				t.Skipf("%s is not installed", tt.interpreter)
+			}
+			client.t = t
+
+			var fn types.Function
+			code := client.do("POST", "/functions", map[string]interface{}{
+				"name":            "roundtrip-" + string(tt.runtime) + "-" + string(tt.wantStatus),
+				"version":         "1.0.0",
+				"runtime":         tt.runtime,
+				"handler":         "main.handler",
+				"code":            base64.StdEncoding.EncodeToString([]byte(tt.code)),
+				"timeout":         10 * time.Second,
+				"memory_mb":       128,
+				"max_concurrency": 1,
+				"retry_policy":    types.RetryPolicy{MaxAttempts: 1},
+			}, &fn)
+			if code != http.StatusCreated {
+				t.Fatalf("create function returned %d", code)
+			}
+
+			// The controller enqueues the invocation for the worker
+			var handle struct {
+				InvocationID string `json:"invocation_id"`
+			}
+			code = client.do("POST", "/invoke", map[string]interface{}{
+				"function_id": fn.ID,
+				"payload":     map[string]string{"name": "dev"},
+			}, &handle)
+			if code != http.StatusAccepted {
+				t.Fatalf("invoke returned %d", code)
+			}
+
+			var inv types.Invocation
+			deadline := time.Now().Add(30 * time.Second)
+			for {
+				client.do("GET", "/invocations/"+handle.InvocationID, nil, &inv)
+				if inv.Status.IsTerminal() || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+
+			if inv.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s (error %+v)", inv.Status, tt.wantStatus, inv.Error)
+			}
+			if tt.wantResult != "" && string(inv.Result) != tt.wantResult {
+				t.Errorf("result = %s, want %s", inv.Result, tt.wantResult)
+			}
+			if tt.wantError != "" && (inv.Error == nil || inv.Error.Type != tt.wantError) {
+				t.Errorf("error = %+v, want type %s", inv.Error, tt.wantError)
+			}
+		})
+	}
+}
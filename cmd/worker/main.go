@@ -12,6 +12,7 @@ import (
 	_ "github.com/lib/pq"
 
 	"GoFaas/internal/config"
+	"GoFaas/internal/core/callback"
 	"GoFaas/internal/core/invocation"
 	"GoFaas/internal/messaging"
 	"GoFaas/internal/observability/logging"
@@ -19,6 +20,7 @@ import (
 	"GoFaas/internal/storage/metadata"
 	"GoFaas/internal/worker"
 	"GoFaas/internal/worker/runtime"
+	"GoFaas/pkg/types"
 )
 
 func main() {
@@ -72,14 +74,38 @@ func main() {
 	}
 
 	// Initialize message queue
-	queue := messaging.NewRedisQueue(redisClient, "faas")
+	var queue messaging.Queue
+	switch cfg.Queue.Type {
+	case "redis":
+		queue = messaging.NewRedisQueue(redisClient, "faas", cfg.Queue.VisibilityTimeout)
+	case "redis-streams":
+		queue = messaging.NewRedisStreamQueue(redisClient, "faas", cfg.Worker.ID, cfg.Queue.VisibilityTimeout)
+	case "postgres":
+		pgQueue, err := messaging.NewPostgresQueue(db, cfg.Database.GetDSN(), cfg.Worker.ID, cfg.Queue.VisibilityTimeout)
+		if err != nil {
+			logger.Error("Failed to initialize PostgreSQL queue", logging.F("error", err))
+			os.Exit(1)
+		}
+		defer pgQueue.Close()
+		queue = pgQueue
+	default:
+		logger.Error("Unknown queue type", logging.F("type", cfg.Queue.Type))
+		os.Exit(1)
+	}
+	notifier := messaging.NewRedisNotifier(redisClient, "faas")
 
-	// Initialize runtime based on configuration
+	// Initialize runtime based on configuration, noting which one is used
+	// for function placement
 	var rt runtime.Runtime
+	runtimeType := types.WorkerRuntimeSimple
 
 	if cfg.Worker.UseContainer {
 		logger.Info("Initializing container-based runtime")
-		rt, err = runtime.NewContainerRuntime(cfg.Worker.WorkDir, logger)
+		rt, err = runtime.NewContainerRuntime(cfg.Worker.WorkDir, runtime.PoolConfig{
+			MinIdle:     cfg.Worker.PoolMinIdle,
+			MaxIdle:     cfg.Worker.PoolMaxIdle,
+			IdleTimeout: cfg.Worker.PoolIdleTimeout,
+		}, logger)
 		if err != nil {
 			logger.Error("Failed to initialize container runtime", logging.F("error", err))
 			logger.Info("Falling back to simple runtime")
@@ -88,6 +114,8 @@ func main() {
 				logger.Error("Failed to initialize simple runtime", logging.F("error", err))
 				os.Exit(1)
 			}
+		} else {
+			runtimeType = types.WorkerRuntimeContainer
 		}
 	} else {
 		logger.Info("Initializing simple runtime")
@@ -99,20 +127,51 @@ func main() {
 	}
 
 	// Initialize invocation service
-	invocationService := invocation.NewService(metadataRepo, metadataRepo, queue, logger)
+	invocationService := invocation.NewService(metadataRepo, metadataRepo, metadataRepo, metadataRepo, queue, notifier, cfg.Invocation.IdempotencyKeyTTL, logger)
 
 	// Initialize worker
 	w := worker.NewWorker(worker.Config{
 		ID:             cfg.Worker.ID,
 		Queue:          queue,
+		Notifier:       notifier,
+		Concurrency:    messaging.NewRedisSemaphore(redisClient, "faas", cfg.Queue.VisibilityTimeout),
+		Registry:       metadataRepo,
 		FunctionRepo:   metadataRepo,
 		InvocationRepo: metadataRepo,
+		VersionRepo:    metadataRepo,
 		FunctionStore:  funcStorage,
 		Runtime:        rt,
 		InvocationSvc:  invocationService,
 		Logger:         logger,
+		RuntimeType:    runtimeType,
+		Labels:         cfg.Worker.Labels,
+
+		LeaseRenewInterval: cfg.Queue.VisibilityTimeout / 3,
+		ReapInterval:       cfg.Queue.ReapInterval,
+		LaneWeights: map[types.Priority]int{
+			types.PriorityHigh:   cfg.Worker.HighPriorityWeight,
+			types.PriorityNormal: cfg.Worker.NormalPriorityWeight,
+			types.PriorityLow:    cfg.Worker.LowPriorityWeight,
+		},
+		Slots:        cfg.Worker.Slots,
+		CPUBudget:    cfg.Worker.CPUBudget,
+		MemoryBytes:  int64(cfg.Worker.MemoryBudgetMB) * 1024 * 1024,
+		DrainTimeout: cfg.Worker.DrainTimeout,
+
+		ConcurrencyDeferDelay: cfg.Worker.ConcurrencyDeferDelay,
 	})
 
+	// Deliver finished invocations to their callback URLs
+	dispatcher := callback.NewDispatcher(queue, metadataRepo, metadataRepo, callback.DispatcherConfig{
+		ID:             cfg.Worker.ID,
+		Timeout:        cfg.Callback.Timeout,
+		MaxAttempts:    cfg.Callback.MaxAttempts,
+		InitialBackoff: cfg.Callback.InitialBackoff,
+		MaxBackoff:     cfg.Callback.MaxBackoff,
+		ReapInterval:   cfg.Queue.ReapInterval,
+	}, logger)
+	dispatcher.Start()
+
 	// Start worker in goroutine
 	workerCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -130,9 +189,10 @@ func main() {
 
 	logger.Info("Shutting down gracefully...")
 
-	// Stop worker
+	// Stop worker, letting executions in progress finish
 	w.Stop()
 	cancel()
+	dispatcher.Stop()
 
 	logger.Info("Worker stopped")
 }
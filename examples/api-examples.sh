@@ -16,7 +16,7 @@ FUNCTION_RESPONSE=$(curl -s -X POST $BASE_URL/functions \
     \"name\": \"hello-go\",
     \"version\": \"1.0.0\",
     \"runtime\": \"go\",
-    \"handler\": \"main\",
+    \"handler\": \"Handler\",
     \"code\": \"$GO_CODE\",
     \"timeout\": \"30s\",
     \"memory_mb\": 128,
@@ -27,6 +27,11 @@ FUNCTION_RESPONSE=$(curl -s -X POST $BASE_URL/functions \
 
 FUNCTION_ID=$(echo $FUNCTION_RESPONSE | jq -r '.data.id')
 echo "Function created with ID: $FUNCTION_ID"
+
+# Go functions are compiled before they can be invoked
+while [ "$(curl -s $BASE_URL/functions/$FUNCTION_ID | jq -r '.data.status')" = "building" ]; do
+  sleep 1
+done
 echo ""
 
 # 2. List functions
@@ -36,7 +41,7 @@ echo ""
 
 # 3. Get function details
 echo "3. Getting function details..."
-curl -s $BASE_URL/functions/$FUNCTION_ID | jq '.data | {id, name, runtime, handler}'
+curl -s $BASE_URL/functions/$FUNCTION_ID | jq '.data | {id, name, runtime, handler, status, build}'
 echo ""
 
 # 4. Invoke function
@@ -59,7 +64,7 @@ sleep 3
 
 # 6. Get invocation result
 echo "6. Getting invocation result..."
-curl -s $BASE_URL/invocations/$INVOCATION_ID | jq '.data | {id, status, result, metrics}'
+curl -s $BASE_URL/invocations/$INVOCATION_ID | jq '.data | {id, status, result, logs, metrics}'
 echo ""
 
 # 7. List invocations
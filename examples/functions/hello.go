@@ -1,9 +1,15 @@
+//go:build ignore
+
+// This file is deployed as function code rather than built with the module;
+// the worker links it with a generated main via the runtime bootstrap.
+
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"os"
+	"log"
 )
 
 type Request struct {
@@ -14,24 +20,25 @@ type Response struct {
 	Message string `json:"message"`
 }
 
-func main() {
-	// Get payload from environment variable
-	payloadStr := os.Getenv("FUNCTION_PAYLOAD")
-	
+// Handler is invoked with the invocation payload; its return value becomes
+// the invocation result
+func Handler(ctx context.Context, payload json.RawMessage) (interface{}, error) {
 	var req Request
-	if payloadStr != "" {
-		json.Unmarshal([]byte(payloadStr), &req)
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, fmt.Errorf("invalid payload: %w", err)
+		}
 	}
-	
+
 	name := req.Name
 	if name == "" {
 		name = "World"
 	}
-	
-	response := Response{
+
+	// Log output is collected separately from the result
+	log.Printf("greeting %s", name)
+
+	return Response{
 		Message: fmt.Sprintf("Hello, %s!", name),
-	}
-	
-	output, _ := json.Marshal(response)
-	fmt.Println(string(output))
+	}, nil
 }
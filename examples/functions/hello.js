@@ -1,23 +1,14 @@
 #!/usr/bin/env node
 
-function main() {
-    // Get payload from environment variable
-    const payloadStr = process.env.FUNCTION_PAYLOAD || '{}';
-    
-    let payload;
-    try {
-        payload = JSON.parse(payloadStr);
-    } catch (e) {
-        payload = {};
-    }
-    
-    const name = payload.name || 'World';
-    
-    const response = {
+// event is the invocation payload; the return value (or resolved promise)
+// becomes the result
+exports.handler = async (event, context) => {
+    const name = (event && event.name) || 'World';
+
+    // Log output is collected separately from the result
+    console.log(`greeting ${name}`);
+
+    return {
         message: `Hello, ${name}!`
     };
-    
-    console.log(JSON.stringify(response));
-}
-
-main();
+};
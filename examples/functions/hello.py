@@ -1,23 +1,14 @@
 #!/usr/bin/env python3
-import json
-import os
 
-def main():
-    # Get payload from environment variable
-    payload_str = os.environ.get('FUNCTION_PAYLOAD', '{}')
-    
-    try:
-        payload = json.loads(payload_str)
-    except:
-        payload = {}
-    
-    name = payload.get('name', 'World')
-    
-    response = {
+
+def handler(event, context):
+    # event is the invocation payload; the return value becomes the result
+    event = event or {}
+    name = event.get('name', 'World')
+
+    # Log output is collected separately from the result
+    print(f'greeting {name}')
+
+    return {
         'message': f'Hello, {name}!'
     }
-    
-    print(json.dumps(response))
-
-if __name__ == '__main__':
-    main()
@@ -61,6 +61,8 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		string(middleware.PermissionFunctionUpdate),
 		string(middleware.PermissionFunctionDelete),
 		string(middleware.PermissionFunctionInvoke),
+		string(middleware.PermissionQueueManage),
+		string(middleware.PermissionWorkerManage),
 	}
 
 	// Generate JWT token
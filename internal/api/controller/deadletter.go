@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"GoFaas/internal/api/common"
+	"GoFaas/internal/core/deadletter"
+	"GoFaas/internal/observability/logging"
+	"GoFaas/pkg/errors"
+)
+
+// DeadLetterHandler handles dead letter administration requests
+type DeadLetterHandler struct {
+	service *deadletter.Service
+	logger  logging.Logger
+}
+
+// NewDeadLetterHandler creates a new dead letter handler
+func NewDeadLetterHandler(service *deadletter.Service, logger logging.Logger) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListDeadLetters handles dead letter listing. Supports ?function_id=,
+// ?reason=, ?limit= (default 50) and ?offset=.
+func (h *DeadLetterHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseDeadLetterFilter(r, 50)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	deadLetters, err := h.service.List(r.Context(), filter)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, deadLetters)
+}
+
+// GetDeadLetter handles dead letter retrieval
+func (h *DeadLetterHandler) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	dl, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, dl)
+}
+
+// DeleteDeadLetter handles deletion of a single dead letter
+func (h *DeadLetterHandler) DeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Dead letter deleted successfully",
+	})
+}
+
+// DeleteDeadLetters handles bulk deletion of the dead letters matching the
+// query filters. Without filters every dead letter is deleted.
+func (h *DeadLetterHandler) DeleteDeadLetters(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseDeadLetterFilter(r, 0)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	deleted, err := h.service.DeleteMatching(r.Context(), filter)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, map[string]int{
+		"deleted": deleted,
+	})
+}
+
+// ReplayDeadLetter handles replaying a single dead letter
+func (h *DeadLetterHandler) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	handle, err := h.service.Replay(r.Context(), id)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusAccepted, handle)
+}
+
+// ReplayDeadLetters handles bulk replay of the dead letters matching the
+// query filters, reporting the outcome for each
+func (h *DeadLetterHandler) ReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseDeadLetterFilter(r, 0)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	results, err := h.service.ReplayMatching(r.Context(), filter)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, results)
+}
+
+// parseDeadLetterFilter reads the dead letter filter from the query string
+func parseDeadLetterFilter(r *http.Request, defaultLimit int) (deadletter.Filter, error) {
+	query := r.URL.Query()
+
+	filter := deadletter.Filter{
+		FunctionID: query.Get("function_id"),
+		Reason:     query.Get("reason"),
+		Limit:      defaultLimit,
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return filter, errors.ValidationError("limit must be a non-negative integer")
+		}
+		filter.Limit = n
+	}
+
+	if offset := query.Get("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return filter, errors.ValidationError("offset must be a non-negative integer")
+		}
+		filter.Offset = n
+	}
+
+	return filter, nil
+}
@@ -36,6 +36,9 @@ func (h *FunctionHandler) CreateFunction(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Record the creator so only they (or an admin) may update the function
+	req.CreatedBy, _ = middleware.GetUserID(r.Context())
+
 	fn, err := h.service.CreateFunction(r.Context(), req)
 	if err != nil {
 		common.WriteError(w, err)
@@ -82,7 +85,6 @@ func (h *FunctionHandler) UpdateFunction(w http.ResponseWriter, r *http.Request)
 	perms, _ := middleware.GetPermissions(r.Context())
 	isAdmin := contains(perms, string(middleware.PermissionAdminAll))
 
-	// TODO: Implement FunctionPermission logic, add FunctionOwner in Function Type too
 	if fn.CreatedBy != userID && !isAdmin {
 		common.WriteError(w, errors.NewAppError(
 			errors.ErrCodeForbidden,
@@ -1,7 +1,9 @@
 package controller
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -9,30 +11,44 @@ import (
 	"GoFaas/internal/core/invocation"
 	"GoFaas/internal/observability/logging"
 	"GoFaas/internal/storage/metadata"
+	"GoFaas/pkg/errors"
 )
 
 // InvocationHandler handles function invocation requests
 type InvocationHandler struct {
-	service *invocation.Service
-	logger  logging.Logger
+	service     *invocation.Service
+	syncMaxWait time.Duration
+	logger      logging.Logger
 }
 
 // NewInvocationHandler creates a new invocation handler
-func NewInvocationHandler(service *invocation.Service, logger logging.Logger) *InvocationHandler {
+func NewInvocationHandler(service *invocation.Service, syncMaxWait time.Duration, logger logging.Logger) *InvocationHandler {
 	return &InvocationHandler{
-		service: service,
-		logger:  logger,
+		service:     service,
+		syncMaxWait: syncMaxWait,
+		logger:      logger,
 	}
 }
 
-// InvokeFunction handles function invocation
+// InvokeFunction handles function invocation. With ?mode=sync the request
+// blocks until the result is ready (see InvokeFunctionSync).
 func (h *InvocationHandler) InvokeFunction(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("mode") == "sync" {
+		h.InvokeFunctionSync(w, r)
+		return
+	}
+
 	var req invocation.InvocationRequest
 	if err := common.ParseJSON(r, &req); err != nil {
 		common.WriteError(w, err)
 		return
 	}
 
+	if err := applyIdempotencyKey(r, &req); err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
 	handle, err := h.service.InvokeAsync(r.Context(), req)
 	if err != nil {
 		common.WriteError(w, err)
@@ -42,6 +58,65 @@ func (h *InvocationHandler) InvokeFunction(w http.ResponseWriter, r *http.Reques
 	common.WriteJSON(w, http.StatusAccepted, handle)
 }
 
+// InvokeFunctionSync handles synchronous function invocation. It responds
+// 200 with the finished invocation, or 202 with the invocation handle when the
+// function does not finish within the wait limit. The optional ?wait=<duration>
+// parameter shortens the wait; it is capped by the configured maximum.
+func (h *InvocationHandler) InvokeFunctionSync(w http.ResponseWriter, r *http.Request) {
+	wait := h.syncMaxWait
+	if waitStr := r.URL.Query().Get("wait"); waitStr != "" {
+		d, err := time.ParseDuration(waitStr)
+		if err != nil || d <= 0 {
+			common.WriteError(w, errors.ValidationError(fmt.Sprintf("invalid wait duration: %s", waitStr)))
+			return
+		}
+		if d < wait {
+			wait = d
+		}
+	}
+
+	var req invocation.InvocationRequest
+	if err := common.ParseJSON(r, &req); err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	if err := applyIdempotencyKey(r, &req); err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	inv, handle, err := h.service.InvokeSync(r.Context(), req, wait)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	if inv == nil {
+		common.WriteJSON(w, http.StatusAccepted, handle)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, inv)
+}
+
+// applyIdempotencyKey copies the Idempotency-Key header into the request.
+// The header and the idempotency_key field may both be given only if they
+// agree.
+func applyIdempotencyKey(r *http.Request, req *invocation.InvocationRequest) error {
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" {
+		return nil
+	}
+
+	if req.IdempotencyKey != "" && req.IdempotencyKey != key {
+		return errors.ValidationError("Idempotency-Key header does not match idempotency_key")
+	}
+	req.IdempotencyKey = key
+
+	return nil
+}
+
 // GetInvocationResult handles invocation result retrieval
 func (h *InvocationHandler) GetInvocationResult(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -56,6 +131,46 @@ func (h *InvocationHandler) GetInvocationResult(w http.ResponseWriter, r *http.R
 	common.WriteJSON(w, http.StatusOK, inv)
 }
 
+// CancelInvocation handles cancelling an unfinished invocation
+func (h *InvocationHandler) CancelInvocation(w http.ResponseWriter, r *http.Request) {
+	invocationID := mux.Vars(r)["id"]
+
+	inv, err := h.service.Cancel(r.Context(), invocationID)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, inv)
+}
+
+// ListAttempts handles listing the execution attempts of an invocation
+func (h *InvocationHandler) ListAttempts(w http.ResponseWriter, r *http.Request) {
+	invocationID := mux.Vars(r)["id"]
+
+	attempts, err := h.service.ListAttempts(r.Context(), invocationID)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, attempts)
+}
+
+// ListCallbackDeliveries handles listing the callback delivery attempts of
+// an invocation
+func (h *InvocationHandler) ListCallbackDeliveries(w http.ResponseWriter, r *http.Request) {
+	invocationID := mux.Vars(r)["id"]
+
+	deliveries, err := h.service.ListCallbackDeliveries(r.Context(), invocationID)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, deliveries)
+}
+
 // ListInvocations handles invocation listing
 func (h *InvocationHandler) ListInvocations(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
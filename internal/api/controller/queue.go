@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"GoFaas/internal/api/common"
+	"GoFaas/internal/core/queuestats"
+	"GoFaas/internal/observability/logging"
+	"GoFaas/pkg/errors"
+)
+
+// QueueHandler handles queue monitoring requests
+type QueueHandler struct {
+	service *queuestats.Service
+	logger  logging.Logger
+}
+
+// NewQueueHandler creates a new queue handler
+func NewQueueHandler(service *queuestats.Service, logger logging.Logger) *QueueHandler {
+	return &QueueHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListQueues handles listing the statistics of every queue
+func (h *QueueHandler) ListQueues(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.List(r.Context())
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, stats)
+}
+
+// GetQueue handles retrieval of the statistics of a queue
+func (h *QueueHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	stats, err := h.service.Get(r.Context(), name)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, stats)
+}
+
+// ListPending handles listing the in-flight messages of a queue. Supports
+// ?limit= (default 50).
+func (h *QueueHandler) ListPending(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			common.WriteError(w, errors.ValidationError("limit must be a positive integer"))
+			return
+		}
+		limit = n
+	}
+
+	pending, err := h.service.ListPending(r.Context(), name, limit)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, pending)
+}
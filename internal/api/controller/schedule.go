@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"GoFaas/internal/api/common"
+	"GoFaas/internal/core/schedule"
+	"GoFaas/internal/observability/logging"
+)
+
+// ScheduleHandler handles function schedule requests
+type ScheduleHandler struct {
+	service *schedule.Service
+	logger  logging.Logger
+}
+
+// NewScheduleHandler creates a new schedule handler
+func NewScheduleHandler(service *schedule.Service, logger logging.Logger) *ScheduleHandler {
+	return &ScheduleHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateSchedule handles schedule creation for a function
+func (h *ScheduleHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var req schedule.CreateScheduleRequest
+	if err := common.ParseJSON(r, &req); err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	sch, err := h.service.CreateSchedule(r.Context(), id, req)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusCreated, sch)
+}
+
+// ListSchedules handles listing the schedules of a function
+func (h *ScheduleHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	schedules, err := h.service.ListSchedules(r.Context(), id)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, schedules)
+}
+
+// GetSchedule handles schedule retrieval
+func (h *ScheduleHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	sch, err := h.service.GetSchedule(r.Context(), vars["id"], vars["schedule"])
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, sch)
+}
+
+// UpdateSchedule handles schedule updates
+func (h *ScheduleHandler) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req schedule.UpdateScheduleRequest
+	if err := common.ParseJSON(r, &req); err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	sch, err := h.service.UpdateSchedule(r.Context(), vars["id"], vars["schedule"], req)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, sch)
+}
+
+// DeleteSchedule handles schedule deletion
+func (h *ScheduleHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := h.service.DeleteSchedule(r.Context(), vars["id"], vars["schedule"]); err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Schedule deleted successfully",
+	})
+}
@@ -18,6 +18,11 @@ type Server struct {
 	addr              string
 	functionHandler   *FunctionHandler
 	invocationHandler *InvocationHandler
+	versionHandler    *VersionHandler
+	scheduleHandler   *ScheduleHandler
+	deadLetterHandler *DeadLetterHandler
+	queueHandler      *QueueHandler
+	workerHandler     *WorkerHandler
 	authHandler       *AuthHandler
 	authMiddleware    *middleware.AuthMiddleware
 	authzMiddleware   *middleware.AuthzMiddleware
@@ -31,6 +36,11 @@ type Config struct {
 	Addr              string
 	FunctionHandler   *FunctionHandler
 	InvocationHandler *InvocationHandler
+	VersionHandler    *VersionHandler
+	ScheduleHandler   *ScheduleHandler
+	DeadLetterHandler *DeadLetterHandler
+	QueueHandler      *QueueHandler
+	WorkerHandler     *WorkerHandler
 	AuthHandler       *AuthHandler
 	AuthMiddleware    *middleware.AuthMiddleware
 	AuthzMiddleware   *middleware.AuthzMiddleware
@@ -44,6 +54,11 @@ func NewServer(cfg Config) *Server {
 		addr:              cfg.Addr,
 		functionHandler:   cfg.FunctionHandler,
 		invocationHandler: cfg.InvocationHandler,
+		versionHandler:    cfg.VersionHandler,
+		scheduleHandler:   cfg.ScheduleHandler,
+		deadLetterHandler: cfg.DeadLetterHandler,
+		queueHandler:      cfg.QueueHandler,
+		workerHandler:     cfg.WorkerHandler,
 		authHandler:       cfg.AuthHandler,
 		authMiddleware:    cfg.AuthMiddleware,
 		authzMiddleware:   cfg.AuthzMiddleware,
@@ -54,11 +69,9 @@ func NewServer(cfg Config) *Server {
 
 // Start starts the HTTP server
 func (s *Server) Start() error {
-	router := s.setupRoutes()
-
 	s.server = &http.Server{
 		Addr:         s.addr,
-		Handler:      router,
+		Handler:      s.Handler(),
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -73,6 +86,11 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// Handler returns the HTTP handler serving the API routes
+func (s *Server) Handler() http.Handler {
+	return s.setupRoutes()
+}
+
 // Stop gracefully stops the HTTP server
 func (s *Server) Stop(ctx context.Context) error {
 	s.logger.Info("Stopping API server")
@@ -122,18 +140,153 @@ func (s *Server) setupRoutes() *mux.Router {
 			http.HandlerFunc(s.functionHandler.DeleteFunction),
 		)).Methods("DELETE")
 
+	// Version and alias routes
+	protected.Handle("/functions/{id}/publish",
+		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionUpdate)(
+			http.HandlerFunc(s.versionHandler.PublishVersion),
+		)).Methods("POST")
+
+	protected.Handle("/functions/{name}/versions",
+		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionRead)(
+			http.HandlerFunc(s.versionHandler.ListVersions),
+		)).Methods("GET")
+
+	protected.Handle("/functions/{name}/versions/{version}",
+		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionRead)(
+			http.HandlerFunc(s.versionHandler.GetVersion),
+		)).Methods("GET")
+
+	protected.Handle("/functions/{name}/aliases",
+		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionRead)(
+			http.HandlerFunc(s.versionHandler.ListAliases),
+		)).Methods("GET")
+
+	protected.Handle("/functions/{name}/aliases/{alias}",
+		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionRead)(
+			http.HandlerFunc(s.versionHandler.GetAlias),
+		)).Methods("GET")
+
+	protected.Handle("/functions/{name}/aliases/{alias}",
+		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionUpdate)(
+			http.HandlerFunc(s.versionHandler.PutAlias),
+		)).Methods("PUT")
+
+	protected.Handle("/functions/{name}/aliases/{alias}",
+		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionUpdate)(
+			http.HandlerFunc(s.versionHandler.DeleteAlias),
+		)).Methods("DELETE")
+
+	// Schedule routes
+	protected.Handle("/functions/{id}/schedules",
+		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionUpdate)(
+			http.HandlerFunc(s.scheduleHandler.CreateSchedule),
+		)).Methods("POST")
+
+	protected.Handle("/functions/{id}/schedules",
+		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionRead)(
+			http.HandlerFunc(s.scheduleHandler.ListSchedules),
+		)).Methods("GET")
+
+	protected.Handle("/functions/{id}/schedules/{schedule}",
+		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionRead)(
+			http.HandlerFunc(s.scheduleHandler.GetSchedule),
+		)).Methods("GET")
+
+	protected.Handle("/functions/{id}/schedules/{schedule}",
+		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionUpdate)(
+			http.HandlerFunc(s.scheduleHandler.UpdateSchedule),
+		)).Methods("PUT")
+
+	protected.Handle("/functions/{id}/schedules/{schedule}",
+		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionUpdate)(
+			http.HandlerFunc(s.scheduleHandler.DeleteSchedule),
+		)).Methods("DELETE")
+
 	// Invocation routes
 	protected.Handle("/invoke",
 		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionInvoke)(
 			http.HandlerFunc(s.invocationHandler.InvokeFunction),
 		)).Methods("POST")
+	protected.Handle("/invoke/sync",
+		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionInvoke)(
+			http.HandlerFunc(s.invocationHandler.InvokeFunctionSync),
+		)).Methods("POST")
 	router.HandleFunc("/invocations/{id}", s.invocationHandler.GetInvocationResult).Methods("GET")
+	protected.Handle("/invocations/{id}",
+		s.authzMiddleware.RequirePermission(middleware.PermissionFunctionInvoke)(
+			http.HandlerFunc(s.invocationHandler.CancelInvocation),
+		)).Methods("DELETE")
+	router.HandleFunc("/invocations/{id}/attempts", s.invocationHandler.ListAttempts).Methods("GET")
+	router.HandleFunc("/invocations/{id}/callbacks", s.invocationHandler.ListCallbackDeliveries).Methods("GET")
 	router.HandleFunc("/invocations", s.invocationHandler.ListInvocations).Methods("GET")
 
+	// Dead letter administration routes
+	protected.Handle("/admin/dead-letters",
+		s.authzMiddleware.RequirePermission(middleware.PermissionQueueManage)(
+			http.HandlerFunc(s.deadLetterHandler.ListDeadLetters),
+		)).Methods("GET")
+
+	protected.Handle("/admin/dead-letters",
+		s.authzMiddleware.RequirePermission(middleware.PermissionQueueManage)(
+			http.HandlerFunc(s.deadLetterHandler.DeleteDeadLetters),
+		)).Methods("DELETE")
+
+	protected.Handle("/admin/dead-letters/replay",
+		s.authzMiddleware.RequirePermission(middleware.PermissionQueueManage)(
+			http.HandlerFunc(s.deadLetterHandler.ReplayDeadLetters),
+		)).Methods("POST")
+
+	protected.Handle("/admin/dead-letters/{id}",
+		s.authzMiddleware.RequirePermission(middleware.PermissionQueueManage)(
+			http.HandlerFunc(s.deadLetterHandler.GetDeadLetter),
+		)).Methods("GET")
+
+	protected.Handle("/admin/dead-letters/{id}",
+		s.authzMiddleware.RequirePermission(middleware.PermissionQueueManage)(
+			http.HandlerFunc(s.deadLetterHandler.DeleteDeadLetter),
+		)).Methods("DELETE")
+
+	protected.Handle("/admin/dead-letters/{id}/replay",
+		s.authzMiddleware.RequirePermission(middleware.PermissionQueueManage)(
+			http.HandlerFunc(s.deadLetterHandler.ReplayDeadLetter),
+		)).Methods("POST")
+
+	// Queue monitoring routes
+	protected.Handle("/admin/queues",
+		s.authzMiddleware.RequirePermission(middleware.PermissionQueueManage)(
+			http.HandlerFunc(s.queueHandler.ListQueues),
+		)).Methods("GET")
+
+	protected.Handle("/admin/queues/{name}",
+		s.authzMiddleware.RequirePermission(middleware.PermissionQueueManage)(
+			http.HandlerFunc(s.queueHandler.GetQueue),
+		)).Methods("GET")
+
+	protected.Handle("/admin/queues/{name}/pending",
+		s.authzMiddleware.RequirePermission(middleware.PermissionQueueManage)(
+			http.HandlerFunc(s.queueHandler.ListPending),
+		)).Methods("GET")
+
+	// Worker administration routes
+	protected.Handle("/admin/workers",
+		s.authzMiddleware.RequirePermission(middleware.PermissionWorkerManage)(
+			http.HandlerFunc(s.workerHandler.ListWorkers),
+		)).Methods("GET")
+
+	protected.Handle("/admin/workers/{id}",
+		s.authzMiddleware.RequirePermission(middleware.PermissionWorkerManage)(
+			http.HandlerFunc(s.workerHandler.GetWorker),
+		)).Methods("GET")
+
+	protected.Handle("/admin/workers/{id}/drain",
+		s.authzMiddleware.RequirePermission(middleware.PermissionWorkerManage)(
+			http.HandlerFunc(s.workerHandler.DrainWorker),
+		)).Methods("POST")
+
 	corsMiddleware := middleware.NewCORSMiddleware(middleware.CORSConfig{
 		AllowedOrigins:   []string{"https://app.example.com", "http://localhost:3000"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "Idempotency-Key"},
 		ExposedHeaders:   []string{"X-RateLimit-Limit", "X-RateLimit-Remaining"},
 		AllowCredentials: true,
 		MaxAge:           3600,
@@ -141,14 +294,18 @@ func (s *Server) setupRoutes() *mux.Router {
 	})
 	router.Use(corsMiddleware.Middleware)
 
-	standardLimiter := middleware.NewRateLimitMiddleware(middleware.RateLimitConfig{
-		RedisClient:       s.redisClient,
-		Logger:            s.logger,
-		RequestsPerWindow: 100,
-		WindowDuration:    time.Minute,
-	})
-
-	router.Use(standardLimiter.Middleware)
+	// Rate limits are counted in Redis; without it (development mode)
+	// requests are not limited
+	if s.redisClient != nil {
+		standardLimiter := middleware.NewRateLimitMiddleware(middleware.RateLimitConfig{
+			RedisClient:       s.redisClient,
+			Logger:            s.logger,
+			RequestsPerWindow: 100,
+			WindowDuration:    time.Minute,
+		})
+
+		router.Use(standardLimiter.Middleware)
+	}
 
 	// Add logging middleware
 	router.Use(s.loggingMiddleware)
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"GoFaas/internal/api/common"
+	"GoFaas/internal/core/version"
+	"GoFaas/internal/observability/logging"
+	"GoFaas/pkg/errors"
+)
+
+// VersionHandler handles function version and alias requests
+type VersionHandler struct {
+	service *version.Service
+	logger  logging.Logger
+}
+
+// NewVersionHandler creates a new version handler
+func NewVersionHandler(service *version.Service, logger logging.Logger) *VersionHandler {
+	return &VersionHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// PublishVersion handles publishing a function's current code as a new version
+func (h *VersionHandler) PublishVersion(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var req version.PublishRequest
+	if r.ContentLength != 0 {
+		if err := common.ParseJSON(r, &req); err != nil {
+			common.WriteError(w, err)
+			return
+		}
+	}
+
+	v, err := h.service.Publish(r.Context(), id, req)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusCreated, v)
+}
+
+// ListVersions handles listing the version history of a function name
+func (h *VersionHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	versions, err := h.service.ListVersions(r.Context(), name)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, versions)
+}
+
+// GetVersion handles retrieval of a single version
+func (h *VersionHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	n, err := strconv.Atoi(vars["version"])
+	if err != nil {
+		common.WriteError(w, errors.ValidationError("version must be a number"))
+		return
+	}
+
+	v, err := h.service.GetVersion(r.Context(), vars["name"], n)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, v)
+}
+
+// ListAliases handles listing the aliases of a function name
+func (h *VersionHandler) ListAliases(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	aliases, err := h.service.ListAliases(r.Context(), name)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, aliases)
+}
+
+// GetAlias handles alias retrieval
+func (h *VersionHandler) GetAlias(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	alias, err := h.service.GetAlias(r.Context(), vars["name"], vars["alias"])
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, alias)
+}
+
+// PutAlias handles creating an alias or moving it to another version
+func (h *VersionHandler) PutAlias(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req version.AliasRequest
+	if err := common.ParseJSON(r, &req); err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	alias, err := h.service.PutAlias(r.Context(), vars["name"], vars["alias"], req)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, alias)
+}
+
+// DeleteAlias handles alias deletion
+func (h *VersionHandler) DeleteAlias(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := h.service.DeleteAlias(r.Context(), vars["name"], vars["alias"]); err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, map[string]string{
+		"message": "Alias deleted successfully",
+	})
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"GoFaas/internal/api/common"
+	"GoFaas/internal/core/registry"
+	"GoFaas/internal/observability/logging"
+)
+
+// WorkerHandler handles worker administration requests
+type WorkerHandler struct {
+	service *registry.Service
+	logger  logging.Logger
+}
+
+// NewWorkerHandler creates a new worker handler
+func NewWorkerHandler(service *registry.Service, logger logging.Logger) *WorkerHandler {
+	return &WorkerHandler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// ListWorkers handles listing the registered workers
+func (h *WorkerHandler) ListWorkers(w http.ResponseWriter, r *http.Request) {
+	workers, err := h.service.List(r.Context())
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, workers)
+}
+
+// GetWorker handles retrieval of a registered worker
+func (h *WorkerHandler) GetWorker(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	worker, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, worker)
+}
+
+// DrainWorker handles stopping a worker from taking new messages
+func (h *WorkerHandler) DrainWorker(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	worker, err := h.service.Drain(r.Context(), id)
+	if err != nil {
+		common.WriteError(w, err)
+		return
+	}
+
+	common.WriteJSON(w, http.StatusOK, worker)
+}
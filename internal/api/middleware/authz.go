@@ -17,6 +17,8 @@ const (
 	PermissionFunctionDelete Permission = "function:delete"
 	PermissionFunctionInvoke Permission = "function:invoke"
 	PermissionInvocationRead Permission = "invocation:read"
+	PermissionQueueManage    Permission = "queue:manage"
+	PermissionWorkerManage   Permission = "worker:manage"
 	PermissionAdminAll       Permission = "admin:*"
 )
 
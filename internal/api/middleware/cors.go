@@ -31,7 +31,7 @@ func NewCORSMiddleware(cfg CORSConfig) *CORSMiddleware {
 		cfg.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
 	}
 	if len(cfg.AllowedHeaders) == 0 {
-		cfg.AllowedHeaders = []string{"Content-Type", "Authorization"}
+		cfg.AllowedHeaders = []string{"Content-Type", "Authorization", "Idempotency-Key"}
 	}
 	if cfg.MaxAge == 0 {
 		cfg.MaxAge = 3600 // 1 hour
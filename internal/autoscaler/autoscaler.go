@@ -0,0 +1,358 @@
+package autoscaler
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"GoFaas/internal/core/invocation"
+	"GoFaas/internal/core/registry"
+	"GoFaas/internal/messaging"
+	"GoFaas/internal/observability/logging"
+	"GoFaas/internal/storage/metadata"
+	"GoFaas/pkg/types"
+)
+
+// Config holds autoscaler configuration
+type Config struct {
+	MinWorkers     int                 // Workers kept running even without messages
+	MaxWorkers     int                 // Upper bound on the workers started
+	SlotsPerWorker int                 // Executions a started worker runs concurrently; fewer than 1 means 1
+	Runtime        types.WorkerRuntime // Runtime of the started workers, matched against function placements
+	Labels         map[string]string   // Labels of the started workers, matched against function placements
+
+	// How often the queues and workers are checked
+	Interval time.Duration
+	// Workers are started at most this often, unless there are fewer than
+	// MinWorkers
+	ScaleUpCooldown time.Duration
+	// A worker is drained only this long after the last worker was started
+	// or drained
+	ScaleDownCooldown time.Duration
+	// A worker is added while the oldest ready message waited longer than
+	// this, even if the workers have slots for the backlog; zero disables
+	MaxMessageAge time.Duration
+	// A started worker that has not registered by then is stopped
+	StartupTimeout time.Duration
+}
+
+// Autoscaler starts and stops workers through a provider to follow the
+// backlog of the execution queues its workers take messages from. The
+// backlog is the ready and in-flight messages; it calls for a worker per
+// SlotsPerWorker messages, less the slots of active workers the provider
+// did not start. Workers are scaled down by draining the least busy one
+// and stopping it once it reports drained, so no execution is cut short.
+type Autoscaler struct {
+	provider     Provider
+	queue        messaging.Queue
+	functionRepo metadata.FunctionRepository
+	registry     *registry.Service
+	cfg          Config
+	logger       logging.Logger
+
+	started     map[string]time.Time // When each of the provider's workers was started
+	stopping    map[string]bool      // Provider's workers told to stop
+	lastScaleUp time.Time
+	lastScale   time.Time
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewAutoscaler creates a new autoscaler
+func NewAutoscaler(
+	provider Provider,
+	queue messaging.Queue,
+	functionRepo metadata.FunctionRepository,
+	registry *registry.Service,
+	cfg Config,
+	logger logging.Logger,
+) *Autoscaler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Second
+	}
+	if cfg.SlotsPerWorker < 1 {
+		cfg.SlotsPerWorker = 1
+	}
+	if cfg.MinWorkers < 0 {
+		cfg.MinWorkers = 0
+	}
+	if cfg.MaxWorkers < cfg.MinWorkers {
+		cfg.MaxWorkers = cfg.MinWorkers
+	}
+
+	return &Autoscaler{
+		provider:     provider,
+		queue:        queue,
+		functionRepo: functionRepo,
+		registry:     registry,
+		cfg:          cfg,
+		logger:       logger,
+		started:      make(map[string]time.Time),
+		stopping:     make(map[string]bool),
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// Start begins checking the queues and workers in the background
+func (a *Autoscaler) Start() {
+	a.wg.Add(1)
+	go a.loop()
+
+	a.logger.Info("Autoscaler started",
+		logging.F("min_workers", a.cfg.MinWorkers),
+		logging.F("max_workers", a.cfg.MaxWorkers),
+		logging.F("interval", a.cfg.Interval),
+	)
+}
+
+// Stop stops the autoscaler. The workers it started keep running.
+func (a *Autoscaler) Stop() {
+	close(a.stopCh)
+	a.wg.Wait()
+}
+
+// loop checks right away and then every interval until the autoscaler is
+// stopped
+func (a *Autoscaler) loop() {
+	defer a.wg.Done()
+
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		a.check(ctx)
+		cancel()
+
+		select {
+		case <-a.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check stops the provider's workers that are done or lost, compares the
+// backlog with the workers left and starts or drains workers to match it
+func (a *Autoscaler) check(ctx context.Context) {
+	ids, err := a.provider.ListWorkers(ctx)
+	if err != nil {
+		a.logger.Error("Failed to list provider workers", logging.F("error", err))
+		return
+	}
+
+	workers, err := a.registry.List(ctx)
+	if err != nil {
+		a.logger.Error("Failed to list registered workers", logging.F("error", err))
+		return
+	}
+
+	backlog, oldest, err := a.backlog(ctx)
+	if err != nil {
+		a.logger.Error("Failed to read queue backlog", logging.F("error", err))
+		return
+	}
+
+	now := time.Now()
+	active, starting := a.reconcile(ctx, ids, workers, now)
+
+	// Active workers the provider did not start take part of the backlog
+	managed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		managed[id] = true
+	}
+	var otherSlots int64
+	for _, w := range workers {
+		if !managed[w.ID] && w.Status == types.WorkerActive {
+			otherSlots += int64(w.Slots)
+		}
+	}
+
+	current := len(active) + starting
+	desired := a.desired(backlog-otherSlots, oldest, current)
+
+	switch {
+	case desired > current:
+		if current >= a.cfg.MinWorkers && now.Sub(a.lastScaleUp) < a.cfg.ScaleUpCooldown {
+			return
+		}
+
+		a.logger.Info("Scaling up workers",
+			logging.F("workers", current),
+			logging.F("desired", desired),
+			logging.F("backlog", backlog),
+			logging.F("oldest_message_age", oldest),
+		)
+		for i := current; i < desired; i++ {
+			id, err := a.provider.StartWorker(ctx)
+			if err != nil {
+				a.logger.Error("Failed to start worker", logging.F("error", err))
+				break
+			}
+			a.started[id] = now
+		}
+		a.lastScaleUp = now
+		a.lastScale = now
+
+	case desired < current && len(active) > 0:
+		if now.Sub(a.lastScale) < a.cfg.ScaleDownCooldown {
+			return
+		}
+
+		// Drain the least busy worker; it is stopped once it reports drained
+		sort.Slice(active, func(i, j int) bool {
+			return active[i].InFlight < active[j].InFlight
+		})
+		w := active[0]
+
+		a.logger.Info("Scaling down workers",
+			logging.F("workers", current),
+			logging.F("desired", desired),
+			logging.F("backlog", backlog),
+			logging.F("worker_id", w.ID),
+		)
+		if _, err := a.registry.Drain(ctx, w.ID); err != nil {
+			a.logger.Error("Failed to drain worker",
+				logging.F("worker_id", w.ID),
+				logging.F("error", err),
+			)
+			return
+		}
+		a.lastScale = now
+	}
+}
+
+// reconcile stops the provider's workers that drained, went offline or
+// never registered, and returns those that are active and the number still
+// starting up
+func (a *Autoscaler) reconcile(ctx context.Context, ids []string, workers []*types.Worker, now time.Time) ([]*types.Worker, int) {
+	registered := make(map[string]*types.Worker, len(workers))
+	for _, w := range workers {
+		registered[w.ID] = w
+	}
+
+	// Forget workers whose process is gone
+	running := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		running[id] = true
+	}
+	for id := range a.started {
+		if !running[id] {
+			delete(a.started, id)
+			delete(a.stopping, id)
+		}
+	}
+
+	active := make([]*types.Worker, 0, len(ids))
+	starting := 0
+	for _, id := range ids {
+		if a.stopping[id] {
+			continue
+		}
+
+		if _, ok := a.started[id]; !ok {
+			// Started before the autoscaler knew of it
+			a.started[id] = now
+		}
+
+		w, ok := registered[id]
+		if !ok {
+			if now.Sub(a.started[id]) < a.cfg.StartupTimeout {
+				starting++
+				continue
+			}
+			a.stop(ctx, id, "did not register in time")
+			continue
+		}
+
+		switch w.Status {
+		case types.WorkerActive:
+			active = append(active, w)
+		case types.WorkerDrained:
+			a.stop(ctx, id, "drained")
+		case types.WorkerOffline:
+			a.stop(ctx, id, "stopped sending heartbeats")
+		}
+	}
+
+	return active, starting
+}
+
+// stop stops one of the provider's workers
+func (a *Autoscaler) stop(ctx context.Context, id, reason string) {
+	a.logger.Info("Stopping worker",
+		logging.F("worker_id", id),
+		logging.F("reason", reason),
+	)
+
+	if err := a.provider.StopWorker(ctx, id); err != nil {
+		a.logger.Error("Failed to stop worker",
+			logging.F("worker_id", id),
+			logging.F("error", err),
+		)
+		return
+	}
+	a.stopping[id] = true
+}
+
+// desired returns how many workers the autoscaler should run for a
+// backlog, within the configured bounds
+func (a *Autoscaler) desired(backlog int64, oldest time.Duration, current int) int {
+	slots := int64(a.cfg.SlotsPerWorker)
+	desired := 0
+	if backlog > 0 {
+		desired = int((backlog + slots - 1) / slots)
+	}
+
+	// Messages waiting too long mean the workers fall behind, for example
+	// because their resource budgets are smaller than their slots
+	if a.cfg.MaxMessageAge > 0 && oldest > a.cfg.MaxMessageAge && desired <= current {
+		desired = current + 1
+	}
+
+	if desired < a.cfg.MinWorkers {
+		desired = a.cfg.MinWorkers
+	}
+	if desired > a.cfg.MaxWorkers {
+		desired = a.cfg.MaxWorkers
+	}
+	return desired
+}
+
+// backlog returns the ready and in-flight messages of the execution queues
+// the started workers take messages from, and how long the oldest ready
+// message of any of them has waited
+func (a *Autoscaler) backlog(ctx context.Context) (int64, time.Duration, error) {
+	placements, err := a.functionRepo.ListPlacements(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	queues := invocation.ExecutionQueues()
+	for _, placement := range placements {
+		if !placement.Matches(a.cfg.Runtime, a.cfg.Labels) {
+			continue
+		}
+		for _, priority := range types.Priorities {
+			queues = append(queues, invocation.PlacementQueue(priority, placement))
+		}
+	}
+
+	var backlog int64
+	var oldest time.Duration
+	for _, queue := range queues {
+		stats, err := a.queue.GetStats(ctx, queue)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		backlog += stats.Size + stats.InFlight
+		if stats.OldestMessageAge > oldest {
+			oldest = stats.OldestMessageAge
+		}
+	}
+
+	return backlog, oldest, nil
+}
@@ -0,0 +1,158 @@
+package autoscaler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"syscall"
+
+	"GoFaas/internal/observability/logging"
+)
+
+// LocalConfig holds local process provider configuration
+type LocalConfig struct {
+	Command  string   // Worker binary
+	Args     []string // Arguments of the worker binary
+	Env      []string // Environment of the workers, in addition to WORKER_ID; the provider's own if nil
+	IDPrefix string   // Worker IDs are the prefix and a sequence number
+}
+
+// LocalProvider runs workers as child processes of the autoscaler
+type LocalProvider struct {
+	cfg    LocalConfig
+	logger logging.Logger
+
+	mu      sync.Mutex
+	seq     int
+	workers map[string]*localWorker
+}
+
+// localWorker is a running worker process
+type localWorker struct {
+	cmd    *exec.Cmd
+	exited chan struct{} // Closed once the process exited
+}
+
+// NewLocalProvider creates a new local process provider
+func NewLocalProvider(cfg LocalConfig, logger logging.Logger) *LocalProvider {
+	if cfg.Env == nil {
+		cfg.Env = os.Environ()
+	}
+
+	return &LocalProvider{
+		cfg:     cfg,
+		logger:  logger,
+		workers: make(map[string]*localWorker),
+	}
+}
+
+// StartWorker implements Provider.StartWorker. The worker writes its logs
+// to the provider's standard output and error.
+func (p *LocalProvider) StartWorker(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seq++
+	id := fmt.Sprintf("%s-%d", p.cfg.IDPrefix, p.seq)
+
+	cmd := exec.Command(p.cfg.Command, p.cfg.Args...)
+	cmd.Env = append(append([]string(nil), p.cfg.Env...), "WORKER_ID="+id)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start worker process: %w", err)
+	}
+
+	worker := &localWorker{cmd: cmd, exited: make(chan struct{})}
+	p.workers[id] = worker
+
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			p.logger.Warn("Worker process exited",
+				logging.F("worker_id", id),
+				logging.F("error", err),
+			)
+		} else {
+			p.logger.Info("Worker process exited", logging.F("worker_id", id))
+		}
+
+		p.mu.Lock()
+		delete(p.workers, id)
+		p.mu.Unlock()
+		close(worker.exited)
+	}()
+
+	p.logger.Info("Worker process started",
+		logging.F("worker_id", id),
+		logging.F("pid", cmd.Process.Pid),
+	)
+
+	return id, nil
+}
+
+// StopWorker implements Provider.StopWorker by sending SIGTERM, on which
+// a worker drains and exits. It does not wait for the process to exit.
+func (p *LocalProvider) StopWorker(ctx context.Context, id string) error {
+	p.mu.Lock()
+	worker, ok := p.workers[id]
+	p.mu.Unlock()
+
+	if !ok {
+		// Already exited
+		return nil
+	}
+
+	if err := worker.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		return fmt.Errorf("failed to stop worker process %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// ListWorkers implements Provider.ListWorkers
+func (p *LocalProvider) ListWorkers(ctx context.Context) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ids := make([]string, 0, len(p.workers))
+	for id := range p.workers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// Shutdown stops every worker process and waits for them to exit. Those
+// still running when ctx is done are killed.
+func (p *LocalProvider) Shutdown(ctx context.Context) {
+	p.mu.Lock()
+	workers := make(map[string]*localWorker, len(p.workers))
+	for id, worker := range p.workers {
+		workers[id] = worker
+	}
+	p.mu.Unlock()
+
+	for id, worker := range workers {
+		if err := worker.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+			p.logger.Warn("Failed to stop worker process",
+				logging.F("worker_id", id),
+				logging.F("error", err),
+			)
+		}
+	}
+
+	for id, worker := range workers {
+		select {
+		case <-worker.exited:
+		case <-ctx.Done():
+			p.logger.Warn("Killing worker process", logging.F("worker_id", id))
+			worker.cmd.Process.Kill()
+			<-worker.exited
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package autoscaler
+
+import "context"
+
+// Provider starts and stops the workers of an autoscaler, for example as
+// local processes or as instances of a cloud provider
+type Provider interface {
+	// StartWorker starts a worker and returns the ID it registers under
+	StartWorker(ctx context.Context) (string, error)
+
+	// StopWorker stops a worker started by the provider. The worker
+	// finishes its executions in progress, up to its drain deadline.
+	StopWorker(ctx context.Context, id string) error
+
+	// ListWorkers returns the IDs of the workers started by the provider
+	// that are still running
+	ListWorkers(ctx context.Context) ([]string, error)
+}
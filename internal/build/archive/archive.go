@@ -0,0 +1,274 @@
+// Package archive unpacks function code archives and packs build output
+// into bundles. Extraction is confined to the target directory and bounded
+// in size, since archives are uploaded by users.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"GoFaas/pkg/types"
+)
+
+const (
+	// MaxExtractedBytes caps the total size of the files in an archive
+	MaxExtractedBytes = 512 * 1024 * 1024
+
+	// MaxFiles caps the number of entries in an archive
+	MaxFiles = 20000
+)
+
+// entry is a regular file or directory read from an archive
+type entry struct {
+	name string
+	mode fs.FileMode
+	dir  bool
+	open func() (io.ReadCloser, error)
+}
+
+// Validate checks that data is a well-formed archive of the given format
+// whose entries stay within the extraction limits
+func Validate(data []byte, format types.CodeFormat) error {
+	return walk(data, format, func(e entry) error { return nil })
+}
+
+// Extract unpacks an archive into dir. Symlinks and other special files are
+// skipped.
+func Extract(data []byte, format types.CodeFormat, dir string) error {
+	return walk(data, format, func(e entry) error {
+		target := filepath.Join(dir, filepath.FromSlash(e.name))
+
+		if e.dir {
+			return os.MkdirAll(target, 0755)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+
+		r, err := e.open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, e.mode.Perm()|0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, r); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+// Pack creates a tar.gz bundle of the files under dir, keeping their modes
+func Pack(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack bundle: %w", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to pack bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to pack bundle: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// walk calls fn for every regular file and directory in the archive after
+// checking its name and the running size and entry totals
+func walk(data []byte, format types.CodeFormat, fn func(entry) error) error {
+	var total int64
+	var count int
+
+	visit := func(e entry, size int64) error {
+		name, err := cleanName(e.name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return nil
+		}
+		e.name = name
+
+		count++
+		if count > MaxFiles {
+			return fmt.Errorf("archive has more than %d entries", MaxFiles)
+		}
+		total += size
+		if total > MaxExtractedBytes {
+			return fmt.Errorf("archive expands to more than %d bytes", MaxExtractedBytes)
+		}
+
+		return fn(e)
+	}
+
+	switch format {
+	case types.CodeFormatZip:
+		return walkZip(data, visit)
+	case types.CodeFormatTarGz:
+		return walkTarGz(data, visit)
+	default:
+		return fmt.Errorf("unsupported archive format: %s", format)
+	}
+}
+
+func walkZip(data []byte, visit func(entry, int64) error) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	for _, f := range zr.File {
+		mode := f.Mode()
+		if !mode.IsRegular() && !mode.IsDir() {
+			continue
+		}
+
+		f := f
+		e := entry{
+			name: f.Name,
+			mode: mode,
+			dir:  mode.IsDir(),
+			open: func() (io.ReadCloser, error) {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				// The header size is not trusted; the copy is bounded too
+				return limitReadCloser(rc, int64(f.UncompressedSize64)), nil
+			},
+		}
+		if err := visit(e, int64(f.UncompressedSize64)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func walkTarGz(data []byte, visit func(entry, int64) error) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("invalid tar.gz archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar.gz archive: %w", err)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeDir:
+		default:
+			continue
+		}
+
+		e := entry{
+			name: hdr.Name,
+			mode: fs.FileMode(hdr.Mode).Perm(),
+			dir:  hdr.Typeflag == tar.TypeDir,
+			open: func() (io.ReadCloser, error) {
+				return io.NopCloser(tr), nil
+			},
+		}
+		if err := visit(e, hdr.Size); err != nil {
+			return err
+		}
+	}
+}
+
+// cleanName normalizes an archive entry name and rejects names that would
+// escape the extraction directory
+func cleanName(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if path.IsAbs(name) {
+		return "", fmt.Errorf("archive entry has absolute path: %s", name)
+	}
+
+	cleaned := path.Clean(name)
+	if cleaned == "." {
+		return "", nil
+	}
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("archive entry escapes the function directory: %s", name)
+	}
+
+	return cleaned, nil
+}
+
+// limitReadCloser bounds reads from rc to n bytes
+func limitReadCloser(rc io.ReadCloser, n int64) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(rc, n), rc}
+}
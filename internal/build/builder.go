@@ -0,0 +1,43 @@
+// Package build compiles function code into deployable artifacts when a
+// function is created or updated, so workers do not pay the compile or
+// dependency installation cost on every invocation.
+package build
+
+import (
+	"context"
+
+	"GoFaas/pkg/types"
+)
+
+// Builder turns function source into an artifact the runtimes can execute
+type Builder interface {
+	// Build compiles spec. The returned result carries the build logs even
+	// when the build fails.
+	Build(ctx context.Context, spec Spec) (*Result, error)
+}
+
+// Spec describes the function code to build
+type Spec struct {
+	FunctionID string
+	Runtime    types.RuntimeType
+	Handler    string
+	Format     types.CodeFormat
+	Code       []byte
+}
+
+// Result is the output of a build. For single-file Go functions the
+// artifact is the executable itself; for archives it is a tar.gz bundle of
+// the function directory with its dependencies installed (see package
+// archive), which workers extract in place of the uploaded code.
+type Result struct {
+	Artifact []byte // Build output
+	Logs     string // Combined output of the build commands
+}
+
+// NeedsBuild reports whether functions with the given runtime and code
+// format are built at deploy time. Go is always compiled; archives of
+// interpreted runtimes get their dependencies installed. Single-file
+// Python and Node.js functions run their source directly.
+func NeedsBuild(runtime types.RuntimeType, format types.CodeFormat) bool {
+	return runtime == types.RuntimeGo || format.IsArchive()
+}
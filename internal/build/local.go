@@ -0,0 +1,265 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"time"
+
+	"GoFaas/internal/build/archive"
+	"GoFaas/internal/worker/runtime/bootstrap"
+	"GoFaas/pkg/types"
+)
+
+const (
+	// maxLogBytes caps the build output kept per build
+	maxLogBytes = 64 * 1024
+
+	// pythonPackagesDir is where requirements.txt is installed inside the
+	// bundle; the Python bootstrap adds it to sys.path
+	pythonPackagesDir = ".packages"
+
+	// goModule is the module path given to Go archives without a go.mod
+	goModule = "function"
+)
+
+// LocalConfig holds local builder configuration
+type LocalConfig struct {
+	WorkDir string        // Scratch space for build directories
+	Timeout time.Duration // Upper bound on a single build
+	GOOS    string        // Target OS of built binaries
+	GOARCH  string        // Target architecture of built binaries
+}
+
+// LocalBuilder implements Builder with the toolchains installed on the
+// host (go, pip and npm). Go binaries are linked statically so they run
+// unchanged in the runtime containers and under SimpleRuntime.
+type LocalBuilder struct {
+	cfg LocalConfig
+}
+
+// NewLocalBuilder creates a new local builder
+func NewLocalBuilder(cfg LocalConfig) (*LocalBuilder, error) {
+	if err := os.MkdirAll(cfg.WorkDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create build directory: %w", err)
+	}
+	// Build commands run in subdirectories, so paths must not be relative
+	workDir, err := filepath.Abs(cfg.WorkDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve build directory: %w", err)
+	}
+	cfg.WorkDir = workDir
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Minute
+	}
+	if cfg.GOOS == "" {
+		cfg.GOOS = "linux"
+	}
+	if cfg.GOARCH == "" {
+		cfg.GOARCH = goruntime.GOARCH
+	}
+
+	return &LocalBuilder{cfg: cfg}, nil
+}
+
+// Build implements Builder.Build
+func (b *LocalBuilder) Build(ctx context.Context, spec Spec) (*Result, error) {
+	dir, err := os.MkdirTemp(b.cfg.WorkDir, spec.FunctionID+"-")
+	if err != nil {
+		return &Result{}, fmt.Errorf("failed to create build directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	buildCtx, cancel := context.WithTimeout(ctx, b.cfg.Timeout)
+	defer cancel()
+
+	logs := &bytes.Buffer{}
+	artifact, err := b.build(buildCtx, spec, dir, logs)
+	result := &Result{Artifact: artifact, Logs: truncateLogs(logs.String())}
+
+	if buildCtx.Err() == context.DeadlineExceeded {
+		return result, fmt.Errorf("build timed out after %s", b.cfg.Timeout)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// build lays out the function source under dir and runs the runtime's
+// build steps
+func (b *LocalBuilder) build(ctx context.Context, spec Spec, dir string, logs *bytes.Buffer) ([]byte, error) {
+	srcDir := filepath.Join(dir, "src")
+	if err := os.MkdirAll(srcDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create source directory: %w", err)
+	}
+
+	if spec.Format.IsArchive() {
+		if err := archive.Extract(spec.Code, spec.Format, srcDir); err != nil {
+			return nil, fmt.Errorf("failed to extract code archive: %w", err)
+		}
+	} else {
+		if err := os.WriteFile(filepath.Join(srcDir, sourceFileName(spec.Runtime)), spec.Code, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write function code: %w", err)
+		}
+	}
+
+	switch spec.Runtime {
+	case types.RuntimeGo:
+		return b.buildGo(ctx, spec, dir, srcDir, logs)
+	case types.RuntimePython:
+		return b.buildPython(ctx, spec, srcDir, logs)
+	case types.RuntimeNodeJS:
+		return b.buildNode(ctx, spec, srcDir, logs)
+	default:
+		return nil, fmt.Errorf("unsupported build runtime: %s", spec.Runtime)
+	}
+}
+
+// buildGo compiles the function and its bootstrap into a static binary.
+// Archives are built as a module, downloading the dependencies in go.mod.
+func (b *LocalBuilder) buildGo(ctx context.Context, spec Spec, dir, srcDir string, logs *bytes.Buffer) ([]byte, error) {
+	file, err := bootstrap.Generate(spec.Runtime, spec.Handler)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, file.Name), file.Content, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write bootstrap: %w", err)
+	}
+
+	outDir := filepath.Join(dir, "out")
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	binary := filepath.Join(outDir, "handler")
+
+	args := []string{"build", "-trimpath", "-o", binary}
+	if spec.Format.IsArchive() {
+		if !fileExists(filepath.Join(srcDir, "go.mod")) {
+			gomod := fmt.Sprintf("module %s\n\ngo 1.21\n", goModule)
+			if err := os.WriteFile(filepath.Join(srcDir, "go.mod"), []byte(gomod), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write go.mod: %w", err)
+			}
+		}
+		// Let go resolve missing go.sum entries unless dependencies are vendored
+		if !fileExists(filepath.Join(srcDir, "vendor", "modules.txt")) {
+			args = append(args, "-mod=mod")
+		}
+		args = append(args, ".")
+	} else {
+		args = append(args, sourceFileName(spec.Runtime), file.Name)
+	}
+
+	env := []string{"CGO_ENABLED=0", "GOOS=" + b.cfg.GOOS, "GOARCH=" + b.cfg.GOARCH}
+	if err := run(ctx, srcDir, env, logs, "go", args...); err != nil {
+		return nil, fmt.Errorf("go build failed: %w", err)
+	}
+
+	if spec.Format.IsArchive() {
+		return archive.Pack(outDir)
+	}
+
+	artifact, err := os.ReadFile(binary)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build output: %w", err)
+	}
+	return artifact, nil
+}
+
+// buildPython installs requirements.txt into the bundle. Only wheels are
+// installed: building a source distribution runs its setup code, which
+// would let an uploader execute arbitrary code on the build host.
+func (b *LocalBuilder) buildPython(ctx context.Context, spec Spec, srcDir string, logs *bytes.Buffer) ([]byte, error) {
+	module, _ := bootstrap.SplitHandler(spec.Handler)
+	module = strings.ReplaceAll(module, ".", "/")
+	if !fileExists(filepath.Join(srcDir, module+".py")) && !fileExists(filepath.Join(srcDir, module, "__init__.py")) {
+		return nil, fmt.Errorf("handler module %s not found in archive", module)
+	}
+
+	if fileExists(filepath.Join(srcDir, "requirements.txt")) {
+		err := run(ctx, srcDir, nil, logs, "python3", "-m", "pip", "install",
+			"--no-cache-dir", "--disable-pip-version-check", "--only-binary=:all:",
+			"--target", pythonPackagesDir, "-r", "requirements.txt")
+		if err != nil {
+			return nil, fmt.Errorf("pip install failed: %w", err)
+		}
+	}
+
+	return archive.Pack(srcDir)
+}
+
+// buildNode installs the production dependencies in package.json into the
+// bundle. Lifecycle scripts are skipped, as they would run code from the
+// upload and its dependencies on the build host.
+func (b *LocalBuilder) buildNode(ctx context.Context, spec Spec, srcDir string, logs *bytes.Buffer) ([]byte, error) {
+	module, _ := bootstrap.SplitHandler(spec.Handler)
+	found := false
+	for _, candidate := range []string{module, module + ".js", filepath.Join(module, "index.js"), filepath.Join(module, "package.json")} {
+		if fileExists(filepath.Join(srcDir, candidate)) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("handler module %s not found in archive", module)
+	}
+
+	if fileExists(filepath.Join(srcDir, "package.json")) {
+		command := "install"
+		if fileExists(filepath.Join(srcDir, "package-lock.json")) {
+			command = "ci"
+		}
+		if err := run(ctx, srcDir, nil, logs, "npm", command, "--omit=dev", "--ignore-scripts", "--no-audit", "--no-fund"); err != nil {
+			return nil, fmt.Errorf("npm %s failed: %w", command, err)
+		}
+	}
+
+	return archive.Pack(srcDir)
+}
+
+// run executes a build command in dir, appending the command line and its
+// output to logs
+func run(ctx context.Context, dir string, env []string, logs *bytes.Buffer, name string, args ...string) error {
+	fmt.Fprintf(logs, "$ %s %s\n", name, strings.Join(args, " "))
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = logs
+	cmd.Stderr = logs
+
+	return cmd.Run()
+}
+
+// sourceFileName returns the file name single-file functions are stored
+// under, matching what the runtimes expect
+func sourceFileName(runtime types.RuntimeType) string {
+	switch runtime {
+	case types.RuntimeGo:
+		return "main.go"
+	case types.RuntimePython:
+		return "main.py"
+	default:
+		return "main.js"
+	}
+}
+
+// fileExists reports whether path exists and is a regular file
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// truncateLogs keeps the end of the build output, where errors are
+func truncateLogs(logs string) string {
+	if len(logs) <= maxLogBytes {
+		return logs
+	}
+	return "... (truncated)\n" + logs[len(logs)-maxLogBytes:]
+}
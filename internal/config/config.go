@@ -3,16 +3,25 @@ package config
 import (
 	"fmt"
 	"os"
+	"runtime"
+	"strings"
 	"time"
 )
 
 // Config holds application configuration
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	Storage  StorageConfig
-	Worker   WorkerConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	Redis      RedisConfig
+	Queue      QueueConfig
+	Storage    StorageConfig
+	Worker     WorkerConfig
+	Invocation InvocationConfig
+	Build      BuildConfig
+	Canary     CanaryConfig
+	Scheduler  SchedulerConfig
+	Callback   CallbackConfig
+	Autoscaler AutoscalerConfig
 }
 
 // ServerConfig holds HTTP server configuration
@@ -37,6 +46,13 @@ type RedisConfig struct {
 	DB       int
 }
 
+// QueueConfig holds message queue configuration
+type QueueConfig struct {
+	Type              string        // "redis" (lists), "redis-streams" or "postgres"
+	VisibilityTimeout time.Duration // A delivered message is reclaimed if its lease is not renewed within this period
+	ReapInterval      time.Duration // How often workers reclaim messages with expired leases
+}
+
 // StorageConfig holds storage configuration
 type StorageConfig struct {
 	Type    string // "local" or "s3"
@@ -49,6 +65,76 @@ type WorkerConfig struct {
 	WorkDir      string
 	RuntimeType  string // "simple" or "container"
 	UseContainer bool   // Enable container-based execution
+	Labels       map[string]string
+
+	// Warm container pool (container runtime only)
+	PoolMinIdle     int
+	PoolMaxIdle     int
+	PoolIdleTimeout time.Duration
+
+	// Relative share of dequeues per priority lane while every lane has messages
+	HighPriorityWeight   int
+	NormalPriorityWeight int
+	LowPriorityWeight    int
+
+	// Concurrent executions and the host resources they may reserve
+	Slots          int
+	CPUBudget      float64       // CPUs; each execution reserves its CPU limit, or one CPU
+	MemoryBudgetMB int           // Each execution reserves its memory limit; 0 for no budget
+	DrainTimeout   time.Duration // How long a stopping worker waits for its executions
+
+	// Invocations of a function at its max_concurrency wait about this long
+	// before they are tried again
+	ConcurrencyDeferDelay time.Duration
+}
+
+// InvocationConfig holds invocation API configuration
+type InvocationConfig struct {
+	SyncMaxWait       time.Duration // Upper bound on how long a sync invoke blocks
+	IdempotencyKeyTTL time.Duration // How long an idempotency key maps to its invocation
+}
+
+// BuildConfig holds deploy-time build configuration
+type BuildConfig struct {
+	WorkDir string        // Scratch space for builds
+	Timeout time.Duration // Upper bound on a single build
+	GOOS    string        // Target OS of compiled Go functions
+	GOARCH  string        // Target architecture of compiled Go functions
+}
+
+// CanaryConfig holds automatic canary rollback configuration
+type CanaryConfig struct {
+	CheckInterval    time.Duration // How often routed aliases are checked
+	Window           time.Duration // Invocations considered when computing the failure rate
+	FailureThreshold float64       // Failure rate (0-1) that triggers a rollback
+	MinInvocations   int           // Invocations required before a canary is judged
+}
+
+// SchedulerConfig holds cron scheduler configuration
+type SchedulerConfig struct {
+	Enabled      bool          // Run the scheduler loop in this controller
+	PollInterval time.Duration // How often due schedules are looked up
+	LeaseTTL     time.Duration // Leadership expires if not renewed within this period
+}
+
+// CallbackConfig holds invocation callback delivery configuration
+type CallbackConfig struct {
+	Timeout        time.Duration // Timeout of a single delivery request
+	MaxAttempts    int           // Deliveries attempted before giving up
+	InitialBackoff time.Duration // Wait before the first retry; doubled for each further retry
+	MaxBackoff     time.Duration // Upper bound on the wait between retries
+}
+
+// AutoscalerConfig holds worker autoscaler configuration
+type AutoscalerConfig struct {
+	MinWorkers        int
+	MaxWorkers        int
+	Interval          time.Duration // How often queues and workers are checked
+	ScaleUpCooldown   time.Duration // Minimum time between starting workers
+	ScaleDownCooldown time.Duration // Minimum time after starting or draining a worker before draining another
+	MaxMessageAge     time.Duration // A worker is added while the oldest ready message waited longer; 0 disables
+	StartupTimeout    time.Duration // A started worker that has not registered by then is stopped
+	WorkerCommand     string        // Worker binary run by the local provider
 }
 
 // Load loads configuration from environment variables
@@ -70,15 +156,73 @@ func Load() (*Config, error) {
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       getEnvInt("REDIS_DB", 0),
 		},
+		Queue: QueueConfig{
+			Type:              getEnv("QUEUE_TYPE", "redis"),
+			VisibilityTimeout: getEnvDuration("QUEUE_VISIBILITY_TIMEOUT", 30*time.Second),
+			ReapInterval:      getEnvDuration("QUEUE_REAP_INTERVAL", 10*time.Second),
+		},
 		Storage: StorageConfig{
 			Type:    getEnv("STORAGE_TYPE", "local"),
 			BaseDir: getEnv("STORAGE_BASE_DIR", "./storage/functions"),
 		},
 		Worker: WorkerConfig{
-			ID:           getEnv("WORKER_ID", fmt.Sprintf("worker-%d", time.Now().Unix())),
+			ID:           getEnv("WORKER_ID", defaultWorkerID()),
 			WorkDir:      getEnv("WORKER_WORK_DIR", "./storage/work"),
 			RuntimeType:  getEnv("WORKER_RUNTIME_TYPE", "container"),
 			UseContainer: getEnvBool("WORKER_USE_CONTAINER", true),
+			Labels:       getEnvLabels("WORKER_LABELS"),
+
+			PoolMinIdle:     getEnvInt("WORKER_POOL_MIN_IDLE", 0),
+			PoolMaxIdle:     getEnvInt("WORKER_POOL_MAX_IDLE", 2),
+			PoolIdleTimeout: getEnvDuration("WORKER_POOL_IDLE_TIMEOUT", 5*time.Minute),
+
+			HighPriorityWeight:   getEnvInt("WORKER_WEIGHT_HIGH", 6),
+			NormalPriorityWeight: getEnvInt("WORKER_WEIGHT_NORMAL", 3),
+			LowPriorityWeight:    getEnvInt("WORKER_WEIGHT_LOW", 1),
+
+			Slots:          getEnvInt("WORKER_SLOTS", runtime.NumCPU()),
+			CPUBudget:      getEnvFloat("WORKER_CPU_BUDGET", float64(runtime.NumCPU())),
+			MemoryBudgetMB: getEnvInt("WORKER_MEMORY_BUDGET_MB", hostMemoryMB()),
+			DrainTimeout:   getEnvDuration("WORKER_DRAIN_TIMEOUT", time.Minute),
+
+			ConcurrencyDeferDelay: getEnvDuration("WORKER_CONCURRENCY_DEFER_DELAY", time.Second),
+		},
+		Invocation: InvocationConfig{
+			SyncMaxWait:       getEnvDuration("INVOKE_SYNC_MAX_WAIT", 10*time.Second),
+			IdempotencyKeyTTL: getEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		},
+		Build: BuildConfig{
+			WorkDir: getEnv("BUILD_WORK_DIR", "./storage/build"),
+			Timeout: getEnvDuration("BUILD_TIMEOUT", 2*time.Minute),
+			GOOS:    getEnv("BUILD_GOOS", "linux"),
+			GOARCH:  getEnv("BUILD_GOARCH", runtime.GOARCH),
+		},
+		Canary: CanaryConfig{
+			CheckInterval:    getEnvDuration("CANARY_CHECK_INTERVAL", 30*time.Second),
+			Window:           getEnvDuration("CANARY_WINDOW", 5*time.Minute),
+			FailureThreshold: getEnvFloat("CANARY_FAILURE_THRESHOLD", 0.2),
+			MinInvocations:   getEnvInt("CANARY_MIN_INVOCATIONS", 20),
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:      getEnvBool("SCHEDULER_ENABLED", true),
+			PollInterval: getEnvDuration("SCHEDULER_POLL_INTERVAL", time.Second),
+			LeaseTTL:     getEnvDuration("SCHEDULER_LEASE_TTL", 15*time.Second),
+		},
+		Callback: CallbackConfig{
+			Timeout:        getEnvDuration("CALLBACK_TIMEOUT", 10*time.Second),
+			MaxAttempts:    getEnvInt("CALLBACK_MAX_ATTEMPTS", 8),
+			InitialBackoff: getEnvDuration("CALLBACK_INITIAL_BACKOFF", 5*time.Second),
+			MaxBackoff:     getEnvDuration("CALLBACK_MAX_BACKOFF", 10*time.Minute),
+		},
+		Autoscaler: AutoscalerConfig{
+			MinWorkers:        getEnvInt("AUTOSCALER_MIN_WORKERS", 1),
+			MaxWorkers:        getEnvInt("AUTOSCALER_MAX_WORKERS", 4),
+			Interval:          getEnvDuration("AUTOSCALER_INTERVAL", 15*time.Second),
+			ScaleUpCooldown:   getEnvDuration("AUTOSCALER_SCALE_UP_COOLDOWN", 30*time.Second),
+			ScaleDownCooldown: getEnvDuration("AUTOSCALER_SCALE_DOWN_COOLDOWN", 5*time.Minute),
+			MaxMessageAge:     getEnvDuration("AUTOSCALER_MAX_MESSAGE_AGE", 30*time.Second),
+			StartupTimeout:    getEnvDuration("AUTOSCALER_STARTUP_TIMEOUT", time.Minute),
+			WorkerCommand:     getEnv("AUTOSCALER_WORKER_COMMAND", "./bin/worker"),
 		},
 	}
 
@@ -112,6 +256,17 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvFloat gets a floating point environment variable or returns a default value
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		var floatValue float64
+		if _, err := fmt.Sscanf(value, "%g", &floatValue); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 // getEnvBool gets a boolean environment variable or returns a default value
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
@@ -120,6 +275,56 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+// getEnvDuration gets a duration environment variable or returns a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// defaultWorkerID names a worker after its host and process, which is
+// unique among running workers
+func defaultWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "worker"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
+
+// getEnvLabels gets comma-separated key=value labels from an environment
+// variable, skipping malformed entries
+func getEnvLabels(key string) map[string]string {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && name != "" {
+			labels[name] = value
+		}
+	}
+	return labels
+}
+
+// hostMemoryMB returns the total memory of the host, or 0 where it cannot
+// be read
+func hostMemoryMB() int {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		var kb int
+		if _, err := fmt.Sscanf(line, "MemTotal: %d kB", &kb); err == nil {
+			return kb / 1024
+		}
+	}
+	return 0
+}
+
 type SecurityConfig struct {
 	JWTSecret     string        `env:"JWT_SECRET" envDefault:"change-me-in-production"`
 	TokenDuration time.Duration `env:"TOKEN_DURATION" envDefault:"24h"`
@@ -0,0 +1,362 @@
+package callback
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"GoFaas/internal/core/invocation"
+	"GoFaas/internal/messaging"
+	"GoFaas/internal/observability/logging"
+	"GoFaas/internal/storage/metadata"
+	"GoFaas/pkg/errors"
+	"GoFaas/pkg/types"
+)
+
+// Headers sent with every callback delivery
+const (
+	HeaderInvocationID = "X-Faas-Invocation-Id"
+	HeaderAttempt      = "X-Faas-Delivery-Attempt"
+	HeaderTimestamp    = "X-Faas-Timestamp"
+	HeaderSignature    = "X-Faas-Signature" // Only sent if the invocation has a callback secret
+)
+
+// DispatcherConfig holds callback delivery configuration
+type DispatcherConfig struct {
+	ID             string        // Names this dispatcher among the consumers of the callback queue; empty sends no heartbeats
+	Timeout        time.Duration // Timeout of a single delivery request
+	MaxAttempts    int           // Deliveries attempted before giving up
+	InitialBackoff time.Duration // Wait before the first retry; doubled for each further retry
+	MaxBackoff     time.Duration // Upper bound on the wait between retries
+	ReapInterval   time.Duration // How often deliveries of a lost dispatcher are requeued; zero disables
+}
+
+// Dispatcher POSTs finished invocations to their callback URLs. Failed
+// deliveries are re-enqueued with exponential backoff, and every attempt is
+// recorded so it can be inspected per invocation.
+type Dispatcher struct {
+	queue          messaging.Queue
+	invocationRepo metadata.InvocationRepository
+	callbackRepo   metadata.CallbackRepository
+	client         *http.Client
+	cfg            DispatcherConfig
+	logger         logging.Logger
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher creates a new callback dispatcher
+func NewDispatcher(
+	queue messaging.Queue,
+	invocationRepo metadata.InvocationRepository,
+	callbackRepo metadata.CallbackRepository,
+	cfg DispatcherConfig,
+	logger logging.Logger,
+) *Dispatcher {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = time.Second
+	}
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = cfg.InitialBackoff
+	}
+
+	return &Dispatcher{
+		queue:          queue,
+		invocationRepo: invocationRepo,
+		callbackRepo:   callbackRepo,
+		client:         &http.Client{Timeout: cfg.Timeout},
+		cfg:            cfg,
+		logger:         logger,
+		stopCh:         make(chan struct{}),
+	}
+}
+
+// Start begins delivering callbacks in the background
+func (d *Dispatcher) Start() {
+	d.wg.Add(1)
+	go d.loop()
+
+	if d.cfg.ReapInterval > 0 {
+		d.wg.Add(1)
+		go d.reapLoop()
+	}
+
+	if d.cfg.ID != "" {
+		d.wg.Add(1)
+		go d.heartbeatLoop()
+	}
+
+	d.logger.Info("Callback dispatcher started", logging.F("max_attempts", d.cfg.MaxAttempts))
+}
+
+// Stop stops the dispatcher and waits for a delivery in progress to finish
+func (d *Dispatcher) Stop() {
+	close(d.stopCh)
+	d.wg.Wait()
+}
+
+// loop delivers queued callbacks until the dispatcher is stopped
+func (d *Dispatcher) loop() {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		default:
+		}
+
+		if err := d.processNext(context.Background()); err != nil {
+			d.logger.Error("Failed to process callback", logging.F("error", err))
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+// reapLoop requeues callbacks whose dispatcher stopped while delivering
+// them, until the dispatcher is stopped. Deliveries finish well within the
+// queue's visibility timeout, so their leases are not renewed.
+func (d *Dispatcher) reapLoop() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.cfg.ReapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		case <-ticker.C:
+			ctx := context.Background()
+			messages, err := d.queue.ClaimExpired(ctx, invocation.CallbackQueueName, 100)
+			if err != nil {
+				d.logger.Error("Failed to claim expired callbacks", logging.F("error", err))
+				continue
+			}
+
+			for _, msg := range messages {
+				if err := d.queue.Requeue(ctx, msg, 0); err != nil {
+					d.logger.Error("Failed to requeue expired callback",
+						logging.F("message_id", msg.ID),
+						logging.F("error", err),
+					)
+				}
+			}
+		}
+	}
+}
+
+// heartbeatLoop reports the dispatcher as an active consumer of the
+// callback queue until it is stopped
+func (d *Dispatcher) heartbeatLoop() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(messaging.ConsumerTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		if err := d.queue.Heartbeat(context.Background(), invocation.CallbackQueueName, d.cfg.ID); err != nil {
+			d.logger.Error("Failed to send queue heartbeat", logging.F("error", err))
+		}
+
+		select {
+		case <-d.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// processNext dequeues and delivers a single callback
+func (d *Dispatcher) processNext(ctx context.Context) error {
+	msg, err := d.queue.Dequeue(ctx, invocation.CallbackQueueName, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to dequeue callback: %w", err)
+	}
+	if msg == nil {
+		return nil
+	}
+
+	var req invocation.CallbackRequest
+	if err := json.Unmarshal(msg.Payload, &req); err != nil {
+		d.queue.DeadLetter(ctx, msg, fmt.Sprintf("invalid payload: %v", err))
+		return nil
+	}
+
+	inv, err := d.invocationRepo.GetInvocationByID(ctx, req.InvocationID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrCodeNotFound {
+			// Deleted along with its function
+			d.ack(ctx, msg)
+			return nil
+		}
+		d.queue.Nack(ctx, msg)
+		return err
+	}
+
+	if inv.CallbackURL != "" {
+		d.deliver(ctx, inv, req.Attempt)
+	}
+
+	d.ack(ctx, msg)
+	return nil
+}
+
+// deliver makes one delivery attempt, records it and schedules a retry if
+// it failed and attempts remain
+func (d *Dispatcher) deliver(ctx context.Context, inv *types.Invocation, attempt int) {
+	delivery := &types.CallbackDelivery{
+		ID:           uuid.New().String(),
+		InvocationID: inv.ID,
+		Attempt:      attempt,
+		URL:          inv.CallbackURL,
+		CreatedAt:    time.Now(),
+	}
+
+	statusCode, err := d.post(ctx, inv, attempt)
+	delivery.Duration = time.Since(delivery.CreatedAt)
+	delivery.StatusCode = statusCode
+
+	if err == nil {
+		delivery.Succeeded = true
+	} else {
+		delivery.Error = err.Error()
+
+		if attempt < d.cfg.MaxAttempts {
+			next := time.Now().Add(d.backoff(attempt))
+			if err := d.retry(ctx, inv.ID, attempt+1, next); err != nil {
+				d.logger.Error("Failed to schedule callback retry",
+					logging.F("invocation_id", inv.ID),
+					logging.F("error", err),
+				)
+			} else {
+				delivery.NextAttemptAt = &next
+			}
+		}
+	}
+
+	if err := d.callbackRepo.CreateCallbackDelivery(ctx, delivery); err != nil {
+		d.logger.Warn("Failed to record callback delivery",
+			logging.F("invocation_id", inv.ID),
+			logging.F("error", err),
+		)
+	}
+
+	fields := []logging.Field{
+		logging.F("invocation_id", inv.ID),
+		logging.F("attempt", attempt),
+		logging.F("status_code", statusCode),
+		logging.F("duration", delivery.Duration),
+	}
+	switch {
+	case delivery.Succeeded:
+		d.logger.Info("Callback delivered", fields...)
+	case delivery.NextAttemptAt != nil:
+		d.logger.Warn("Callback delivery failed, will retry", append(fields, logging.F("error", err))...)
+	default:
+		d.logger.Error("Callback delivery failed, giving up", append(fields, logging.F("error", err))...)
+	}
+}
+
+// post sends the invocation to its callback URL and returns the response
+// status, which is zero if no response was received. Any status other
+// than 2xx is an error.
+func (d *Dispatcher) post(ctx context.Context, inv *types.Invocation, attempt int) (int, error) {
+	body, err := json.Marshal(inv)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal invocation: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inv.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("invalid callback request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderInvocationID, inv.ID)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(attempt))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	if inv.CallbackSecret != "" {
+		req.Header.Set(HeaderSignature, Sign(inv.CallbackSecret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Drain a bounded amount so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// retry enqueues the given delivery attempt to run at the given time
+func (d *Dispatcher) retry(ctx context.Context, invocationID string, attempt int, at time.Time) error {
+	payload, err := json.Marshal(invocation.CallbackRequest{InvocationID: invocationID, Attempt: attempt})
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"invocation_id": invocationID,
+	}
+
+	return d.queue.EnqueueWithOptions(ctx, invocation.CallbackQueueName, payload, headers, messaging.EnqueueOptions{RunAt: at})
+}
+
+// backoff returns the wait after the given failed attempt
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.cfg.InitialBackoff
+	for i := 1; i < attempt && wait < d.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.cfg.MaxBackoff {
+		wait = d.cfg.MaxBackoff
+	}
+	return wait
+}
+
+// ack acknowledges a processed message, logging failures
+func (d *Dispatcher) ack(ctx context.Context, msg *messaging.Message) {
+	if err := d.queue.Ack(ctx, msg); err != nil {
+		d.logger.Warn("Failed to acknowledge callback message",
+			logging.F("message_id", msg.ID),
+			logging.F("error", err),
+		)
+	}
+}
+
+// Sign returns the signature header value of a callback body: the hex
+// encoded HMAC-SHA256, keyed with the callback secret, of the timestamp
+// header value, a period and the body
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
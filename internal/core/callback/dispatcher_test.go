@@ -0,0 +1,180 @@
+package callback
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"GoFaas/internal/core/invocation"
+	"GoFaas/internal/messaging"
+	"GoFaas/internal/observability/logging"
+	"GoFaas/internal/storage/metadata"
+	"GoFaas/pkg/types"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"inv-1"}`)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		want      string
+	}{
+		{
+			name:      "known vector",
+			secret:    "topsecret",
+			timestamp: 1700000000,
+			want:      "sha256=288ae0dd6bff8a9f465db4eab35f539e70d11331653fefde0a48796eba1b0739",
+		},
+		{
+			name:      "other secret",
+			secret:    "other",
+			timestamp: 1700000000,
+			want:      "sha256=8dad96ea9966eff25d0bb12d8f4c6a6b5ac9eaf6133d0909172f7d5838a6c7ca",
+		},
+		{
+			name:      "other timestamp",
+			secret:    "topsecret",
+			timestamp: 1700000001,
+			want:      "sha256=b4e7dbb5c31a79078f45a7bd386b25fbc8af210be3c261679357a71ca7ca8643",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, tt.timestamp, body); got != tt.want {
+				t.Fatalf("Sign = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(nil, nil, nil, DispatcherConfig{
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+	}, logging.NewSimpleLogger())
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 3, want: 4 * time.Second},
+		{attempt: 4, want: 8 * time.Second},
+		{attempt: 5, want: 10 * time.Second},
+		{attempt: 100, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempt), func(t *testing.T) {
+			if got := d.backoff(tt.attempt); got != tt.want {
+				t.Fatalf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		attempt     int
+		wantSuccess bool
+		wantRetry   bool
+	}{
+		{name: "delivered", status: http.StatusOK, attempt: 1, wantSuccess: true},
+		{name: "retried", status: http.StatusInternalServerError, attempt: 1, wantRetry: true},
+		{name: "last attempt", status: http.StatusInternalServerError, attempt: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			var got *http.Request
+			var gotBody []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				gotBody, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			repo := metadata.NewMemoryRepository()
+			now := time.Now()
+			if err := repo.Create(ctx, &types.Function{ID: "f1", Name: "hello", Version: "1.0.0", CreatedAt: now, UpdatedAt: now}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			inv := &types.Invocation{
+				ID:             "inv-1",
+				FunctionID:     "f1",
+				Status:         types.StatusCompleted,
+				CallbackURL:    server.URL,
+				CallbackSecret: "topsecret",
+				CreatedAt:      now,
+			}
+			if err := repo.CreateInvocation(ctx, inv); err != nil {
+				t.Fatalf("CreateInvocation: %v", err)
+			}
+
+			q := messaging.NewMemoryQueue(time.Minute)
+			d := NewDispatcher(q, repo, repo, DispatcherConfig{
+				MaxAttempts:    3,
+				InitialBackoff: time.Minute,
+			}, logging.NewSimpleLogger())
+
+			d.deliver(ctx, inv, tt.attempt)
+
+			if got == nil {
+				t.Fatal("callback was not sent")
+			}
+			if id := got.Header.Get(HeaderInvocationID); id != inv.ID {
+				t.Errorf("%s = %q, want %q", HeaderInvocationID, id, inv.ID)
+			}
+			if attempt := got.Header.Get(HeaderAttempt); attempt != strconv.Itoa(tt.attempt) {
+				t.Errorf("%s = %q, want %d", HeaderAttempt, attempt, tt.attempt)
+			}
+			timestamp, err := strconv.ParseInt(got.Header.Get(HeaderTimestamp), 10, 64)
+			if err != nil {
+				t.Fatalf("%s: %v", HeaderTimestamp, err)
+			}
+			if sig := got.Header.Get(HeaderSignature); sig != Sign(inv.CallbackSecret, timestamp, gotBody) {
+				t.Errorf("%s = %q does not sign the body", HeaderSignature, sig)
+			}
+
+			deliveries, err := repo.ListCallbackDeliveries(ctx, inv.ID)
+			if err != nil {
+				t.Fatalf("ListCallbackDeliveries: %v", err)
+			}
+			if len(deliveries) != 1 {
+				t.Fatalf("recorded %d deliveries, want 1", len(deliveries))
+			}
+			delivery := deliveries[0]
+			if delivery.Succeeded != tt.wantSuccess || delivery.StatusCode != tt.status || delivery.Attempt != tt.attempt {
+				t.Errorf("delivery = %+v, want succeeded %v, status %d, attempt %d", delivery, tt.wantSuccess, tt.status, tt.attempt)
+			}
+			if (delivery.NextAttemptAt != nil) != tt.wantRetry {
+				t.Errorf("NextAttemptAt = %v, want retry %v", delivery.NextAttemptAt, tt.wantRetry)
+			}
+
+			stats, err := q.GetStats(ctx, invocation.CallbackQueueName)
+			if err != nil {
+				t.Fatalf("GetStats: %v", err)
+			}
+			wantDelayed := int64(0)
+			if tt.wantRetry {
+				wantDelayed = 1
+			}
+			if stats.Delayed != wantDelayed {
+				t.Errorf("Delayed = %d, want %d", stats.Delayed, wantDelayed)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DeadLetter is an execution message that was given up on
+type DeadLetter struct {
+	MessageID      string          `json:"message_id"`
+	InvocationID   string          `json:"invocation_id,omitempty"`
+	FunctionID     string          `json:"function_id,omitempty"`
+	Reason         string          `json:"reason"`
+	Attempts       int             `json:"attempts"`
+	EnqueuedAt     time.Time       `json:"enqueued_at"`
+	DeadLetteredAt *time.Time      `json:"dead_lettered_at,omitempty"`
+	Request        json.RawMessage `json:"request"` // The queued execution request; a JSON string if it is not valid JSON
+}
+
+// Filter selects dead letters. Empty fields match everything.
+type Filter struct {
+	FunctionID string
+	Reason     string // Matches reasons containing this text
+	Limit      int
+	Offset     int
+}
+
+// ReplayResult reports the outcome of replaying one dead letter
+type ReplayResult struct {
+	MessageID    string `json:"message_id"`
+	InvocationID string `json:"invocation_id,omitempty"` // The new invocation
+	ReplayOf     string `json:"replay_of,omitempty"`     // The original invocation
+	Error        string `json:"error,omitempty"`
+}
@@ -0,0 +1,281 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"GoFaas/internal/core/invocation"
+	"GoFaas/internal/messaging"
+	"GoFaas/internal/observability/logging"
+	"GoFaas/pkg/errors"
+)
+
+// Service inspects, deletes and replays the dead-lettered messages of the
+// execution queues of every priority lane and placement
+type Service struct {
+	queue   messaging.Queue
+	invoker *invocation.Service
+	logger  logging.Logger
+}
+
+// NewService creates a new dead letter service
+func NewService(queue messaging.Queue, invoker *invocation.Service, logger logging.Logger) *Service {
+	return &Service{
+		queue:   queue,
+		invoker: invoker,
+		logger:  logger,
+	}
+}
+
+// List lists dead letters matching the filter, most recent first
+func (s *Service) List(ctx context.Context, filter Filter) ([]*DeadLetter, error) {
+	messages, err := s.match(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetters := make([]*DeadLetter, 0, len(messages))
+	for _, msg := range messages {
+		deadLetters = append(deadLetters, toDeadLetter(msg))
+	}
+
+	return deadLetters, nil
+}
+
+// Get retrieves a dead letter by message ID
+func (s *Service) Get(ctx context.Context, messageID string) (*DeadLetter, error) {
+	msg, err := s.find(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDeadLetter(msg), nil
+}
+
+// Delete deletes a dead letter
+func (s *Service) Delete(ctx context.Context, messageID string) error {
+	msg, err := s.find(ctx, messageID)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.queue.RemoveDeadLetter(ctx, msg.Queue, messageID)
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to delete dead letter: %v", err))
+	}
+	if !removed {
+		return errors.NotFound("dead letter", messageID)
+	}
+
+	s.logger.Info("Dead letter deleted", logging.F("message_id", messageID))
+
+	return nil
+}
+
+// DeleteMatching deletes the dead letters matching the filter and returns
+// how many were deleted
+func (s *Service) DeleteMatching(ctx context.Context, filter Filter) (int, error) {
+	messages, err := s.match(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, msg := range messages {
+		removed, err := s.queue.RemoveDeadLetter(ctx, msg.Queue, msg.ID)
+		if err != nil {
+			return deleted, errors.InternalError(fmt.Sprintf("failed to delete dead letter: %v", err))
+		}
+		if removed {
+			deleted++
+		}
+	}
+
+	s.logger.Info("Dead letters deleted",
+		logging.F("count", deleted),
+		logging.F("function_id", filter.FunctionID),
+		logging.F("reason", filter.Reason),
+	)
+
+	return deleted, nil
+}
+
+// Replay re-runs a dead letter as a new invocation linked to the original
+// one, and removes it from the dead letters
+func (s *Service) Replay(ctx context.Context, messageID string) (*invocation.InvocationHandle, error) {
+	msg, err := s.find(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.replay(ctx, msg)
+}
+
+// ReplayMatching replays the dead letters matching the filter. A failure
+// to replay one does not stop the others; each outcome is reported.
+func (s *Service) ReplayMatching(ctx context.Context, filter Filter) ([]*ReplayResult, error) {
+	messages, err := s.match(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*ReplayResult, 0, len(messages))
+	for _, msg := range messages {
+		result := &ReplayResult{MessageID: msg.ID}
+
+		handle, err := s.replay(ctx, msg)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.InvocationID = handle.InvocationID
+			result.ReplayOf = handle.ReplayOf
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// replay claims a dead letter by removing it, so concurrent replays of the
+// same message cannot both succeed, and enqueues its execution request as
+// a new invocation. If that fails the message is dead-lettered again.
+func (s *Service) replay(ctx context.Context, msg *messaging.Message) (*invocation.InvocationHandle, error) {
+	var execReq invocation.ExecutionRequest
+	if err := json.Unmarshal(msg.Payload, &execReq); err != nil || execReq.InvocationID == "" {
+		return nil, errors.ValidationError(fmt.Sprintf("dead letter %s does not hold an execution request", msg.ID))
+	}
+
+	removed, err := s.queue.RemoveDeadLetter(ctx, msg.Queue, msg.ID)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to claim dead letter: %v", err))
+	}
+	if !removed {
+		// Replayed or deleted since it was read
+		return nil, errors.NotFound("dead letter", msg.ID)
+	}
+
+	handle, err := s.invoker.Replay(ctx, execReq)
+	if err != nil {
+		if dlErr := s.queue.DeadLetter(ctx, msg, msg.Headers["dead_letter_reason"]); dlErr != nil {
+			s.logger.Error("Failed to restore dead letter after failed replay",
+				logging.F("message_id", msg.ID),
+				logging.F("error", dlErr),
+			)
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Dead letter replayed",
+		logging.F("message_id", msg.ID),
+		logging.F("invocation_id", handle.InvocationID),
+		logging.F("replay_of", handle.ReplayOf),
+	)
+
+	return handle, nil
+}
+
+// list returns the dead-lettered messages of every execution queue, most
+// recently dead-lettered first
+func (s *Service) list(ctx context.Context) ([]*messaging.Message, error) {
+	queues, err := s.invoker.ListExecutionQueues(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*messaging.Message, 0)
+	for _, queue := range queues {
+		queued, err := s.queue.ListDeadLetters(ctx, queue)
+		if err != nil {
+			return nil, errors.InternalError(fmt.Sprintf("failed to list dead letters: %v", err))
+		}
+		messages = append(messages, queued...)
+	}
+
+	// Each queue lists its own dead letters in order; merge them
+	sort.SliceStable(messages, func(i, j int) bool {
+		return deadLetteredAt(messages[i]).After(deadLetteredAt(messages[j]))
+	})
+
+	return messages, nil
+}
+
+// find returns the dead-lettered message with the given ID
+func (s *Service) find(ctx context.Context, messageID string) (*messaging.Message, error) {
+	messages, err := s.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, msg := range messages {
+		if msg.ID == messageID {
+			return msg, nil
+		}
+	}
+
+	return nil, errors.NotFound("dead letter", messageID)
+}
+
+// match returns the dead-lettered messages selected by the filter
+func (s *Service) match(ctx context.Context, filter Filter) ([]*messaging.Message, error) {
+	messages, err := s.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]*messaging.Message, 0)
+	skipped := 0
+	for _, msg := range messages {
+		if filter.FunctionID != "" && msg.Headers["function_id"] != filter.FunctionID {
+			continue
+		}
+		if filter.Reason != "" && !strings.Contains(msg.Headers["dead_letter_reason"], filter.Reason) {
+			continue
+		}
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+
+		matched = append(matched, msg)
+		if filter.Limit > 0 && len(matched) == filter.Limit {
+			break
+		}
+	}
+
+	return matched, nil
+}
+
+// deadLetteredAt returns when a message was dead-lettered, or the zero
+// time if it is not recorded
+func deadLetteredAt(msg *messaging.Message) time.Time {
+	at, _ := time.Parse(time.RFC3339, msg.Headers["dead_lettered_at"])
+	return at
+}
+
+// toDeadLetter describes a dead-lettered message
+func toDeadLetter(msg *messaging.Message) *DeadLetter {
+	dl := &DeadLetter{
+		MessageID:    msg.ID,
+		InvocationID: msg.Headers["invocation_id"],
+		FunctionID:   msg.Headers["function_id"],
+		Reason:       msg.Headers["dead_letter_reason"],
+		Attempts:     msg.Attempts,
+		EnqueuedAt:   msg.EnqueuedAt,
+		Request:      msg.Payload,
+	}
+
+	if at, err := time.Parse(time.RFC3339, msg.Headers["dead_lettered_at"]); err == nil {
+		dl.DeadLetteredAt = &at
+	}
+
+	if !json.Valid(msg.Payload) {
+		dl.Request, _ = json.Marshal(string(msg.Payload))
+	}
+
+	return dl
+}
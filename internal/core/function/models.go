@@ -8,24 +8,38 @@ import (
 
 // CreateFunctionRequest represents a function creation request
 type CreateFunctionRequest struct {
-	Name        string            `json:"name"`
-	Version     string            `json:"version"`
-	Runtime     types.RuntimeType `json:"runtime"`
-	Handler     string            `json:"handler"`
-	Code        string            `json:"code"` // Base64 encoded
-	Timeout     time.Duration     `json:"timeout"`
-	Memory      int               `json:"memory_mb"`
-	Environment map[string]string `json:"environment"`
-	Concurrency int               `json:"max_concurrency"`
-	Metadata    map[string]string `json:"metadata"`
+	Name        string             `json:"name"`
+	Version     string             `json:"version"`
+	Runtime     types.RuntimeType  `json:"runtime"`
+	Handler     string             `json:"handler"`
+	Code        string             `json:"code"`                  // Base64 encoded
+	CodeFormat  types.CodeFormat   `json:"code_format,omitempty"` // "file" (default), "zip" or "tar.gz"
+	Timeout     time.Duration      `json:"timeout"`
+	Memory      int                `json:"memory_mb"`
+	Environment map[string]string  `json:"environment"`
+	Concurrency int                `json:"max_concurrency"`
+	RetryPolicy *types.RetryPolicy `json:"retry_policy,omitempty"` // Defaults to types.DefaultRetryPolicy
+	Placement   *types.Placement   `json:"placement,omitempty"`    // Workers it may run on; any if unset
+	Metadata    map[string]string  `json:"metadata"`
+	CreatedBy   string             `json:"-"` // Set from the authenticated user
 }
 
 // UpdateFunctionRequest represents a function update request
 type UpdateFunctionRequest struct {
 	Handler     *string            `json:"handler,omitempty"`
-	Code        *string            `json:"code,omitempty"` // Base64 encoded
+	Code        *string            `json:"code,omitempty"`        // Base64 encoded
+	CodeFormat  *types.CodeFormat  `json:"code_format,omitempty"` // Applies to Code; defaults to the current format
 	Timeout     *time.Duration     `json:"timeout,omitempty"`
 	Memory      *int               `json:"memory_mb,omitempty"`
 	Environment map[string]string  `json:"environment,omitempty"`
 	Concurrency *int               `json:"max_concurrency,omitempty"`
+	RetryPolicy *types.RetryPolicy `json:"retry_policy,omitempty"`
+	Placement   *types.Placement   `json:"placement,omitempty"` // An empty placement lets it run on any worker
+}
+
+// ChangeEvent is published on ChangedChannel when a function's code or
+// configuration changes, so workers drop state kept for its old code
+type ChangeEvent struct {
+	FunctionID string `json:"function_id"`
+	Deleted    bool   `json:"deleted,omitempty"` // Its published versions are gone too
 }
@@ -3,35 +3,84 @@ package function
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
+	"GoFaas/internal/build"
+	"GoFaas/internal/build/archive"
+	"GoFaas/internal/messaging"
 	"GoFaas/internal/observability/logging"
 	"GoFaas/internal/storage/function"
 	"GoFaas/internal/storage/metadata"
+	"GoFaas/internal/worker/runtime/bootstrap"
 	"GoFaas/pkg/errors"
 	"GoFaas/pkg/types"
 	"GoFaas/pkg/utils"
 )
 
+// ChangedChannel is the notifier channel on which ChangeEvents are
+// broadcast to workers
+const ChangedChannel = "function_changed"
+
+// buildGracePeriod is how long past the build timeout a build may take to
+// store and record its result before it is taken for abandoned
+const buildGracePeriod = time.Minute
+
 // Service implements function management business logic
 type Service struct {
-	repo    metadata.FunctionRepository
-	storage function.Storage
-	logger  logging.Logger
+	repo         metadata.FunctionRepository
+	storage      function.Storage
+	builder      build.Builder
+	concurrency  messaging.Semaphore
+	notifier     messaging.Notifier
+	buildTimeout time.Duration
+	logger       logging.Logger
+
+	// Builds run in the background until the service is stopped
+	buildCtx   context.Context
+	stopBuilds context.CancelFunc
+	builds     sync.WaitGroup
 }
 
-// NewService creates a new function service
-func NewService(repo metadata.FunctionRepository, storage function.Storage, logger logging.Logger) *Service {
+// NewService creates a new function service. concurrency holds the slots
+// of the invocations running per function, as taken by workers; notifier,
+// if not nil, tells workers about updated and deleted functions.
+// buildTimeout is the builder's upper bound on a build; builds running for
+// longer were abandoned by a stopped process and are restarted.
+func NewService(repo metadata.FunctionRepository, storage function.Storage, builder build.Builder, concurrency messaging.Semaphore, notifier messaging.Notifier, buildTimeout time.Duration, logger logging.Logger) *Service {
+	buildCtx, stopBuilds := context.WithCancel(context.Background())
+
 	return &Service{
-		repo:    repo,
-		storage: storage,
-		logger:  logger,
+		repo:         repo,
+		storage:      storage,
+		builder:      builder,
+		concurrency:  concurrency,
+		notifier:     notifier,
+		buildTimeout: buildTimeout,
+		logger:       logger,
+		buildCtx:     buildCtx,
+		stopBuilds:   stopBuilds,
 	}
 }
 
+// Start restarts abandoned builds now and then every build timeout, until
+// the service is stopped
+func (s *Service) Start() {
+	s.builds.Add(1)
+	go s.sweepLoop()
+}
+
+// Stop interrupts the builds in progress and waits for them to return.
+// Interrupted functions stay building, so a later sweep restarts them.
+func (s *Service) Stop() {
+	s.stopBuilds()
+	s.builds.Wait()
+}
+
 // CreateFunction creates a new function
 func (s *Service) CreateFunction(ctx context.Context, req CreateFunctionRequest) (*types.Function, error) {
 	// Validate request
@@ -45,6 +94,14 @@ func (s *Service) CreateFunction(ctx context.Context, req CreateFunctionRequest)
 		return nil, errors.ValidationError(fmt.Sprintf("invalid base64 code: %v", err))
 	}
 
+	format := req.CodeFormat
+	if format == "" {
+		format = types.CodeFormatFile
+	}
+	if err := validateCode(codeBytes, format); err != nil {
+		return nil, err
+	}
+
 	// Generate function ID
 	functionID := uuid.New().String()
 
@@ -69,17 +126,23 @@ func (s *Service) CreateFunction(ctx context.Context, req CreateFunctionRequest)
 			SourceType: "local",
 			Checksum:   checksum,
 			Size:       int64(len(codeBytes)),
+			Format:     format,
 		},
 		Config: types.FunctionConfig{
 			Timeout:     req.Timeout,
 			Memory:      req.Memory,
 			Environment: req.Environment,
 			Concurrency: req.Concurrency,
+			Retry:       req.RetryPolicy,
+			Placement:   normalizePlacement(req.Placement),
 		},
 		Metadata:  req.Metadata,
+		Status:    types.FunctionReady,
+		CreatedBy: req.CreatedBy,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	needsBuild := s.prepareBuild(fn)
 
 	// Save to database
 	if err := s.repo.Create(ctx, fn); err != nil {
@@ -88,6 +151,10 @@ func (s *Service) CreateFunction(ctx context.Context, req CreateFunctionRequest)
 		return nil, err
 	}
 
+	if needsBuild {
+		s.startBuild(*fn, codeBytes)
+	}
+
 	s.logger.Info("Function created successfully",
 		logging.F("function_id", functionID),
 		logging.F("name", req.Name),
@@ -105,6 +172,18 @@ func (s *Service) GetFunction(ctx context.Context, id string) (*types.Function,
 		return nil, err
 	}
 
+	// Report the invocations running now; the function is still useful
+	// without the count
+	inFlight, err := s.concurrency.Count(ctx, fn.ID)
+	if err != nil {
+		s.logger.Warn("Failed to count running invocations",
+			logging.F("function_id", fn.ID),
+			logging.F("error", err),
+		)
+	} else {
+		fn.InFlight = &inFlight
+	}
+
 	return fn, nil
 }
 
@@ -126,8 +205,14 @@ func (s *Service) UpdateFunction(ctx context.Context, id string, req UpdateFunct
 		return nil, err
 	}
 
+	previousHandler := fn.Handler
+	previousArtifact := fn.Code.Artifact
+
 	// Update fields
 	if req.Handler != nil {
+		if err := bootstrap.ValidateHandler(fn.Runtime, *req.Handler); err != nil {
+			return nil, errors.ValidationError(err.Error())
+		}
 		fn.Handler = *req.Handler
 	}
 	if req.Timeout != nil {
@@ -151,14 +236,35 @@ func (s *Service) UpdateFunction(ctx context.Context, id string, req UpdateFunct
 		}
 		fn.Config.Concurrency = *req.Concurrency
 	}
+	if req.RetryPolicy != nil {
+		if err := req.RetryPolicy.Validate(); err != nil {
+			return nil, errors.ValidationError(err.Error())
+		}
+		fn.Config.Retry = req.RetryPolicy
+	}
+	if req.Placement != nil {
+		if err := req.Placement.Validate(); err != nil {
+			return nil, errors.ValidationError(err.Error())
+		}
+		fn.Config.Placement = normalizePlacement(req.Placement)
+	}
 
 	// Update code if provided
+	var codeBytes []byte
 	if req.Code != nil {
-		codeBytes, err := base64.StdEncoding.DecodeString(*req.Code)
+		codeBytes, err = base64.StdEncoding.DecodeString(*req.Code)
 		if err != nil {
 			return nil, errors.ValidationError(fmt.Sprintf("invalid base64 code: %v", err))
 		}
 
+		format := fn.Code.Format
+		if req.CodeFormat != nil {
+			format = *req.CodeFormat
+		}
+		if err := validateCode(codeBytes, format); err != nil {
+			return nil, err
+		}
+
 		// Store new code
 		codeLocation, err := s.storage.Store(ctx, id, codeBytes)
 		if err != nil {
@@ -169,6 +275,19 @@ func (s *Service) UpdateFunction(ctx context.Context, id string, req UpdateFunct
 		fn.Code.Source = codeLocation
 		fn.Code.Checksum = utils.SHA256Hash(codeBytes)
 		fn.Code.Size = int64(len(codeBytes))
+		fn.Code.Format = format
+	}
+
+	// New code or a new handler invalidates the previous build
+	needsBuild := false
+	if req.Code != nil || fn.Handler != previousHandler {
+		if codeBytes == nil {
+			codeBytes, err = s.storage.Retrieve(ctx, fn.Code.Source)
+			if err != nil {
+				return nil, errors.InternalError(fmt.Sprintf("failed to retrieve function code: %v", err))
+			}
+		}
+		needsBuild = s.prepareBuild(fn)
 	}
 
 	fn.UpdatedAt = time.Now()
@@ -178,6 +297,24 @@ func (s *Service) UpdateFunction(ctx context.Context, id string, req UpdateFunct
 		return nil, err
 	}
 
+	if needsBuild {
+		s.startBuild(*fn, codeBytes)
+	}
+
+	s.signalChange(ctx, ChangeEvent{FunctionID: id})
+
+	// Remove the superseded build (best effort); workers that already
+	// loaded it keep their copy
+	if previousArtifact != "" && previousArtifact != fn.Code.Artifact {
+		if err := s.storage.Delete(ctx, previousArtifact); err != nil {
+			s.logger.Warn("Failed to delete previous build artifact",
+				logging.F("function_id", id),
+				logging.F("artifact", previousArtifact),
+				logging.F("error", err),
+			)
+		}
+	}
+
 	s.logger.Info("Function updated successfully",
 		logging.F("function_id", id),
 		logging.F("name", fn.Name),
@@ -199,6 +336,8 @@ func (s *Service) DeleteFunction(ctx context.Context, id string) error {
 		return err
 	}
 
+	s.signalChange(ctx, ChangeEvent{FunctionID: id, Deleted: true})
+
 	// Delete stored code (best effort)
 	if err := s.storage.Delete(ctx, fn.Code.Source); err != nil {
 		s.logger.Error("Failed to delete function code",
@@ -226,6 +365,210 @@ func (s *Service) ListFunctions(ctx context.Context, filter metadata.FunctionFil
 	return functions, nil
 }
 
+// signalChange tells workers that a function changed. Workers also notice
+// new code when they next run the function, so a lost event only delays
+// freeing what they keep for the old code.
+func (s *Service) signalChange(ctx context.Context, event ChangeEvent) {
+	if s.notifier == nil {
+		return
+	}
+
+	payload, _ := json.Marshal(event)
+	if err := s.notifier.Publish(ctx, ChangedChannel, payload); err != nil {
+		s.logger.Warn("Failed to signal function change",
+			logging.F("function_id", event.FunctionID),
+			logging.F("error", err),
+		)
+	}
+}
+
+// prepareBuild resets the build state of fn for its current code and
+// handler. It reports whether a build has to be started once fn is saved.
+func (s *Service) prepareBuild(fn *types.Function) bool {
+	fn.Code.Artifact = ""
+
+	if !build.NeedsBuild(fn.Runtime, fn.Code.Format) {
+		fn.Status = types.FunctionReady
+		fn.Build = nil
+		return false
+	}
+
+	now := time.Now()
+	fn.Status = types.FunctionBuilding
+	fn.Build = &types.BuildInfo{StartedAt: &now}
+	return true
+}
+
+// startBuild runs the build of fn in the background
+func (s *Service) startBuild(fn types.Function, code []byte) {
+	s.builds.Add(1)
+	go func() {
+		defer s.builds.Done()
+		s.runBuild(fn, code)
+	}()
+}
+
+// runBuild compiles the code of fn and records the outcome. If fn is
+// updated again before the build finishes, the result is discarded in
+// favour of the newer build.
+func (s *Service) runBuild(fn types.Function, code []byte) {
+	ctx := s.buildCtx
+	logger := s.logger.WithFields(
+		logging.F("function_id", fn.ID),
+		logging.F("checksum", fn.Code.Checksum),
+	)
+
+	logger.Info("Function build started")
+
+	result, err := s.builder.Build(ctx, build.Spec{
+		FunctionID: fn.ID,
+		Runtime:    fn.Runtime,
+		Handler:    fn.Handler,
+		Format:     fn.Code.Format,
+		Code:       code,
+	})
+	if ctx.Err() != nil {
+		// Stopped mid-build; the function is left building for a sweep
+		logger.Warn("Function build interrupted")
+		return
+	}
+
+	now := time.Now()
+	fn.Build = &types.BuildInfo{
+		StartedAt:   fn.Build.StartedAt,
+		CompletedAt: &now,
+	}
+	if result != nil {
+		fn.Build.Logs = result.Logs
+	}
+
+	if err == nil {
+		fn.Code.Artifact, err = s.storage.StoreArtifact(ctx, fn.ID, uuid.New().String(), result.Artifact)
+	}
+
+	if err != nil {
+		fn.Status = types.FunctionFailed
+		fn.Build.Error = err.Error()
+	} else {
+		fn.Status = types.FunctionReady
+	}
+	fn.UpdatedAt = now
+
+	applied, err := s.repo.CompleteBuild(ctx, &fn)
+	if err != nil || !applied {
+		// The artifact is not referenced by any function record
+		if fn.Code.Artifact != "" {
+			s.storage.Delete(ctx, fn.Code.Artifact)
+		}
+		if err != nil {
+			logger.Error("Failed to record function build", logging.F("error", err))
+		} else {
+			logger.Info("Function build superseded by a newer version")
+		}
+		return
+	}
+
+	if fn.Status == types.FunctionFailed {
+		logger.Warn("Function build failed", logging.F("error", fn.Build.Error))
+		return
+	}
+
+	logger.Info("Function build completed",
+		logging.F("duration", now.Sub(*fn.Build.StartedAt)),
+		logging.F("artifact_size", len(result.Artifact)),
+	)
+}
+
+// sweepLoop restarts abandoned builds every build timeout until the service
+// is stopped
+func (s *Service) sweepLoop() {
+	defer s.builds.Done()
+
+	interval := s.buildTimeout
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.sweepBuilds(s.buildCtx)
+
+		select {
+		case <-s.buildCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepBuilds restarts the builds that have run for longer than the build
+// timeout allows. Such a build was started by a process that stopped before
+// recording its result, which would otherwise leave the function building
+// forever.
+func (s *Service) sweepBuilds(ctx context.Context) {
+	building := types.FunctionBuilding
+	fns, err := s.repo.List(ctx, metadata.FunctionFilter{Status: &building})
+	if err != nil {
+		s.logger.Error("Failed to list building functions", logging.F("error", err))
+		return
+	}
+
+	cutoff := time.Now().Add(-s.buildTimeout - buildGracePeriod)
+	for _, fn := range fns {
+		if fn.Build == nil || fn.Build.StartedAt == nil || fn.Build.StartedAt.After(cutoff) {
+			continue
+		}
+		if err := s.restartBuild(ctx, fn); err != nil {
+			s.logger.Error("Failed to restart abandoned build",
+				logging.F("function_id", fn.ID),
+				logging.F("error", err),
+			)
+		}
+	}
+}
+
+// restartBuild takes over the abandoned build of fn. Of several processes
+// sweeping at once only one restarts it.
+func (s *Service) restartBuild(ctx context.Context, fn *types.Function) error {
+	code, err := s.storage.Retrieve(ctx, fn.Code.Source)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve function code: %w", err)
+	}
+
+	staleStart := *fn.Build.StartedAt
+	s.prepareBuild(fn)
+	fn.UpdatedAt = time.Now()
+
+	restarted, err := s.repo.RestartBuild(ctx, fn, staleStart)
+	if err != nil || !restarted {
+		return err
+	}
+
+	s.logger.Warn("Restarting abandoned function build",
+		logging.F("function_id", fn.ID),
+		logging.F("abandoned_at", staleStart),
+	)
+	s.startBuild(*fn, code)
+
+	return nil
+}
+
+// validateCode checks that decoded function code matches its format
+func validateCode(code []byte, format types.CodeFormat) error {
+	if !format.IsValid() {
+		return errors.ValidationError(fmt.Sprintf("unsupported code format: %s", format))
+	}
+
+	if format.IsArchive() {
+		if err := archive.Validate(code, format); err != nil {
+			return errors.ValidationError(err.Error())
+		}
+	}
+
+	return nil
+}
+
 // validateCreateRequest validates function creation request
 func (s *Service) validateCreateRequest(req CreateFunctionRequest) error {
 	if err := utils.ValidateFunctionName(req.Name); err != nil {
@@ -244,6 +587,10 @@ func (s *Service) validateCreateRequest(req CreateFunctionRequest) error {
 		return errors.ValidationError("handler is required")
 	}
 
+	if err := bootstrap.ValidateHandler(req.Runtime, req.Handler); err != nil {
+		return errors.ValidationError(err.Error())
+	}
+
 	if req.Code == "" {
 		return errors.ValidationError("function code is required")
 	}
@@ -260,5 +607,25 @@ func (s *Service) validateCreateRequest(req CreateFunctionRequest) error {
 		return errors.ValidationError("concurrency must be positive")
 	}
 
+	if req.RetryPolicy != nil {
+		if err := req.RetryPolicy.Validate(); err != nil {
+			return errors.ValidationError(err.Error())
+		}
+	}
+
+	if req.Placement != nil {
+		if err := req.Placement.Validate(); err != nil {
+			return errors.ValidationError(err.Error())
+		}
+	}
+
 	return nil
 }
+
+// normalizePlacement maps a placement that allows every worker to nil
+func normalizePlacement(p *types.Placement) *types.Placement {
+	if p.IsZero() {
+		return nil
+	}
+	return p
+}
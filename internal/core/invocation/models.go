@@ -9,33 +9,59 @@ import (
 
 // InvocationRequest represents a function invocation request
 type InvocationRequest struct {
-	FunctionID string                 `json:"function_id"`
-	Payload    json.RawMessage        `json:"payload"`
-	Headers    map[string]string      `json:"headers"`
-	Timeout    *time.Duration         `json:"timeout,omitempty"`
+	FunctionID string            `json:"function_id"` // Function ID, or "name:alias" / "name:version" for a published version
+	Payload    json.RawMessage   `json:"payload"`
+	Headers    map[string]string `json:"headers"`
+	Timeout    *time.Duration    `json:"timeout,omitempty"`
+	Delay      *time.Duration    `json:"delay,omitempty"`    // Start no earlier than this long from now
+	RunAt      *time.Time        `json:"run_at,omitempty"`   // Start no earlier than this time; exclusive with Delay
+	Priority   types.Priority    `json:"priority,omitempty"` // Execution lane; defaults to normal
+
+	// IdempotencyKey makes retries safe: a repeated key for the same
+	// function returns the original invocation instead of invoking again
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+
+	// CallbackURL receives the invocation once it is finished; deliveries
+	// are signed with CallbackSecret if it is set
+	CallbackURL    string `json:"callback_url,omitempty"`
+	CallbackSecret string `json:"callback_secret,omitempty"`
 }
 
 // InvocationHandle represents an async invocation handle
 type InvocationHandle struct {
-	InvocationID string              `json:"invocation_id"`
-	FunctionID   string              `json:"function_id"`
-	Status       types.ExecutionStatus `json:"status"`
-	CreatedAt    time.Time           `json:"created_at"`
+	InvocationID    string                `json:"invocation_id"`
+	FunctionID      string                `json:"function_id"`
+	FunctionVersion int                   `json:"function_version,omitempty"` // Published version chosen for the invocation
+	Status          types.ExecutionStatus `json:"status"`
+	Priority        types.Priority        `json:"priority"`
+	CreatedAt       time.Time             `json:"created_at"`
+	RunAt           *time.Time            `json:"run_at,omitempty"`
+	Replayed        bool                  `json:"replayed,omitempty"`  // The idempotency key matched an earlier invocation
+	ReplayOf        string                `json:"replay_of,omitempty"` // Invocation this one re-runs
 }
 
 // ExecutionRequest represents a function execution request (queued message)
 type ExecutionRequest struct {
 	InvocationID string            `json:"invocation_id"`
 	FunctionID   string            `json:"function_id"`
+	VersionID    string            `json:"version_id,omitempty"` // Published version to run; empty for the function's current code
 	Payload      json.RawMessage   `json:"payload"`
 	Headers      map[string]string `json:"headers"`
 	Timeout      *time.Duration    `json:"timeout"`
+	Priority     types.Priority    `json:"priority,omitempty"` // Lane the request is queued in; empty for normal
+}
+
+// CallbackRequest represents a callback delivery (queued message)
+type CallbackRequest struct {
+	InvocationID string `json:"invocation_id"`
+	Attempt      int    `json:"attempt"` // 1 for the first delivery
 }
 
 // ExecutionResult represents a function execution result
 type ExecutionResult struct {
-	Status  types.ExecutionStatus  `json:"status"`
-	Result  json.RawMessage        `json:"result,omitempty"`
-	Error   *types.ExecutionError  `json:"error,omitempty"`
+	Status  types.ExecutionStatus   `json:"status"`
+	Result  json.RawMessage         `json:"result,omitempty"`
+	Error   *types.ExecutionError   `json:"error,omitempty"`
 	Metrics *types.ExecutionMetrics `json:"metrics,omitempty"`
+	Logs    []types.LogEntry        `json:"logs,omitempty"`
 }
@@ -1,30 +1,102 @@
 package invocation
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/google/uuid"
 
+	"GoFaas/internal/core/version"
 	"GoFaas/internal/messaging"
 	"GoFaas/internal/observability/logging"
 	"GoFaas/internal/storage/metadata"
 	"GoFaas/pkg/errors"
 	"GoFaas/pkg/types"
+	"GoFaas/pkg/utils"
 )
 
 const (
-	// ExecutionQueueName is the queue name for function executions
+	// ExecutionQueueName is the queue name for function executions of
+	// normal priority; the other lanes append their priority to it
 	ExecutionQueueName = "faas_executions"
+
+	// CallbackQueueName is the queue name for callback deliveries
+	CallbackQueueName = "faas_callbacks"
+
+	// CancelChannel is the notifier channel on which the IDs of cancelled
+	// invocations are broadcast to workers
+	CancelChannel = "invocation_cancel"
+
+	// maxIdempotencyKeyLength bounds client-supplied idempotency keys
+	maxIdempotencyKeyLength = 255
 )
 
+// ExecutionQueue returns the execution queue of a priority lane. Unknown
+// priorities use the normal lane.
+func ExecutionQueue(priority types.Priority) string {
+	switch priority {
+	case types.PriorityHigh, types.PriorityLow:
+		return ExecutionQueueName + "_" + string(priority)
+	default:
+		return ExecutionQueueName
+	}
+}
+
+// ExecutionQueues returns the execution queues of all lanes, highest
+// priority first
+func ExecutionQueues() []string {
+	queues := make([]string, len(types.Priorities))
+	for i, priority := range types.Priorities {
+		queues[i] = ExecutionQueue(priority)
+	}
+	return queues
+}
+
+// PlacementQueue returns the execution queue of a priority lane for
+// functions with the given placement, so only matching workers take them.
+// Functions that run on any worker use the lane's own queue.
+func PlacementQueue(priority types.Priority, placement *types.Placement) string {
+	key := placement.Key()
+	if key == "" {
+		return ExecutionQueue(priority)
+	}
+	return ExecutionQueue(priority) + ":" + key
+}
+
+// PlacementQueues returns the execution queues of all lanes, followed by
+// those of each placement
+func PlacementQueues(placements []*types.Placement) []string {
+	queues := ExecutionQueues()
+	for _, placement := range placements {
+		if placement.IsZero() {
+			continue
+		}
+		for _, priority := range types.Priorities {
+			queues = append(queues, PlacementQueue(priority, placement))
+		}
+	}
+	return queues
+}
+
+// ResultChannel returns the notifier channel on which the terminal state of
+// an invocation is announced
+func ResultChannel(invocationID string) string {
+	return fmt.Sprintf("invocation_result:%s", invocationID)
+}
+
 // Service implements invocation business logic
 type Service struct {
 	functionRepo   metadata.FunctionRepository
 	invocationRepo metadata.InvocationRepository
+	versionRepo    metadata.VersionRepository
+	callbackRepo   metadata.CallbackRepository
 	queue          messaging.Queue
+	notifier       messaging.Notifier
+	idempotencyTTL time.Duration
 	logger         logging.Logger
 }
 
@@ -32,83 +104,568 @@ type Service struct {
 func NewService(
 	functionRepo metadata.FunctionRepository,
 	invocationRepo metadata.InvocationRepository,
+	versionRepo metadata.VersionRepository,
+	callbackRepo metadata.CallbackRepository,
 	queue messaging.Queue,
+	notifier messaging.Notifier,
+	idempotencyTTL time.Duration,
 	logger logging.Logger,
 ) *Service {
 	return &Service{
 		functionRepo:   functionRepo,
 		invocationRepo: invocationRepo,
+		versionRepo:    versionRepo,
+		callbackRepo:   callbackRepo,
 		queue:          queue,
+		notifier:       notifier,
+		idempotencyTTL: idempotencyTTL,
 		logger:         logger,
 	}
 }
 
 // InvokeAsync invokes a function asynchronously
 func (s *Service) InvokeAsync(ctx context.Context, req InvocationRequest) (*InvocationHandle, error) {
-	// Validate function exists
-	fn, err := s.functionRepo.GetByID(ctx, req.FunctionID)
+	handle, err := s.invoke(ctx, uuid.New().String(), req)
 	if err != nil {
 		return nil, err
 	}
+	if handle.Replayed {
+		return handle, nil
+	}
 
-	// Create invocation record
+	s.logger.Info("Function invoked asynchronously",
+		logging.F("invocation_id", handle.InvocationID),
+		logging.F("function_id", req.FunctionID),
+	)
+
+	return handle, nil
+}
+
+// InvokeSync invokes a function and waits up to maxWait for it to reach a
+// terminal state. If it finishes in time the completed invocation is
+// returned; otherwise the invocation is nil and the handle can be used to
+// poll for the result later.
+func (s *Service) InvokeSync(ctx context.Context, req InvocationRequest, maxWait time.Duration) (*types.Invocation, *InvocationHandle, error) {
 	invocationID := uuid.New().String()
+
+	// Subscribe before enqueueing so a fast worker cannot finish unnoticed
+	sub, err := s.notifier.Subscribe(ctx, ResultChannel(invocationID))
+	if err != nil {
+		return nil, nil, errors.InternalError(fmt.Sprintf("failed to subscribe to invocation result: %v", err))
+	}
+	defer sub.Close()
+
+	handle, err := s.invoke(ctx, invocationID, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if handle.Replayed {
+		// Wait for the original invocation instead, which may have
+		// finished long ago
+		invocationID = handle.InvocationID
+		originalSub, err := s.notifier.Subscribe(ctx, ResultChannel(invocationID))
+		if err != nil {
+			return nil, nil, errors.InternalError(fmt.Sprintf("failed to subscribe to invocation result: %v", err))
+		}
+		defer originalSub.Close()
+
+		invocation, err := s.invocationRepo.GetInvocationByID(ctx, invocationID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if invocation.Status.IsTerminal() {
+			return invocation, handle, nil
+		}
+
+		return s.await(ctx, originalSub, handle, maxWait)
+	}
+
+	s.logger.Info("Function invoked synchronously",
+		logging.F("invocation_id", invocationID),
+		logging.F("function_id", req.FunctionID),
+		logging.F("max_wait", maxWait),
+	)
+
+	return s.await(ctx, sub, handle, maxWait)
+}
+
+// await waits up to maxWait for a result event of the handle's invocation
+// and returns the invocation if it reached a terminal state
+func (s *Service) await(ctx context.Context, sub messaging.Subscription, handle *InvocationHandle, maxWait time.Duration) (*types.Invocation, *InvocationHandle, error) {
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+
+	select {
+	case <-sub.Events():
+	case <-timer.C:
+	case <-ctx.Done():
+		return nil, handle, nil
+	}
+
+	// Read the stored record rather than trusting the event payload; this
+	// also covers events lost while the subscription was reconnecting
+	invocation, err := s.invocationRepo.GetInvocationByID(ctx, handle.InvocationID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !invocation.Status.IsTerminal() {
+		handle.Status = invocation.Status
+		return nil, handle, nil
+	}
+
+	return invocation, handle, nil
+}
+
+// invoke records an invocation under the given ID and enqueues it for execution
+func (s *Service) invoke(ctx context.Context, invocationID string, req InvocationRequest) (*InvocationHandle, error) {
+	// Resolve the function or published version to run
+	functionID := req.FunctionID
+	var versionID string
+	var versionNumber int
+	var defaultTimeout time.Duration
+	var placement *types.Placement
+
+	if name, qualifier, ok := version.ParseReference(req.FunctionID); ok {
+		v, err := version.Resolve(ctx, s.versionRepo, name, qualifier)
+		if err != nil {
+			return nil, err
+		}
+		functionID = v.FunctionID
+		versionID = v.ID
+		versionNumber = v.Version
+		defaultTimeout = v.Config.Timeout
+		placement = v.Config.Placement
+	} else {
+		fn, err := s.functionRepo.GetByID(ctx, req.FunctionID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch fn.Status {
+		case types.FunctionBuilding:
+			return nil, errors.Conflict(fmt.Sprintf("function %s is still building", fn.ID))
+		case types.FunctionFailed:
+			return nil, errors.Conflict(fmt.Sprintf("function %s failed to build; see its build logs", fn.ID))
+		}
+		defaultTimeout = fn.Config.Timeout
+		placement = fn.Config.Placement
+	}
+
+	now := time.Now()
+	runAt, err := delayedStart(req, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateCallback(req); err != nil {
+		return nil, err
+	}
+
+	priority := req.Priority
+	if priority == "" {
+		priority = types.PriorityNormal
+	}
+	if !priority.IsValid() {
+		return nil, errors.ValidationError(fmt.Sprintf("priority must be high, normal or low: %s", priority))
+	}
+
+	var idempotencyHash string
+	if req.IdempotencyKey != "" {
+		if len(req.IdempotencyKey) > maxIdempotencyKeyLength {
+			return nil, errors.ValidationError(fmt.Sprintf("idempotency key must be at most %d characters", maxIdempotencyKeyLength))
+		}
+
+		idempotencyHash = payloadHash(req.Payload)
+		handle, err := s.replay(ctx, functionID, req.IdempotencyKey, idempotencyHash, now)
+		if err != nil || handle != nil {
+			return handle, err
+		}
+	}
+
+	status := types.StatusPending
+	if runAt != nil {
+		status = types.StatusDelayed
+	}
+
+	// Create invocation record
 	invocation := &types.Invocation{
-		ID:         invocationID,
-		FunctionID: req.FunctionID,
-		Payload:    req.Payload,
-		Headers:    req.Headers,
-		Status:     types.StatusPending,
-		CreatedAt:  time.Now(),
+		ID:              invocationID,
+		FunctionID:      functionID,
+		FunctionVersion: versionNumber,
+		Payload:         req.Payload,
+		Headers:         req.Headers,
+		Status:          status,
+		Priority:        priority,
+		Placement:       placement,
+		CreatedAt:       now,
+		RunAt:           runAt,
+		IdempotencyKey:  req.IdempotencyKey,
+		IdempotencyHash: idempotencyHash,
+		CallbackURL:     req.CallbackURL,
+		CallbackSecret:  req.CallbackSecret,
 	}
 
 	if err := s.invocationRepo.CreateInvocation(ctx, invocation); err != nil {
+		if req.IdempotencyKey != "" && hasCode(err, errors.ErrCodeConflict) {
+			// A concurrent request with the same key created the invocation first
+			if handle, rerr := s.replay(ctx, functionID, req.IdempotencyKey, idempotencyHash, now); rerr != nil || handle != nil {
+				return handle, rerr
+			}
+		}
 		return nil, err
 	}
 
 	// Create execution request
 	execReq := ExecutionRequest{
 		InvocationID: invocationID,
-		FunctionID:   req.FunctionID,
+		FunctionID:   functionID,
+		VersionID:    versionID,
 		Payload:      req.Payload,
 		Headers:      req.Headers,
 		Timeout:      req.Timeout,
+		Priority:     priority,
 	}
 
 	// If no timeout specified, use function's default timeout
 	if execReq.Timeout == nil {
-		execReq.Timeout = &fn.Config.Timeout
+		execReq.Timeout = &defaultTimeout
+	}
+
+	if err := s.enqueue(ctx, PlacementQueue(priority, placement), execReq, runAt); err != nil {
+		s.abandon(invocation, err)
+		return nil, err
 	}
 
-	// Enqueue execution request
+	return &InvocationHandle{
+		InvocationID:    invocationID,
+		FunctionID:      functionID,
+		FunctionVersion: versionNumber,
+		Status:          status,
+		Priority:        priority,
+		CreatedAt:       invocation.CreatedAt,
+		RunAt:           runAt,
+	}, nil
+}
+
+// enqueue queues an execution request in the given execution queue, to be
+// delivered no earlier than runAt if it is set
+func (s *Service) enqueue(ctx context.Context, queue string, execReq ExecutionRequest, runAt *time.Time) error {
 	payload, err := json.Marshal(execReq)
 	if err != nil {
-		return nil, errors.InternalError(fmt.Sprintf("failed to marshal execution request: %v", err))
+		return errors.InternalError(fmt.Sprintf("failed to marshal execution request: %v", err))
 	}
 
 	headers := map[string]string{
-		"invocation_id": invocationID,
-		"function_id":   req.FunctionID,
+		"invocation_id": execReq.InvocationID,
+		"function_id":   execReq.FunctionID,
 	}
 
-	if err := s.queue.Enqueue(ctx, ExecutionQueueName, payload, headers); err != nil {
-		return nil, errors.InternalError(fmt.Sprintf("failed to enqueue execution: %v", err))
+	// The invocation ID doubles as the message ID so a delayed invocation
+	// can be removed from the queue when it is cancelled
+	opts := messaging.EnqueueOptions{MessageID: execReq.InvocationID}
+	if runAt != nil {
+		opts.RunAt = *runAt
 	}
 
-	s.logger.Info("Function invoked asynchronously",
-		logging.F("invocation_id", invocationID),
-		logging.F("function_id", req.FunctionID),
-		logging.F("function_name", fn.Name),
+	if err := s.queue.EnqueueWithOptions(ctx, queue, payload, headers, opts); err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to enqueue execution: %v", err))
+	}
+
+	return nil
+}
+
+// abandon marks an invocation that could not be queued as failed and
+// releases its idempotency key, so it is not left pending forever and a
+// retry with the same key invokes the function again instead of replaying
+// the abandoned invocation. The caller's context may be what made the
+// enqueue fail, so it is not used.
+func (s *Service) abandon(invocation *types.Invocation, cause error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	logger := s.logger.WithFields(logging.F("invocation_id", invocation.ID))
+
+	now := time.Now()
+	invocation.Status = types.StatusFailed
+	invocation.Error = &types.ExecutionError{
+		Type:    "EnqueueError",
+		Message: cause.Error(),
+	}
+	invocation.CompletedAt = &now
+	if err := s.invocationRepo.UpdateInvocation(ctx, invocation); err != nil {
+		logger.Error("Failed to mark unqueued invocation failed", logging.F("error", err))
+	}
+
+	if invocation.IdempotencyKey != "" {
+		if err := s.invocationRepo.ReleaseIdempotencyKey(ctx, invocation.ID); err != nil {
+			logger.Error("Failed to release idempotency key of unqueued invocation", logging.F("error", err))
+		}
+	}
+}
+
+// Replay runs the execution request of an earlier invocation again as a
+// new invocation that links back to the original through ReplayOf. The
+// payload, headers, priority and callback of the original are kept; its
+// idempotency key is not. It is queued by the current placement of the
+// function or version, which may have changed since the original ran.
+func (s *Service) Replay(ctx context.Context, execReq ExecutionRequest) (*InvocationHandle, error) {
+	original, err := s.invocationRepo.GetInvocationByID(ctx, execReq.InvocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	placement, err := s.placement(ctx, execReq)
+	if err != nil {
+		return nil, err
+	}
+
+	invocation := &types.Invocation{
+		ID:              uuid.New().String(),
+		FunctionID:      original.FunctionID,
+		FunctionVersion: original.FunctionVersion,
+		Payload:         original.Payload,
+		Headers:         original.Headers,
+		Status:          types.StatusPending,
+		Priority:        original.Priority,
+		Placement:       placement,
+		CreatedAt:       time.Now(),
+		CallbackURL:     original.CallbackURL,
+		CallbackSecret:  original.CallbackSecret,
+		ReplayOf:        original.ID,
+	}
+
+	if err := s.invocationRepo.CreateInvocation(ctx, invocation); err != nil {
+		return nil, err
+	}
+
+	execReq.InvocationID = invocation.ID
+	execReq.Priority = invocation.Priority
+	if err := s.enqueue(ctx, PlacementQueue(invocation.Priority, placement), execReq, nil); err != nil {
+		s.abandon(invocation, err)
+		return nil, err
+	}
+
+	s.logger.Info("Invocation replayed",
+		logging.F("invocation_id", invocation.ID),
+		logging.F("replay_of", original.ID),
+		logging.F("function_id", invocation.FunctionID),
 	)
 
 	return &InvocationHandle{
-		InvocationID: invocationID,
-		FunctionID:   req.FunctionID,
-		Status:       types.StatusPending,
-		CreatedAt:    invocation.CreatedAt,
+		InvocationID:    invocation.ID,
+		FunctionID:      invocation.FunctionID,
+		FunctionVersion: invocation.FunctionVersion,
+		Status:          invocation.Status,
+		Priority:        invocation.Priority,
+		CreatedAt:       invocation.CreatedAt,
+		ReplayOf:        original.ID,
+	}, nil
+}
+
+// placement returns the placement of the function or published version an
+// execution request runs
+func (s *Service) placement(ctx context.Context, execReq ExecutionRequest) (*types.Placement, error) {
+	if execReq.VersionID != "" {
+		v, err := s.versionRepo.GetVersionByID(ctx, execReq.VersionID)
+		if err != nil {
+			return nil, err
+		}
+		return v.Config.Placement, nil
+	}
+
+	fn, err := s.functionRepo.GetByID(ctx, execReq.FunctionID)
+	if err != nil {
+		return nil, err
+	}
+	return fn.Config.Placement, nil
+}
+
+// ListExecutionQueues returns the execution queues of all lanes, including
+// those of the placements of functions, published versions and unfinished
+// invocations
+func (s *Service) ListExecutionQueues(ctx context.Context) ([]string, error) {
+	placements, err := s.functionRepo.ListPlacements(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return PlacementQueues(placements), nil
+}
+
+// replay returns a handle to the invocation that holds an idempotency key
+// of a function, or nil if the key is free. Keys older than the retention
+// window are released so the request invokes the function again; a key
+// reused with a different payload is rejected.
+func (s *Service) replay(ctx context.Context, functionID, key, hash string, now time.Time) (*InvocationHandle, error) {
+	existing, err := s.invocationRepo.GetInvocationByIdempotencyKey(ctx, functionID, key)
+	if err != nil {
+		if hasCode(err, errors.ErrCodeNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if s.idempotencyTTL > 0 && now.Sub(existing.CreatedAt) >= s.idempotencyTTL {
+		if err := s.invocationRepo.ReleaseIdempotencyKey(ctx, existing.ID); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	if existing.IdempotencyHash != hash {
+		return nil, errors.Conflict(fmt.Sprintf("idempotency key %s was already used with a different payload", key))
+	}
+
+	s.logger.Info("Idempotent invocation replayed",
+		logging.F("invocation_id", existing.ID),
+		logging.F("function_id", functionID),
+		logging.F("idempotency_key", key),
+	)
+
+	return &InvocationHandle{
+		InvocationID:    existing.ID,
+		FunctionID:      existing.FunctionID,
+		FunctionVersion: existing.FunctionVersion,
+		Status:          existing.Status,
+		Priority:        existing.Priority,
+		CreatedAt:       existing.CreatedAt,
+		RunAt:           existing.RunAt,
+		Replayed:        true,
 	}, nil
 }
 
+// payloadHash fingerprints a payload for idempotency checks. Valid JSON is
+// compacted first so formatting differences between retries do not count
+// as a different payload.
+func payloadHash(payload json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, payload); err != nil {
+		return utils.SHA256Hash(payload)
+	}
+	return utils.SHA256Hash(buf.Bytes())
+}
+
+// hasCode reports whether err is an application error with the given code
+func hasCode(err error, code errors.ErrorCode) bool {
+	appErr, ok := err.(*errors.AppError)
+	return ok && appErr.Code == code
+}
+
+// validateCallback checks that a callback URL is an absolute HTTP(S) URL
+func validateCallback(req InvocationRequest) error {
+	if req.CallbackURL == "" {
+		if req.CallbackSecret != "" {
+			return errors.ValidationError("callback_secret requires callback_url")
+		}
+		return nil
+	}
+
+	u, err := url.Parse(req.CallbackURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.ValidationError(fmt.Sprintf("callback_url must be an absolute http or https URL: %s", req.CallbackURL))
+	}
+
+	return nil
+}
+
+// delayedStart returns when a delayed invocation may start, or nil if it
+// should be queued immediately
+func delayedStart(req InvocationRequest, now time.Time) (*time.Time, error) {
+	if req.Delay != nil && req.RunAt != nil {
+		return nil, errors.ValidationError("delay and run_at are mutually exclusive")
+	}
+
+	var runAt time.Time
+	switch {
+	case req.Delay != nil:
+		if *req.Delay < 0 {
+			return nil, errors.ValidationError("delay must not be negative")
+		}
+		runAt = now.Add(*req.Delay)
+	case req.RunAt != nil:
+		runAt = *req.RunAt
+	}
+
+	if !runAt.After(now) {
+		return nil, nil
+	}
+
+	return &runAt, nil
+}
+
+// Cancel stops an invocation that has not finished. A delayed or queued
+// invocation is removed from the queue; otherwise the worker running it is
+// told to kill it. The invocation is marked cancelled either way, and
+// later results from the worker are discarded.
+func (s *Service) Cancel(ctx context.Context, invocationID string) (*types.Invocation, error) {
+	invocation, err := s.invocationRepo.GetInvocationByID(ctx, invocationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if invocation.Status.IsTerminal() {
+		return nil, errors.Conflict(fmt.Sprintf("invocation %s is already %s", invocationID, invocation.Status))
+	}
+
+	removed := false
+	if invocation.Status == types.StatusDelayed || invocation.Status == types.StatusPending {
+		removed, err = s.queue.Remove(ctx, PlacementQueue(invocation.Priority, invocation.Placement), invocationID)
+		if err != nil {
+			return nil, errors.InternalError(fmt.Sprintf("failed to remove invocation from queue: %v", err))
+		}
+		// If it was not found a worker has already taken it
+	}
+
+	now := time.Now()
+	cancelled, err := s.invocationRepo.CancelInvocation(ctx, invocationID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		// It finished while being cancelled
+		current, err := s.invocationRepo.GetInvocationByID(ctx, invocationID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.Conflict(fmt.Sprintf("invocation %s is already %s", invocationID, current.Status))
+	}
+
+	if !removed {
+		s.signalCancel(ctx, invocationID)
+	}
+
+	invocation.Status = types.StatusCancelled
+	invocation.CompletedAt = &now
+	s.notifyResult(ctx, invocation)
+	s.scheduleCallback(ctx, invocation)
+
+	s.logger.Info("Invocation cancelled",
+		logging.F("invocation_id", invocationID),
+		logging.F("function_id", invocation.FunctionID),
+		logging.F("removed_from_queue", removed),
+	)
+
+	return invocation, nil
+}
+
+// signalCancel tells workers to stop the invocation if one of them is
+// running it. Workers also check for cancellation before starting, so a
+// lost signal only matters for an invocation that is already running.
+func (s *Service) signalCancel(ctx context.Context, invocationID string) {
+	if s.notifier == nil {
+		return
+	}
+
+	if err := s.notifier.Publish(ctx, CancelChannel, []byte(invocationID)); err != nil {
+		s.logger.Warn("Failed to signal invocation cancellation",
+			logging.F("invocation_id", invocationID),
+			logging.F("error", err),
+		)
+	}
+}
+
 // GetResult retrieves invocation result
 func (s *Service) GetResult(ctx context.Context, invocationID string) (*types.Invocation, error) {
 	invocation, err := s.invocationRepo.GetInvocationByID(ctx, invocationID)
@@ -119,6 +676,24 @@ func (s *Service) GetResult(ctx context.Context, invocationID string) (*types.In
 	return invocation, nil
 }
 
+// ListAttempts lists the execution attempts of an invocation
+func (s *Service) ListAttempts(ctx context.Context, invocationID string) ([]*types.InvocationAttempt, error) {
+	if _, err := s.invocationRepo.GetInvocationByID(ctx, invocationID); err != nil {
+		return nil, err
+	}
+
+	return s.invocationRepo.ListAttempts(ctx, invocationID)
+}
+
+// ListCallbackDeliveries lists the callback delivery attempts of an invocation
+func (s *Service) ListCallbackDeliveries(ctx context.Context, invocationID string) ([]*types.CallbackDelivery, error) {
+	if _, err := s.invocationRepo.GetInvocationByID(ctx, invocationID); err != nil {
+		return nil, err
+	}
+
+	return s.callbackRepo.ListCallbackDeliveries(ctx, invocationID)
+}
+
 // ListInvocations lists invocations with filters
 func (s *Service) ListInvocations(ctx context.Context, filter metadata.InvocationFilter) ([]*types.Invocation, error) {
 	invocations, err := s.invocationRepo.ListInvocations(ctx, filter)
@@ -147,7 +722,16 @@ func (s *Service) UpdateInvocationStatus(ctx context.Context, invocationID strin
 		invocation.CompletedAt = &now
 	}
 
-	return s.invocationRepo.UpdateInvocation(ctx, invocation)
+	if err := s.invocationRepo.UpdateInvocation(ctx, invocation); err != nil {
+		return err
+	}
+
+	if status.IsTerminal() {
+		s.notifyResult(ctx, invocation)
+		s.scheduleCallback(ctx, invocation)
+	}
+
+	return nil
 }
 
 // UpdateInvocationResult updates invocation result (used by workers)
@@ -161,6 +745,7 @@ func (s *Service) UpdateInvocationResult(ctx context.Context, invocationID strin
 	invocation.Result = result.Result
 	invocation.Error = result.Error
 	invocation.Metrics = result.Metrics
+	invocation.Logs = result.Logs
 
 	now := time.Now()
 	if invocation.StartedAt == nil {
@@ -168,5 +753,51 @@ func (s *Service) UpdateInvocationResult(ctx context.Context, invocationID strin
 	}
 	invocation.CompletedAt = &now
 
-	return s.invocationRepo.UpdateInvocation(ctx, invocation)
+	if err := s.invocationRepo.UpdateInvocation(ctx, invocation); err != nil {
+		return err
+	}
+
+	s.notifyResult(ctx, invocation)
+	s.scheduleCallback(ctx, invocation)
+
+	return nil
+}
+
+// notifyResult announces a terminal invocation to synchronous waiters (best effort)
+func (s *Service) notifyResult(ctx context.Context, invocation *types.Invocation) {
+	if s.notifier == nil {
+		return
+	}
+
+	if err := s.notifier.Publish(ctx, ResultChannel(invocation.ID), []byte(invocation.Status)); err != nil {
+		s.logger.Warn("Failed to publish invocation result",
+			logging.F("invocation_id", invocation.ID),
+			logging.F("error", err),
+		)
+	}
+}
+
+// scheduleCallback queues the first delivery of a finished invocation to
+// its callback URL, if it has one
+func (s *Service) scheduleCallback(ctx context.Context, invocation *types.Invocation) {
+	if invocation.CallbackURL == "" {
+		return
+	}
+
+	payload, err := json.Marshal(CallbackRequest{InvocationID: invocation.ID, Attempt: 1})
+	if err != nil {
+		s.logger.Error("Failed to marshal callback request", logging.F("error", err))
+		return
+	}
+
+	headers := map[string]string{
+		"invocation_id": invocation.ID,
+	}
+
+	if err := s.queue.Enqueue(ctx, CallbackQueueName, payload, headers); err != nil {
+		s.logger.Error("Failed to enqueue invocation callback",
+			logging.F("invocation_id", invocation.ID),
+			logging.F("error", err),
+		)
+	}
 }
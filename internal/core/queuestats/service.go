@@ -0,0 +1,101 @@
+package queuestats
+
+import (
+	"context"
+	"fmt"
+
+	"GoFaas/internal/core/invocation"
+	"GoFaas/internal/messaging"
+	"GoFaas/internal/observability/logging"
+	"GoFaas/pkg/errors"
+)
+
+// Service reports the statistics and in-flight messages of the platform's
+// queues
+type Service struct {
+	queue   messaging.Queue
+	invoker *invocation.Service
+	logger  logging.Logger
+}
+
+// NewService creates a new queue statistics service
+func NewService(queue messaging.Queue, invoker *invocation.Service, logger logging.Logger) *Service {
+	return &Service{
+		queue:   queue,
+		invoker: invoker,
+		logger:  logger,
+	}
+}
+
+// Names returns the names of the queues reported on, including the
+// execution queues of function placements
+func (s *Service) Names(ctx context.Context) ([]string, error) {
+	names, err := s.invoker.ListExecutionQueues(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(names, invocation.CallbackQueueName), nil
+}
+
+// List returns the statistics of every queue
+func (s *Service) List(ctx context.Context) ([]*messaging.QueueStats, error) {
+	names, err := s.Names(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]*messaging.QueueStats, 0, len(names))
+	for _, name := range names {
+		queueStats, err := s.queue.GetStats(ctx, name)
+		if err != nil {
+			return nil, errors.InternalError(fmt.Sprintf("failed to get queue stats: %v", err))
+		}
+		stats = append(stats, queueStats)
+	}
+
+	return stats, nil
+}
+
+// Get returns the statistics of a queue
+func (s *Service) Get(ctx context.Context, name string) (*messaging.QueueStats, error) {
+	if err := s.checkKnown(ctx, name); err != nil {
+		return nil, err
+	}
+
+	stats, err := s.queue.GetStats(ctx, name)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to get queue stats: %v", err))
+	}
+
+	return stats, nil
+}
+
+// ListPending returns the delivered, unacknowledged messages of a queue,
+// soonest lease expiry first
+func (s *Service) ListPending(ctx context.Context, name string, limit int) ([]*messaging.PendingMessage, error) {
+	if err := s.checkKnown(ctx, name); err != nil {
+		return nil, err
+	}
+
+	pending, err := s.queue.ListPending(ctx, name, limit)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to list pending messages: %v", err))
+	}
+
+	return pending, nil
+}
+
+// checkKnown returns NotFound unless name is one of the queues reported on
+func (s *Service) checkKnown(ctx context.Context, name string) error {
+	names, err := s.Names(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, known := range names {
+		if known == name {
+			return nil
+		}
+	}
+	return errors.NotFound("queue", name)
+}
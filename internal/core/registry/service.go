@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"context"
+	"time"
+
+	"GoFaas/internal/messaging"
+	"GoFaas/internal/observability/logging"
+	"GoFaas/internal/storage/metadata"
+	"GoFaas/pkg/types"
+)
+
+// HeartbeatTimeout is how long a worker counts as online after its last
+// heartbeat. Workers send one several times within it.
+const HeartbeatTimeout = messaging.ConsumerTimeout
+
+// Service reports on the registered workers and drains them
+type Service struct {
+	repo   metadata.WorkerRepository
+	logger logging.Logger
+}
+
+// NewService creates a new worker registry service
+func NewService(repo metadata.WorkerRepository, logger logging.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// List returns the registered workers, in the order they started
+func (s *Service) List(ctx context.Context) ([]*types.Worker, error) {
+	workers, err := s.repo.ListWorkers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, w := range workers {
+		w.Status = status(w, now)
+	}
+
+	return workers, nil
+}
+
+// Get retrieves a registered worker
+func (s *Service) Get(ctx context.Context, id string) (*types.Worker, error) {
+	w, err := s.repo.GetWorker(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	w.Status = status(w, time.Now())
+	return w, nil
+}
+
+// Drain asks a worker to stop taking messages. It finishes the executions
+// in progress and then idles until it is stopped. The worker learns of it
+// with its next heartbeat.
+func (s *Service) Drain(ctx context.Context, id string) (*types.Worker, error) {
+	if err := s.repo.DrainWorker(ctx, id); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Worker drain requested", logging.F("worker_id", id))
+
+	return s.Get(ctx, id)
+}
+
+// status derives whether a worker takes messages from its registration
+func status(w *types.Worker, now time.Time) types.WorkerStatus {
+	switch {
+	case now.Sub(w.LastHeartbeatAt) > HeartbeatTimeout:
+		return types.WorkerOffline
+	case w.Drained:
+		return types.WorkerDrained
+	case w.Draining:
+		return types.WorkerDraining
+	default:
+		return types.WorkerActive
+	}
+}
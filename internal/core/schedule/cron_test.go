@@ -0,0 +1,157 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "every minute", expr: "* * * * *"},
+		{name: "steps, ranges and lists", expr: "*/15 9-17 1,15 * 1-5"},
+		{name: "range with step", expr: "0-30/10 * * * *"},
+		{name: "names", expr: "0 0 * jan-mar MON,wed"},
+		{name: "sunday as 7", expr: "0 0 * * 7"},
+		{name: "macro", expr: "@Daily"},
+		{name: "surrounding spaces", expr: "  0 0 * * *  "},
+		{name: "too few fields", expr: "* * * *", wantErr: true},
+		{name: "too many fields", expr: "* * * * * *", wantErr: true},
+		{name: "minute out of range", expr: "60 * * * *", wantErr: true},
+		{name: "day of month zero", expr: "0 0 0 * *", wantErr: true},
+		{name: "reversed range", expr: "0 0 * * 5-1", wantErr: true},
+		{name: "zero step", expr: "*/0 * * * *", wantErr: true},
+		{name: "unknown name", expr: "0 0 * foo *", wantErr: true},
+		{name: "name in numeric field", expr: "0 mon * * *", wantErr: true},
+		{name: "unknown macro", expr: "@often", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCron(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCron(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+	utc := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "step",
+			expr: "*/15 * * * *",
+			from: utc(2024, 1, 1, 10, 7),
+			want: utc(2024, 1, 1, 10, 15),
+		},
+		{
+			name: "strictly after",
+			expr: "*/15 * * * *",
+			from: utc(2024, 1, 1, 10, 15),
+			want: utc(2024, 1, 1, 10, 30),
+		},
+		{
+			name: "seconds are truncated",
+			expr: "* * * * *",
+			from: time.Date(2024, 1, 1, 10, 15, 30, 0, time.UTC),
+			want: utc(2024, 1, 1, 10, 16),
+		},
+		{
+			name: "weekdays skip the weekend",
+			expr: "0 9 * * mon-fri",
+			from: utc(2024, 1, 5, 10, 0), // Friday
+			want: utc(2024, 1, 8, 9, 0),
+		},
+		{
+			name: "restricted day fields match either",
+			expr: "0 0 1,15 * 5",
+			from: utc(2024, 1, 2, 0, 0), // Tuesday
+			want: utc(2024, 1, 5, 0, 0), // Friday, before the 15th
+		},
+		{
+			name: "sunday as 7",
+			expr: "0 0 * * 7",
+			from: utc(2024, 1, 1, 0, 0), // Monday
+			want: utc(2024, 1, 7, 0, 0),
+		},
+		{
+			name: "year end",
+			expr: "@yearly",
+			from: utc(2024, 6, 1, 0, 0),
+			want: utc(2025, 1, 1, 0, 0),
+		},
+		{
+			name: "leap day",
+			expr: "0 0 29 2 *",
+			from: utc(2024, 3, 1, 0, 0),
+			want: utc(2028, 2, 29, 0, 0),
+		},
+		{
+			name: "never",
+			expr: "0 0 30 2 *",
+			from: utc(2024, 1, 1, 0, 0),
+			want: time.Time{},
+		},
+		{
+			name: "evaluated in the location",
+			expr: "0 9 * * *",
+			from: time.Date(2024, 1, 1, 10, 0, 0, 0, newYork),
+			want: time.Date(2024, 1, 2, 9, 0, 0, 0, newYork),
+		},
+		{
+			name: "skipped by spring forward",
+			expr: "30 2 * * *",
+			from: time.Date(2024, 3, 10, 0, 0, 0, 0, newYork),
+			want: time.Date(2024, 3, 11, 2, 30, 0, 0, newYork),
+		},
+		{
+			name: "hour after spring forward",
+			expr: "0 * * * *",
+			from: time.Date(2024, 3, 10, 1, 30, 0, 0, newYork),
+			want: time.Date(2024, 3, 10, 3, 0, 0, 0, newYork),
+		},
+		{
+			name: "repeated by fall back fires once",
+			expr: "30 1 * * *",
+			from: utc(2024, 11, 3, 5, 30).In(newYork), // First 01:30, EDT
+			want: time.Date(2024, 11, 4, 1, 30, 0, 0, newYork),
+		},
+		{
+			name: "after the repeated hour",
+			expr: "*/30 * * * *",
+			from: utc(2024, 11, 3, 5, 30).In(newYork), // First 01:30, EDT
+			want: time.Date(2024, 11, 3, 2, 0, 0, 0, newYork),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+			}
+
+			got := c.Next(tt.from)
+			if !got.Equal(tt.want) {
+				t.Fatalf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package schedule
+
+import "encoding/json"
+
+// CreateScheduleRequest represents a schedule creation request
+type CreateScheduleRequest struct {
+	Cron     string          `json:"cron"`
+	Timezone string          `json:"timezone"` // IANA name, defaults to UTC
+	Payload  json.RawMessage `json:"payload"`
+	Enabled  *bool           `json:"enabled,omitempty"` // Defaults to true
+}
+
+// UpdateScheduleRequest represents a schedule update request; omitted
+// fields are left unchanged
+type UpdateScheduleRequest struct {
+	Cron     *string          `json:"cron,omitempty"`
+	Timezone *string          `json:"timezone,omitempty"`
+	Payload  *json.RawMessage `json:"payload,omitempty"`
+	Enabled  *bool            `json:"enabled,omitempty"`
+}
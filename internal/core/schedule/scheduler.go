@@ -0,0 +1,198 @@
+package schedule
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"GoFaas/internal/core/invocation"
+	"GoFaas/internal/messaging"
+	"GoFaas/internal/observability/logging"
+	"GoFaas/internal/storage/metadata"
+	"GoFaas/pkg/types"
+)
+
+// SchedulerConfig holds scheduler loop configuration
+type SchedulerConfig struct {
+	PollInterval time.Duration // How often due schedules are looked up
+	BatchSize    int           // Maximum schedules fired per poll
+}
+
+// Scheduler fires due schedules by invoking their functions. Every
+// controller runs one, but only the holder of the scheduler lease fires
+// schedules; each run is additionally claimed in the database, so a run is
+// enqueued at most once even while leadership changes hands.
+type Scheduler struct {
+	scheduleRepo metadata.ScheduleRepository
+	invoker      *invocation.Service
+	lease        messaging.Lease
+	cfg          SchedulerConfig
+	logger       logging.Logger
+
+	leader bool
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a new scheduler
+func NewScheduler(
+	scheduleRepo metadata.ScheduleRepository,
+	invoker *invocation.Service,
+	lease messaging.Lease,
+	cfg SchedulerConfig,
+	logger logging.Logger,
+) *Scheduler {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+
+	return &Scheduler{
+		scheduleRepo: scheduleRepo,
+		invoker:      invoker,
+		lease:        lease,
+		cfg:          cfg,
+		logger:       logger,
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// Start begins polling for due schedules in the background
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go s.loop()
+
+	s.logger.Info("Scheduler started", logging.F("poll_interval", s.cfg.PollInterval))
+}
+
+// Stop stops the scheduler and gives up leadership so another controller
+// can take over without waiting for the lease to expire
+func (s *Scheduler) Stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.lease.Release(ctx); err != nil {
+		s.logger.Warn("Failed to release scheduler lease", logging.F("error", err))
+	}
+}
+
+// loop polls every interval until the scheduler is stopped
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			s.poll(ctx)
+			cancel()
+		}
+	}
+}
+
+// poll renews leadership and, while leading, fires the due schedules
+func (s *Scheduler) poll(ctx context.Context) {
+	leader, err := s.lease.Acquire(ctx)
+	if err != nil {
+		s.logger.Error("Failed to acquire scheduler lease", logging.F("error", err))
+		leader = false
+	}
+
+	if leader != s.leader {
+		s.leader = leader
+		if leader {
+			s.logger.Info("Became scheduler leader")
+		} else {
+			s.logger.Info("Lost scheduler leadership")
+		}
+	}
+
+	if !leader {
+		return
+	}
+
+	now := time.Now()
+	due, err := s.scheduleRepo.ListDueSchedules(ctx, now, s.cfg.BatchSize)
+	if err != nil {
+		s.logger.Error("Failed to list due schedules", logging.F("error", err))
+		return
+	}
+
+	for _, sch := range due {
+		s.fire(ctx, sch, now)
+	}
+}
+
+// fire claims the due run of a schedule and invokes its function. Runs
+// missed while no controller was leading are not replayed: the schedule
+// fires once and continues from the current time.
+func (s *Scheduler) fire(ctx context.Context, sch *types.Schedule, now time.Time) {
+	due := *sch.NextRunAt
+
+	next, err := nextRun(sch.Cron, sch.Timezone, now)
+	if err != nil {
+		// Only possible if the timezone database changed since the schedule was saved
+		s.logger.Error("Invalid schedule, disabling further runs",
+			logging.F("schedule_id", sch.ID),
+			logging.F("error", err),
+		)
+		next = time.Time{}
+	}
+
+	claimed, err := s.scheduleRepo.ClaimScheduleRun(ctx, sch.ID, due, next)
+	if err != nil {
+		s.logger.Error("Failed to claim schedule run",
+			logging.F("schedule_id", sch.ID),
+			logging.F("error", err),
+		)
+		return
+	}
+	if !claimed {
+		// Updated, deleted or fired elsewhere since it was read
+		return
+	}
+
+	handle, err := s.invoker.InvokeAsync(ctx, invocation.InvocationRequest{
+		FunctionID: sch.FunctionID,
+		Payload:    sch.Payload,
+		Headers: map[string]string{
+			"X-Schedule-Id":  sch.ID,
+			"X-Scheduled-At": due.UTC().Format(time.RFC3339),
+		},
+	})
+
+	var invocationID, runError string
+	if err != nil {
+		runError = err.Error()
+		s.logger.Warn("Scheduled invocation failed",
+			logging.F("schedule_id", sch.ID),
+			logging.F("function_id", sch.FunctionID),
+			logging.F("error", err),
+		)
+	} else {
+		invocationID = handle.InvocationID
+		s.logger.Info("Schedule fired",
+			logging.F("schedule_id", sch.ID),
+			logging.F("function_id", sch.FunctionID),
+			logging.F("invocation_id", invocationID),
+		)
+	}
+
+	if err := s.scheduleRepo.RecordScheduleRun(ctx, sch.ID, invocationID, runError); err != nil {
+		s.logger.Warn("Failed to record schedule run",
+			logging.F("schedule_id", sch.ID),
+			logging.F("error", err),
+		)
+	}
+}
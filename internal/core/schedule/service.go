@@ -0,0 +1,197 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"GoFaas/internal/observability/logging"
+	"GoFaas/internal/storage/metadata"
+	"GoFaas/pkg/errors"
+	"GoFaas/pkg/types"
+)
+
+// defaultTimezone is used when a schedule does not name one
+const defaultTimezone = "UTC"
+
+// Service implements function schedule business logic
+type Service struct {
+	functionRepo metadata.FunctionRepository
+	scheduleRepo metadata.ScheduleRepository
+	logger       logging.Logger
+}
+
+// NewService creates a new schedule service
+func NewService(
+	functionRepo metadata.FunctionRepository,
+	scheduleRepo metadata.ScheduleRepository,
+	logger logging.Logger,
+) *Service {
+	return &Service{
+		functionRepo: functionRepo,
+		scheduleRepo: scheduleRepo,
+		logger:       logger,
+	}
+}
+
+// CreateSchedule creates a schedule for a function
+func (s *Service) CreateSchedule(ctx context.Context, functionID string, req CreateScheduleRequest) (*types.Schedule, error) {
+	if _, err := s.functionRepo.GetByID(ctx, functionID); err != nil {
+		return nil, err
+	}
+
+	if req.Timezone == "" {
+		req.Timezone = defaultTimezone
+	}
+
+	now := time.Now()
+	sch := &types.Schedule{
+		ID:         uuid.New().String(),
+		FunctionID: functionID,
+		Cron:       req.Cron,
+		Timezone:   req.Timezone,
+		Payload:    req.Payload,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if err := scheduleNextRun(sch, now); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduleRepo.CreateSchedule(ctx, sch); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Schedule created",
+		logging.F("schedule_id", sch.ID),
+		logging.F("function_id", functionID),
+		logging.F("cron", sch.Cron),
+		logging.F("timezone", sch.Timezone),
+	)
+
+	return sch, nil
+}
+
+// GetSchedule retrieves a schedule of a function
+func (s *Service) GetSchedule(ctx context.Context, functionID, id string) (*types.Schedule, error) {
+	sch, err := s.scheduleRepo.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if sch.FunctionID != functionID {
+		return nil, errors.NotFound("schedule", id)
+	}
+
+	return sch, nil
+}
+
+// ListSchedules lists the schedules of a function
+func (s *Service) ListSchedules(ctx context.Context, functionID string) ([]*types.Schedule, error) {
+	if _, err := s.functionRepo.GetByID(ctx, functionID); err != nil {
+		return nil, err
+	}
+
+	return s.scheduleRepo.ListSchedules(ctx, functionID)
+}
+
+// UpdateSchedule updates a schedule. The next run is recomputed from now,
+// so changing the expression or re-enabling a schedule does not fire runs
+// that were missed in between.
+func (s *Service) UpdateSchedule(ctx context.Context, functionID, id string, req UpdateScheduleRequest) (*types.Schedule, error) {
+	sch, err := s.GetSchedule(ctx, functionID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Cron != nil {
+		sch.Cron = *req.Cron
+	}
+	if req.Timezone != nil {
+		sch.Timezone = *req.Timezone
+		if sch.Timezone == "" {
+			sch.Timezone = defaultTimezone
+		}
+	}
+	if req.Payload != nil {
+		sch.Payload = *req.Payload
+	}
+	if req.Enabled != nil {
+		sch.Enabled = *req.Enabled
+	}
+
+	now := time.Now()
+	sch.UpdatedAt = now
+	if err := scheduleNextRun(sch, now); err != nil {
+		return nil, err
+	}
+
+	if err := s.scheduleRepo.UpdateSchedule(ctx, sch); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Schedule updated",
+		logging.F("schedule_id", sch.ID),
+		logging.F("function_id", functionID),
+		logging.F("enabled", sch.Enabled),
+	)
+
+	return sch, nil
+}
+
+// DeleteSchedule deletes a schedule of a function
+func (s *Service) DeleteSchedule(ctx context.Context, functionID, id string) error {
+	if _, err := s.GetSchedule(ctx, functionID, id); err != nil {
+		return err
+	}
+
+	if err := s.scheduleRepo.DeleteSchedule(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.Info("Schedule deleted",
+		logging.F("schedule_id", id),
+		logging.F("function_id", functionID),
+	)
+
+	return nil
+}
+
+// scheduleNextRun validates the schedule's expression and timezone and
+// sets its next run after now, or clears it if the schedule is disabled
+func scheduleNextRun(sch *types.Schedule, now time.Time) error {
+	next, err := nextRun(sch.Cron, sch.Timezone, now)
+	if err != nil {
+		return errors.ValidationError(err.Error())
+	}
+	if next.IsZero() {
+		return errors.ValidationError(fmt.Sprintf("cron expression %q never matches", sch.Cron))
+	}
+
+	sch.NextRunAt = nil
+	if sch.Enabled {
+		sch.NextRunAt = &next
+	}
+
+	return nil
+}
+
+// nextRun returns the first time after the given one at which a cron
+// expression matches in a timezone. The zero time means it never does.
+func nextRun(cronExpr, timezone string, after time.Time) (time.Time, error) {
+	c, err := ParseCron(cronExpr)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unknown timezone %q", timezone)
+	}
+
+	return c.Next(after.In(loc)), nil
+}
@@ -0,0 +1,165 @@
+package version
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"GoFaas/internal/observability/logging"
+	"GoFaas/internal/storage/metadata"
+	"GoFaas/pkg/types"
+)
+
+// CanaryConfig holds canary monitoring configuration
+type CanaryConfig struct {
+	Interval         time.Duration // How often routed aliases are checked
+	Window           time.Duration // Invocations older than this are ignored
+	FailureThreshold float64       // Failure rate (0-1) at which the canary is rolled back
+	MinInvocations   int           // Terminal invocations required before judging a canary
+}
+
+// CanaryMonitor watches aliases that route traffic to an additional
+// version and sets the routing weight to zero when that version's failure
+// rate crosses the threshold
+type CanaryMonitor struct {
+	versionRepo    metadata.VersionRepository
+	invocationRepo metadata.InvocationRepository
+	cfg            CanaryConfig
+	logger         logging.Logger
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewCanaryMonitor creates a new canary monitor
+func NewCanaryMonitor(
+	versionRepo metadata.VersionRepository,
+	invocationRepo metadata.InvocationRepository,
+	cfg CanaryConfig,
+	logger logging.Logger,
+) *CanaryMonitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 5 * time.Minute
+	}
+	if cfg.MinInvocations <= 0 {
+		cfg.MinInvocations = 1
+	}
+
+	return &CanaryMonitor{
+		versionRepo:    versionRepo,
+		invocationRepo: invocationRepo,
+		cfg:            cfg,
+		logger:         logger,
+		stopCh:         make(chan struct{}),
+	}
+}
+
+// Start begins checking routed aliases in the background
+func (m *CanaryMonitor) Start() {
+	m.wg.Add(1)
+	go m.loop()
+
+	m.logger.Info("Canary monitor started",
+		logging.F("interval", m.cfg.Interval),
+		logging.F("window", m.cfg.Window),
+		logging.F("failure_threshold", m.cfg.FailureThreshold),
+	)
+}
+
+// Stop stops the monitor and waits for a running check to finish
+func (m *CanaryMonitor) Stop() {
+	close(m.stopCh)
+	m.wg.Wait()
+}
+
+// loop runs a check every interval until the monitor is stopped
+func (m *CanaryMonitor) loop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopCh:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), m.cfg.Interval)
+			m.check(ctx)
+			cancel()
+		}
+	}
+}
+
+// check evaluates every alias that currently routes traffic
+func (m *CanaryMonitor) check(ctx context.Context) {
+	aliases, err := m.versionRepo.ListRoutedAliases(ctx)
+	if err != nil {
+		m.logger.Error("Failed to list routed aliases", logging.F("error", err))
+		return
+	}
+
+	for _, alias := range aliases {
+		if err := m.checkAlias(ctx, alias); err != nil {
+			m.logger.Error("Failed to check canary",
+				logging.F("name", alias.FunctionName),
+				logging.F("alias", alias.Name),
+				logging.F("error", err),
+			)
+		}
+	}
+}
+
+// checkAlias rolls back the alias's routing if its canary version fails
+// too often. Only invocations since the routing was set are counted, so
+// failures of an earlier rollout do not count against a new one.
+func (m *CanaryMonitor) checkAlias(ctx context.Context, alias *types.FunctionAlias) error {
+	canary, err := m.versionRepo.GetVersion(ctx, alias.FunctionName, alias.Routing.Version)
+	if err != nil {
+		return err
+	}
+
+	since := time.Now().Add(-m.cfg.Window)
+	if alias.Routing.StartedAt.After(since) {
+		since = alias.Routing.StartedAt
+	}
+
+	counts, err := m.invocationRepo.CountVersionOutcomes(ctx, canary.FunctionID, canary.Version, since)
+	if err != nil {
+		return err
+	}
+
+	if counts.Total < m.cfg.MinInvocations {
+		return nil
+	}
+
+	rate := float64(counts.Failed) / float64(counts.Total)
+	if rate < m.cfg.FailureThreshold {
+		return nil
+	}
+
+	reason := fmt.Sprintf("version %d failed %d of %d invocations (%.0f%%), threshold %.0f%%",
+		canary.Version, counts.Failed, counts.Total, rate*100, m.cfg.FailureThreshold*100)
+
+	applied, err := m.versionRepo.RollbackAliasRouting(ctx, alias, reason)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		// The alias was changed since it was read; the next check sees the new routing
+		return nil
+	}
+
+	m.logger.Warn("Canary rolled back",
+		logging.F("name", alias.FunctionName),
+		logging.F("alias", alias.Name),
+		logging.F("version", canary.Version),
+		logging.F("reason", reason),
+	)
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package version
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"GoFaas/internal/observability/logging"
+	"GoFaas/internal/storage/metadata"
+	"GoFaas/pkg/types"
+)
+
+// outcome is an invocation of a version, created age before the check
+type outcome struct {
+	version int
+	status  types.ExecutionStatus
+	age     time.Duration
+}
+
+// outcomes returns n outcomes of the canary version with the given status
+func outcomes(n int, status types.ExecutionStatus) []outcome {
+	out := make([]outcome, n)
+	for i := range out {
+		out[i] = outcome{version: 2, status: status, age: time.Second}
+	}
+	return out
+}
+
+func TestCanaryCheckAlias(t *testing.T) {
+	tests := []struct {
+		name         string
+		outcomes     []outcome
+		wantRollback bool
+	}{
+		{
+			name:     "too few invocations",
+			outcomes: outcomes(3, types.StatusFailed),
+		},
+		{
+			name:     "below threshold",
+			outcomes: append(outcomes(4, types.StatusFailed), outcomes(6, types.StatusCompleted)...),
+		},
+		{
+			name:         "at threshold",
+			outcomes:     append(outcomes(5, types.StatusFailed), outcomes(5, types.StatusCompleted)...),
+			wantRollback: true,
+		},
+		{
+			name:         "timeouts count as failures",
+			outcomes:     append(outcomes(6, types.StatusTimeout), outcomes(4, types.StatusCompleted)...),
+			wantRollback: true,
+		},
+		{
+			name:     "unfinished invocations are not counted",
+			outcomes: append(outcomes(4, types.StatusFailed), outcomes(5, types.StatusRunning)...),
+		},
+		{
+			name: "other versions are not counted",
+			outcomes: append(append(outcomes(2, types.StatusFailed), outcomes(3, types.StatusCompleted)...),
+				outcome{version: 1, status: types.StatusFailed, age: time.Second},
+				outcome{version: 1, status: types.StatusFailed, age: time.Second},
+			),
+		},
+		{
+			name: "failures before the rollout are not counted",
+			outcomes: append(outcomes(4, types.StatusCompleted),
+				outcome{version: 2, status: types.StatusFailed, age: 2 * time.Minute},
+				outcome{version: 2, status: types.StatusFailed, age: 2 * time.Minute},
+				outcome{version: 2, status: types.StatusFailed, age: 2 * time.Minute},
+				outcome{version: 2, status: types.StatusFailed, age: 2 * time.Minute},
+				outcome{version: 2, status: types.StatusFailed, age: 2 * time.Minute},
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now()
+
+			repo := metadata.NewMemoryRepository()
+			if err := repo.Create(ctx, &types.Function{ID: "f1", Name: "hello", Version: "1.0.0", CreatedAt: now, UpdatedAt: now}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			for _, id := range []string{"v1", "v2"} {
+				if err := repo.CreateVersion(ctx, &types.FunctionVersion{ID: id, FunctionID: "f1", FunctionName: "hello", CreatedAt: now}); err != nil {
+					t.Fatalf("CreateVersion: %v", err)
+				}
+			}
+			if err := repo.PutAlias(ctx, &types.FunctionAlias{
+				FunctionName: "hello",
+				Name:         "live",
+				Version:      1,
+				Routing:      &types.AliasRouting{Version: 2, Weight: 10, StartedAt: now.Add(-time.Minute)},
+				UpdatedAt:    now,
+			}); err != nil {
+				t.Fatalf("PutAlias: %v", err)
+			}
+
+			for i, o := range tt.outcomes {
+				if err := repo.CreateInvocation(ctx, &types.Invocation{
+					ID:              fmt.Sprintf("inv-%d", i),
+					FunctionID:      "f1",
+					FunctionVersion: o.version,
+					Status:          o.status,
+					CreatedAt:       now.Add(-o.age),
+				}); err != nil {
+					t.Fatalf("CreateInvocation: %v", err)
+				}
+			}
+
+			m := NewCanaryMonitor(repo, repo, CanaryConfig{
+				Window:           5 * time.Minute,
+				FailureThreshold: 0.5,
+				MinInvocations:   5,
+			}, logging.NewSimpleLogger())
+
+			alias, err := repo.GetAlias(ctx, "hello", "live")
+			if err != nil {
+				t.Fatalf("GetAlias: %v", err)
+			}
+			if err := m.checkAlias(ctx, alias); err != nil {
+				t.Fatalf("checkAlias: %v", err)
+			}
+
+			alias, err = repo.GetAlias(ctx, "hello", "live")
+			if err != nil {
+				t.Fatalf("GetAlias: %v", err)
+			}
+			rolledBack := alias.Routing.Weight == 0 && alias.Routing.RolledBackAt != nil
+			if rolledBack != tt.wantRollback {
+				t.Fatalf("routing = %+v, want rollback %v", alias.Routing, tt.wantRollback)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package version
+
+// PublishRequest represents a request to publish a function version
+type PublishRequest struct {
+	Description string `json:"description"`
+}
+
+// AliasRequest represents a request to create or move an alias
+type AliasRequest struct {
+	Version     int             `json:"version"`
+	Description string          `json:"description"`
+	Routing     *RoutingRequest `json:"routing,omitempty"`
+}
+
+// RoutingRequest sends a percentage of an alias's invocations to an
+// additional version
+type RoutingRequest struct {
+	Version int `json:"version"`
+	Weight  int `json:"weight"` // Percentage of invocations, 0-100
+}
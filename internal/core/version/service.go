@@ -0,0 +1,246 @@
+package version
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"GoFaas/internal/observability/logging"
+	"GoFaas/internal/storage/function"
+	"GoFaas/internal/storage/metadata"
+	"GoFaas/pkg/errors"
+	"GoFaas/pkg/types"
+	"GoFaas/pkg/utils"
+)
+
+// aliasNameRegex validates alias names. Aliases start with a letter so
+// they cannot be confused with version numbers in a reference.
+var aliasNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+
+// Service implements function version and alias business logic
+type Service struct {
+	functionRepo metadata.FunctionRepository
+	versionRepo  metadata.VersionRepository
+	storage      function.Storage
+	logger       logging.Logger
+}
+
+// NewService creates a new version service
+func NewService(
+	functionRepo metadata.FunctionRepository,
+	versionRepo metadata.VersionRepository,
+	storage function.Storage,
+	logger logging.Logger,
+) *Service {
+	return &Service{
+		functionRepo: functionRepo,
+		versionRepo:  versionRepo,
+		storage:      storage,
+		logger:       logger,
+	}
+}
+
+// ParseReference splits a "name:qualifier" function reference, where the
+// qualifier is an alias or a version number. ok is false for plain function
+// IDs.
+func ParseReference(ref string) (name, qualifier string, ok bool) {
+	name, qualifier, ok = strings.Cut(ref, ":")
+	return name, qualifier, ok
+}
+
+// Resolve returns the version a "name:qualifier" reference points at. For
+// an alias with routing, the additional version is picked for the routed
+// percentage of calls.
+func Resolve(ctx context.Context, repo metadata.VersionRepository, name, qualifier string) (*types.FunctionVersion, error) {
+	if n, err := strconv.Atoi(qualifier); err == nil {
+		return repo.GetVersion(ctx, name, n)
+	}
+
+	alias, err := repo.GetAlias(ctx, name, qualifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.GetVersion(ctx, name, pickVersion(alias))
+}
+
+// pickVersion chooses the version an alias invocation runs
+func pickVersion(alias *types.FunctionAlias) int {
+	if alias.Routing != nil && alias.Routing.Weight > 0 && rand.Intn(100) < alias.Routing.Weight {
+		return alias.Routing.Version
+	}
+	return alias.Version
+}
+
+// Publish snapshots the current code and configuration of a function as
+// the next version of its name. The code and build artifact are copied, so
+// later updates of the function do not affect the version.
+func (s *Service) Publish(ctx context.Context, functionID string, req PublishRequest) (*types.FunctionVersion, error) {
+	fn, err := s.functionRepo.GetByID(ctx, functionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if fn.Status != types.FunctionReady {
+		return nil, errors.Conflict(fmt.Sprintf("function %s is %s; only ready functions can be published", fn.ID, fn.Status))
+	}
+
+	versionID := uuid.New().String()
+
+	code, err := s.storage.Retrieve(ctx, fn.Code.Source)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to retrieve function code: %v", err))
+	}
+	if utils.SHA256Hash(code) != fn.Code.Checksum {
+		return nil, errors.Conflict(fmt.Sprintf("function %s changed while publishing, retry", fn.ID))
+	}
+
+	codeLocation, err := s.storage.StoreVersion(ctx, fn.ID, versionID, "code", code)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to store version code: %v", err))
+	}
+
+	var artifactLocation string
+	if fn.Code.Artifact != "" {
+		artifact, err := s.storage.Retrieve(ctx, fn.Code.Artifact)
+		if err != nil {
+			// The build was replaced by a concurrent update
+			s.storage.Delete(ctx, codeLocation)
+			return nil, errors.Conflict(fmt.Sprintf("function %s changed while publishing, retry", fn.ID))
+		}
+
+		artifactLocation, err = s.storage.StoreVersion(ctx, fn.ID, versionID, "artifact", artifact)
+		if err != nil {
+			s.storage.Delete(ctx, codeLocation)
+			return nil, errors.InternalError(fmt.Sprintf("failed to store version artifact: %v", err))
+		}
+	}
+
+	v := &types.FunctionVersion{
+		ID:           versionID,
+		FunctionID:   fn.ID,
+		FunctionName: fn.Name,
+		Runtime:      fn.Runtime,
+		Handler:      fn.Handler,
+		Code: types.FunctionCode{
+			Source:     codeLocation,
+			SourceType: fn.Code.SourceType,
+			Checksum:   fn.Code.Checksum,
+			Size:       fn.Code.Size,
+			Format:     fn.Code.Format,
+			Artifact:   artifactLocation,
+		},
+		Config:      fn.Config,
+		Description: req.Description,
+		CreatedAt:   time.Now(),
+	}
+
+	if err := s.versionRepo.CreateVersion(ctx, v); err != nil {
+		s.storage.Delete(ctx, codeLocation)
+		return nil, err
+	}
+
+	s.logger.Info("Function version published",
+		logging.F("function_id", fn.ID),
+		logging.F("name", fn.Name),
+		logging.F("version", v.Version),
+	)
+
+	return v, nil
+}
+
+// ListVersions returns the published versions of a function name, newest first
+func (s *Service) ListVersions(ctx context.Context, name string) ([]*types.FunctionVersion, error) {
+	return s.versionRepo.ListVersions(ctx, name)
+}
+
+// GetVersion retrieves a published version
+func (s *Service) GetVersion(ctx context.Context, name string, version int) (*types.FunctionVersion, error) {
+	return s.versionRepo.GetVersion(ctx, name, version)
+}
+
+// PutAlias creates an alias or atomically moves it to another version
+func (s *Service) PutAlias(ctx context.Context, name, aliasName string, req AliasRequest) (*types.FunctionAlias, error) {
+	if !aliasNameRegex.MatchString(aliasName) {
+		return nil, errors.ValidationError("alias name must start with a lowercase letter and contain only lowercase letters, digits, hyphens and underscores (max 63 characters)")
+	}
+	if req.Version <= 0 {
+		return nil, errors.ValidationError("version must be positive")
+	}
+
+	now := time.Now()
+	alias := &types.FunctionAlias{
+		FunctionName: name,
+		Name:         aliasName,
+		Version:      req.Version,
+		Description:  req.Description,
+		UpdatedAt:    now,
+	}
+
+	if req.Routing != nil {
+		if req.Routing.Version <= 0 {
+			return nil, errors.ValidationError("routing version must be positive")
+		}
+		if req.Routing.Version == req.Version {
+			return nil, errors.ValidationError("routing version must differ from the alias version")
+		}
+		if req.Routing.Weight < 0 || req.Routing.Weight > 100 {
+			return nil, errors.ValidationError("routing weight must be between 0 and 100")
+		}
+
+		alias.Routing = &types.AliasRouting{
+			Version:   req.Routing.Version,
+			Weight:    req.Routing.Weight,
+			StartedAt: now,
+		}
+	}
+
+	if err := s.versionRepo.PutAlias(ctx, alias); err != nil {
+		return nil, err
+	}
+
+	fields := []logging.Field{
+		logging.F("name", name),
+		logging.F("alias", aliasName),
+		logging.F("version", req.Version),
+	}
+	if alias.Routing != nil {
+		fields = append(fields,
+			logging.F("routing_version", alias.Routing.Version),
+			logging.F("routing_weight", alias.Routing.Weight),
+		)
+	}
+	s.logger.Info("Function alias updated", fields...)
+
+	return alias, nil
+}
+
+// GetAlias retrieves an alias
+func (s *Service) GetAlias(ctx context.Context, name, aliasName string) (*types.FunctionAlias, error) {
+	return s.versionRepo.GetAlias(ctx, name, aliasName)
+}
+
+// ListAliases lists the aliases of a function name
+func (s *Service) ListAliases(ctx context.Context, name string) ([]*types.FunctionAlias, error) {
+	return s.versionRepo.ListAliases(ctx, name)
+}
+
+// DeleteAlias deletes an alias
+func (s *Service) DeleteAlias(ctx context.Context, name, aliasName string) error {
+	if err := s.versionRepo.DeleteAlias(ctx, name, aliasName); err != nil {
+		return err
+	}
+
+	s.logger.Info("Function alias deleted",
+		logging.F("name", name),
+		logging.F("alias", aliasName),
+	)
+
+	return nil
+}
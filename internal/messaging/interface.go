@@ -2,9 +2,18 @@ package messaging
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// ConsumerTimeout is how long a consumer counts as active after its last
+// heartbeat. Consumers should heartbeat several times within it.
+const ConsumerTimeout = 30 * time.Second
+
+// ErrLeaseLost is returned when renewing the lease of a message that was
+// acknowledged or claimed by another consumer after its lease expired
+var ErrLeaseLost = errors.New("message lease lost")
+
 // Message represents a queue message
 type Message struct {
 	ID         string            `json:"id"`
@@ -13,23 +22,120 @@ type Message struct {
 	Headers    map[string]string `json:"headers"`
 	Attempts   int               `json:"attempts"`
 	EnqueuedAt time.Time         `json:"enqueued_at"`
+
+	receipt string // Identifies the delivery to the queue that made it
+}
+
+// PendingMessage describes a delivered message that has not been
+// acknowledged yet
+type PendingMessage struct {
+	ID             string    `json:"id"`
+	Consumer       string    `json:"consumer,omitempty"` // Consumer holding the message, if the queue tracks it
+	Attempts       int       `json:"attempts"`           // Deliveries including the current one
+	LeaseExpiresAt time.Time `json:"lease_expires_at"`   // The message can be claimed after this
+}
+
+// EnqueueOptions controls how a message is enqueued
+type EnqueueOptions struct {
+	MessageID string    // Message ID; generated if empty
+	RunAt     time.Time // Deliver no earlier than this; zero delivers immediately
 }
 
 // Queue defines message queue operations
 type Queue interface {
 	Enqueue(ctx context.Context, queue string, payload []byte, headers map[string]string) error
+	EnqueueWithOptions(ctx context.Context, queue string, payload []byte, headers map[string]string, opts EnqueueOptions) error
+	// Remove deletes a delayed or queued message that has not been
+	// delivered to a consumer yet and reports whether it was found
+	Remove(ctx context.Context, queue, messageID string) (bool, error)
+	// Dequeue delivers a message under a lease that expires after the
+	// queue's visibility timeout unless it is extended
 	Dequeue(ctx context.Context, queue string, timeout time.Duration) (*Message, error)
+	// DequeueFirst delivers a message like Dequeue from the first of the
+	// queues, in order, that has one. It may return no message before
+	// timeout so the caller can choose a new order.
+	DequeueFirst(ctx context.Context, queues []string, timeout time.Duration) (*Message, error)
+	// ExtendLease renews the lease of a delivered message. It returns
+	// ErrLeaseLost if the lease expired and the message was claimed.
+	ExtendLease(ctx context.Context, message *Message) error
+	// ClaimExpired takes over up to limit delivered messages whose lease
+	// expired, as if they were delivered to the caller again. Their
+	// Attempts are those of the delivery whose lease expired: the claim
+	// itself is not an attempt. The caller must ack, requeue or dead-letter
+	// them before the new lease expires.
+	ClaimExpired(ctx context.Context, queue string, limit int) ([]*Message, error)
+	// ListPending returns up to limit delivered messages that have not been
+	// acknowledged, the soonest lease expiry first
+	ListPending(ctx context.Context, queue string, limit int) ([]*PendingMessage, error)
 	Ack(ctx context.Context, message *Message) error
 	Nack(ctx context.Context, message *Message) error
+	// Requeue returns a message to the queue for redelivery after delay,
+	// keeping its attempt count. Lowering message.Attempts first keeps the
+	// delivery from counting as an attempt.
+	Requeue(ctx context.Context, message *Message, delay time.Duration) error
 	DeadLetter(ctx context.Context, message *Message, reason string) error
+	// ListDeadLetters returns the dead-lettered messages of a queue, most
+	// recently dead-lettered first
+	ListDeadLetters(ctx context.Context, queue string) ([]*Message, error)
+	// RemoveDeadLetter deletes a dead-lettered message and reports whether
+	// it was found
+	RemoveDeadLetter(ctx context.Context, queue, messageID string) (bool, error)
+	// Heartbeat records that consumer is active on the queue. GetStats
+	// counts the consumers that sent one within ConsumerTimeout.
+	Heartbeat(ctx context.Context, queue, consumer string) error
 	GetStats(ctx context.Context, queue string) (*QueueStats, error)
 }
 
 // QueueStats represents queue statistics
 type QueueStats struct {
-	Name        string  `json:"name"`
-	Size        int64   `json:"size"`
-	Consumers   int     `json:"consumers"`
-	EnqueueRate float64 `json:"enqueue_rate"`
-	DequeueRate float64 `json:"dequeue_rate"`
+	Name             string        `json:"name"`
+	Size             int64         `json:"size"`               // Messages ready for delivery
+	InFlight         int64         `json:"in_flight"`          // Delivered messages not acknowledged yet
+	Delayed          int64         `json:"delayed"`            // Messages held back until a later time
+	DeadLetters      int64         `json:"dead_letters"`       // Dead-lettered messages
+	OldestMessageAge time.Duration `json:"oldest_message_age"` // How long the oldest ready message has waited; zero if none
+	Consumers        int           `json:"consumers"`          // Consumers that sent a heartbeat within ConsumerTimeout
+	EnqueueRate      float64       `json:"enqueue_rate"`       // Messages enqueued per second over the last minute
+	DequeueRate      float64       `json:"dequeue_rate"`       // Messages delivered per second over the last minute
+}
+
+// Notifier defines fire-and-forget event publishing between processes.
+// Unlike Queue, events are not persisted: only subscribers connected at
+// publish time receive them.
+type Notifier interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	Subscribe(ctx context.Context, channel string) (Subscription, error)
+}
+
+// Subscription represents an active channel subscription
+type Subscription interface {
+	// Events returns the channel on which published payloads are delivered
+	Events() <-chan []byte
+	// Close unsubscribes and releases resources
+	Close() error
+}
+
+// Semaphore bounds how many holders share a named resource at once across
+// processes. Slots expire unless their holder renews them within the
+// semaphore's TTL, so the slots of a crashed holder are freed.
+type Semaphore interface {
+	// Acquire takes a slot of name for holder if fewer than limit are held,
+	// or renews the slot holder already has, and reports whether holder
+	// has one
+	Acquire(ctx context.Context, name, holder string, limit int) (bool, error)
+	// Release frees the slot of name held by holder, if any
+	Release(ctx context.Context, name, holder string) error
+	// Count returns the number of slots of name held
+	Count(ctx context.Context, name string) (int, error)
+}
+
+// Lease is an expiring lock held by at most one process at a time. It is
+// used to elect a leader among replicas: the holder must renew it before
+// the TTL elapses or another process may take over.
+type Lease interface {
+	// Acquire takes the lease, or renews it if this process already holds
+	// it, and reports whether it is held
+	Acquire(ctx context.Context) (bool, error)
+	// Release gives the lease up if this process holds it
+	Release(ctx context.Context) error
 }
@@ -0,0 +1,530 @@
+package messaging
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryQueue implements Queue in process memory, for tests and the
+// single-process development binary. It keeps the semantics of the Redis
+// queues: delayed delivery, leases that expire unless extended, claiming of
+// expired leases and dead letters. Messages are lost when the process
+// exits.
+type MemoryQueue struct {
+	mu                sync.Mutex
+	queues            map[string]*memoryQueue
+	visibilityTimeout time.Duration
+	deliveries        uint64        // Source of delivery receipts
+	wakeup            chan struct{} // Closed when a message is queued on any queue
+}
+
+// memoryQueue holds the messages of one named queue
+type memoryQueue struct {
+	ready       []*readyMessage             // Queued messages, oldest first
+	delayed     map[string]*delayedMessage  // Delayed messages by ID
+	inflight    map[string]*inflightMessage // Delivered messages by receipt
+	deadLetters []*Message                  // Most recently dead-lettered first
+	consumers   map[string]time.Time        // Last heartbeat by consumer
+	enqueued    map[int64]int64             // Enqueues by rate bucket (unix seconds)
+	dequeued    map[int64]int64             // Dequeues by rate bucket (unix seconds)
+}
+
+// readyMessage is a queued message and the time it became ready
+type readyMessage struct {
+	message *Message
+	readyAt time.Time
+}
+
+// delayedMessage is a message held back until runAt
+type delayedMessage struct {
+	message *Message
+	runAt   time.Time
+}
+
+// inflightMessage is a delivered message and the expiry of its lease
+type inflightMessage struct {
+	message   *Message
+	expiresAt time.Time
+}
+
+// NewMemoryQueue creates a new in-memory queue. Delivered messages whose
+// lease is not renewed within visibilityTimeout can be claimed by
+// ClaimExpired.
+func NewMemoryQueue(visibilityTimeout time.Duration) *MemoryQueue {
+	if visibilityTimeout <= 0 {
+		visibilityTimeout = defaultVisibilityTimeout
+	}
+
+	return &MemoryQueue{
+		queues:            make(map[string]*memoryQueue),
+		visibilityTimeout: visibilityTimeout,
+		wakeup:            make(chan struct{}),
+	}
+}
+
+// Enqueue adds a message to the queue
+func (q *MemoryQueue) Enqueue(ctx context.Context, queue string, payload []byte, headers map[string]string) error {
+	return q.EnqueueWithOptions(ctx, queue, payload, headers, EnqueueOptions{})
+}
+
+// EnqueueWithOptions adds a message to the queue, holding it back until
+// opts.RunAt if that is in the future
+func (q *MemoryQueue) EnqueueWithOptions(ctx context.Context, queue string, payload []byte, headers map[string]string, opts EnqueueOptions) error {
+	if opts.MessageID == "" {
+		opts.MessageID = uuid.New().String()
+	}
+
+	message := cloneMessage(&Message{
+		ID:         opts.MessageID,
+		Queue:      queue,
+		Payload:    payload,
+		Headers:    headers,
+		EnqueuedAt: time.Now(),
+	})
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	mq := q.queue(queue)
+	q.schedule(mq, message, opts.RunAt)
+	countBucket(mq.enqueued, time.Now())
+	return nil
+}
+
+// Remove deletes a delayed or queued message before it is delivered
+func (q *MemoryQueue) Remove(ctx context.Context, queue, messageID string) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	mq := q.queue(queue)
+	if _, ok := mq.delayed[messageID]; ok {
+		delete(mq.delayed, messageID)
+		return true, nil
+	}
+
+	for i, ready := range mq.ready {
+		if ready.message.ID == messageID {
+			mq.ready = append(mq.ready[:i], mq.ready[i+1:]...)
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Dequeue removes and returns a message from the queue, waiting up to
+// timeout for one to become available. A timeout of zero waits until ctx
+// is done.
+func (q *MemoryQueue) Dequeue(ctx context.Context, queue string, timeout time.Duration) (*Message, error) {
+	return q.DequeueFirst(ctx, []string{queue}, timeout)
+}
+
+// DequeueFirst removes and returns a message from the first of the queues
+// that has one, waiting up to timeout for one to become available. A
+// timeout of zero waits until ctx is done.
+func (q *MemoryQueue) DequeueFirst(ctx context.Context, queues []string, timeout time.Duration) (*Message, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	for {
+		q.mu.Lock()
+		var next time.Time
+		for _, queue := range queues {
+			mq := q.queue(queue)
+			if due := q.promoteDelayed(mq); !due.IsZero() && (next.IsZero() || due.Before(next)) {
+				next = due
+			}
+
+			if len(mq.ready) > 0 {
+				message := mq.ready[0].message
+				mq.ready = mq.ready[1:]
+				delivered := q.deliver(mq, message)
+				countBucket(mq.dequeued, time.Now())
+				q.mu.Unlock()
+				return delivered, nil
+			}
+		}
+		wakeup := q.wakeup
+		q.mu.Unlock()
+
+		// Wake up for the next delayed message or the deadline, whichever
+		// comes first
+		var wait time.Duration
+		if !next.IsZero() {
+			wait = time.Until(next)
+		}
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return nil, nil // No message available
+			}
+			if next.IsZero() || remaining < wait {
+				wait = remaining
+			}
+		}
+
+		var timer <-chan time.Time
+		var t *time.Timer
+		if !next.IsZero() || !deadline.IsZero() {
+			t = time.NewTimer(wait)
+			timer = t.C
+		}
+
+		select {
+		case <-wakeup:
+		case <-timer:
+		case <-ctx.Done():
+			if t != nil {
+				t.Stop()
+			}
+			return nil, ctx.Err()
+		}
+		if t != nil {
+			t.Stop()
+		}
+	}
+}
+
+// ExtendLease renews the lease of a delivered message for another
+// visibility timeout
+func (q *MemoryQueue) ExtendLease(ctx context.Context, message *Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	inflight, ok := q.queue(message.Queue).inflight[message.receipt]
+	if !ok {
+		return ErrLeaseLost
+	}
+
+	inflight.expiresAt = time.Now().Add(q.visibilityTimeout)
+	return nil
+}
+
+// ClaimExpired takes over delivered messages whose lease expired. Each
+// claim is a new delivery with a new receipt, so the consumer that lost the
+// lease can no longer extend it.
+func (q *MemoryQueue) ClaimExpired(ctx context.Context, queue string, limit int) ([]*Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	mq := q.queue(queue)
+	now := time.Now()
+
+	expired := make([]*inflightMessage, 0)
+	for _, inflight := range mq.inflight {
+		if !inflight.expiresAt.After(now) {
+			expired = append(expired, inflight)
+		}
+	}
+	sortInflight(expired)
+	if limit > 0 && len(expired) > limit {
+		expired = expired[:limit]
+	}
+
+	messages := make([]*Message, 0, len(expired))
+	for _, inflight := range expired {
+		delete(mq.inflight, inflight.message.receipt)
+		// The claim is not an attempt; it keeps the attempt of the delivery
+		// whose lease expired
+		inflight.message.Attempts--
+		messages = append(messages, q.deliver(mq, inflight.message))
+		countBucket(mq.dequeued, now)
+	}
+
+	return messages, nil
+}
+
+// ListPending returns delivered messages by lease expiry
+func (q *MemoryQueue) ListPending(ctx context.Context, queue string, limit int) ([]*PendingMessage, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	mq := q.queue(queue)
+	inflight := make([]*inflightMessage, 0, len(mq.inflight))
+	for _, m := range mq.inflight {
+		inflight = append(inflight, m)
+	}
+	sortInflight(inflight)
+	if limit > 0 && len(inflight) > limit {
+		inflight = inflight[:limit]
+	}
+
+	pending := make([]*PendingMessage, 0, len(inflight))
+	for _, m := range inflight {
+		pending = append(pending, &PendingMessage{
+			ID:             m.message.ID,
+			Attempts:       m.message.Attempts,
+			LeaseExpiresAt: m.expiresAt,
+		})
+	}
+
+	return pending, nil
+}
+
+// Ack acknowledges successful message processing
+func (q *MemoryQueue) Ack(ctx context.Context, message *Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.release(q.queue(message.Queue), message)
+	return nil
+}
+
+// Nack rejects a message and requeues it
+func (q *MemoryQueue) Nack(ctx context.Context, message *Message) error {
+	return q.Requeue(ctx, message, 0)
+}
+
+// Requeue returns a delivered message to the queue. With a positive delay
+// it is held back like a message enqueued with RunAt, so it can still be
+// removed by ID.
+func (q *MemoryQueue) Requeue(ctx context.Context, message *Message, delay time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	mq := q.queue(message.Queue)
+	q.release(mq, message)
+
+	var runAt time.Time
+	if delay > 0 {
+		runAt = time.Now().Add(delay)
+	}
+	q.schedule(mq, cloneMessage(message), runAt)
+
+	return nil
+}
+
+// DeadLetter moves a message to the dead letters of its queue
+func (q *MemoryQueue) DeadLetter(ctx context.Context, message *Message, reason string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// Add reason to message headers
+	if message.Headers == nil {
+		message.Headers = make(map[string]string)
+	}
+	message.Headers["dead_letter_reason"] = reason
+	message.Headers["dead_lettered_at"] = time.Now().Format(time.RFC3339)
+
+	mq := q.queue(message.Queue)
+	q.release(mq, message)
+
+	deadLetter := cloneMessage(message)
+	deadLetter.receipt = ""
+	mq.deadLetters = append([]*Message{deadLetter}, mq.deadLetters...)
+
+	return nil
+}
+
+// ListDeadLetters returns the dead-lettered messages of a queue
+func (q *MemoryQueue) ListDeadLetters(ctx context.Context, queue string) ([]*Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	deadLetters := q.queue(queue).deadLetters
+	messages := make([]*Message, 0, len(deadLetters))
+	for _, message := range deadLetters {
+		messages = append(messages, cloneMessage(message))
+	}
+
+	return messages, nil
+}
+
+// RemoveDeadLetter deletes a message from the dead letters of a queue
+func (q *MemoryQueue) RemoveDeadLetter(ctx context.Context, queue, messageID string) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	mq := q.queue(queue)
+	for i, message := range mq.deadLetters {
+		if message.ID == messageID {
+			mq.deadLetters = append(mq.deadLetters[:i], mq.deadLetters[i+1:]...)
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Heartbeat records that consumer is active on the queue
+func (q *MemoryQueue) Heartbeat(ctx context.Context, queue, consumer string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.queue(queue).consumers[consumer] = time.Now()
+	return nil
+}
+
+// GetStats returns queue statistics. OldestMessageAge is measured from when
+// the oldest queued message became ready.
+func (q *MemoryQueue) GetStats(ctx context.Context, queue string) (*QueueStats, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	mq := q.queue(queue)
+	now := time.Now()
+	q.promoteDelayed(mq)
+
+	stats := &QueueStats{
+		Name:        queue,
+		Size:        int64(len(mq.ready)),
+		InFlight:    int64(len(mq.inflight)),
+		Delayed:     int64(len(mq.delayed)),
+		DeadLetters: int64(len(mq.deadLetters)),
+		EnqueueRate: ratePerSecond(sumBuckets(mq.enqueued, now), now),
+		DequeueRate: ratePerSecond(sumBuckets(mq.dequeued, now), now),
+	}
+
+	for _, ready := range mq.ready {
+		if age := now.Sub(ready.readyAt); age > stats.OldestMessageAge {
+			stats.OldestMessageAge = age
+		}
+	}
+
+	for consumer, lastSeen := range mq.consumers {
+		if now.Sub(lastSeen) < ConsumerTimeout {
+			stats.Consumers++
+		} else {
+			delete(mq.consumers, consumer)
+		}
+	}
+
+	return stats, nil
+}
+
+// queue returns the state of a named queue, creating it on first use. The
+// caller must hold q.mu.
+func (q *MemoryQueue) queue(name string) *memoryQueue {
+	mq, ok := q.queues[name]
+	if !ok {
+		mq = &memoryQueue{
+			delayed:   make(map[string]*delayedMessage),
+			inflight:  make(map[string]*inflightMessage),
+			consumers: make(map[string]time.Time),
+			enqueued:  make(map[int64]int64),
+			dequeued:  make(map[int64]int64),
+		}
+		q.queues[name] = mq
+	}
+	return mq
+}
+
+// schedule queues a message, or holds it back if runAt is in the future.
+// The caller must hold q.mu.
+func (q *MemoryQueue) schedule(mq *memoryQueue, message *Message, runAt time.Time) {
+	message.receipt = ""
+	now := time.Now()
+	if runAt.After(now) {
+		mq.delayed[message.ID] = &delayedMessage{message: message, runAt: runAt}
+	} else {
+		mq.ready = append(mq.ready, &readyMessage{message: message, readyAt: now})
+	}
+
+	// Wake up waiting consumers, including for a delayed message that is
+	// due sooner than the one they wait for
+	close(q.wakeup)
+	q.wakeup = make(chan struct{})
+}
+
+// promoteDelayed queues the delayed messages that are due, in delivery
+// order, and returns the delivery time of the next delayed message, or zero
+// if there is none. The caller must hold q.mu.
+func (q *MemoryQueue) promoteDelayed(mq *memoryQueue) time.Time {
+	now := time.Now()
+
+	due := make([]*delayedMessage, 0)
+	var next time.Time
+	for id, delayed := range mq.delayed {
+		if !delayed.runAt.After(now) {
+			due = append(due, delayed)
+			delete(mq.delayed, id)
+		} else if next.IsZero() || delayed.runAt.Before(next) {
+			next = delayed.runAt
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].runAt.Before(due[j].runAt)
+	})
+	for _, delayed := range due {
+		mq.ready = append(mq.ready, &readyMessage{message: delayed.message, readyAt: delayed.runAt})
+	}
+
+	return next
+}
+
+// deliver leases a message to the caller, counting the delivery, and
+// returns the caller's copy. The caller must hold q.mu.
+func (q *MemoryQueue) deliver(mq *memoryQueue, message *Message) *Message {
+	q.deliveries++
+	message.receipt = strconv.FormatUint(q.deliveries, 10)
+	message.Attempts++
+
+	mq.inflight[message.receipt] = &inflightMessage{
+		message:   message,
+		expiresAt: time.Now().Add(q.visibilityTimeout),
+	}
+
+	return cloneMessage(message)
+}
+
+// release ends the lease of a delivered message. Messages that were not
+// delivered by this queue, or whose lease was claimed, have nothing to
+// release. The caller must hold q.mu.
+func (q *MemoryQueue) release(mq *memoryQueue, message *Message) {
+	if message.receipt == "" {
+		return
+	}
+	delete(mq.inflight, message.receipt)
+}
+
+// countBucket adds one to the current rate bucket of an event, dropping
+// buckets that left the rate window
+func countBucket(counts map[int64]int64, now time.Time) {
+	oldest := rateBuckets(now)[0].Unix()
+	for bucket := range counts {
+		if bucket < oldest {
+			delete(counts, bucket)
+		}
+	}
+	counts[now.Truncate(rateBucket).Unix()]++
+}
+
+// sumBuckets adds up the counts of the buckets in the rate window
+func sumBuckets(counts map[int64]int64, now time.Time) int64 {
+	var total int64
+	for _, bucket := range rateBuckets(now) {
+		total += counts[bucket.Unix()]
+	}
+	return total
+}
+
+// sortInflight orders delivered messages by lease expiry, soonest first
+func sortInflight(inflight []*inflightMessage) {
+	sort.Slice(inflight, func(i, j int) bool {
+		return inflight[i].expiresAt.Before(inflight[j].expiresAt)
+	})
+}
+
+// cloneMessage copies a message so the queue and its callers do not share
+// headers or payload
+func cloneMessage(message *Message) *Message {
+	clone := *message
+	if message.Payload != nil {
+		clone.Payload = append([]byte(nil), message.Payload...)
+	}
+	if message.Headers != nil {
+		clone.Headers = make(map[string]string, len(message.Headers))
+		for k, v := range message.Headers {
+			clone.Headers[k] = v
+		}
+	}
+	return &clone
+}
@@ -0,0 +1,22 @@
+package messaging
+
+import "context"
+
+// MemoryLease implements Lease for a single process, which is always the
+// leader
+type MemoryLease struct{}
+
+// NewMemoryLease creates a lease that is always held
+func NewMemoryLease() *MemoryLease {
+	return &MemoryLease{}
+}
+
+// Acquire reports that the lease is held
+func (l *MemoryLease) Acquire(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+// Release does nothing, as no other process can take the lease
+func (l *MemoryLease) Release(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,90 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+)
+
+// memorySubscriptionBuffer is the number of events a subscription holds
+// before further events are dropped
+const memorySubscriptionBuffer = 16
+
+// MemoryNotifier implements Notifier within a single process
+type MemoryNotifier struct {
+	mu            sync.Mutex
+	subscriptions map[string]map[*memorySubscription]struct{}
+}
+
+// NewMemoryNotifier creates a new in-memory notifier
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{
+		subscriptions: make(map[string]map[*memorySubscription]struct{}),
+	}
+}
+
+// Publish sends a payload to all current subscribers of a channel. Like
+// Redis pub/sub it does not wait for slow subscribers: an event is dropped
+// for a subscriber whose buffer is full.
+func (n *MemoryNotifier) Publish(ctx context.Context, channel string, payload []byte) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for sub := range n.subscriptions[channel] {
+		event := append([]byte(nil), payload...)
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Subscribe subscribes to a channel. Events published after it returns are
+// delivered.
+func (n *MemoryNotifier) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	sub := &memorySubscription{
+		notifier: n,
+		channel:  channel,
+		events:   make(chan []byte, memorySubscriptionBuffer),
+	}
+
+	if n.subscriptions[channel] == nil {
+		n.subscriptions[channel] = make(map[*memorySubscription]struct{})
+	}
+	n.subscriptions[channel][sub] = struct{}{}
+
+	return sub, nil
+}
+
+// memorySubscription is a subscription to a MemoryNotifier channel
+type memorySubscription struct {
+	notifier *MemoryNotifier
+	channel  string
+	events   chan []byte
+	once     sync.Once
+}
+
+// Events returns the channel on which published payloads are delivered
+func (s *memorySubscription) Events() <-chan []byte {
+	return s.events
+}
+
+// Close unsubscribes and closes the events channel
+func (s *memorySubscription) Close() error {
+	s.once.Do(func() {
+		n := s.notifier
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		delete(n.subscriptions[s.channel], s)
+		if len(n.subscriptions[s.channel]) == 0 {
+			delete(n.subscriptions, s.channel)
+		}
+		close(s.events)
+	})
+	return nil
+}
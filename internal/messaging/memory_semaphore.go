@@ -0,0 +1,74 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemorySemaphore implements Semaphore within a single process
+type MemorySemaphore struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	slots map[string]map[string]time.Time // Expiry of each holder's slot, by name
+}
+
+// NewMemorySemaphore creates a semaphore whose slots expire after ttl
+func NewMemorySemaphore(ttl time.Duration) *MemorySemaphore {
+	return &MemorySemaphore{
+		ttl:   ttl,
+		slots: make(map[string]map[string]time.Time),
+	}
+}
+
+// Acquire takes or renews a slot
+func (s *MemorySemaphore) Acquire(ctx context.Context, name, holder string, limit int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	holders := s.held(name, now)
+	if _, ok := holders[holder]; !ok && len(holders) >= limit {
+		return false, nil
+	}
+
+	if holders == nil {
+		holders = make(map[string]time.Time)
+		s.slots[name] = holders
+	}
+	holders[holder] = now.Add(s.ttl)
+	return true, nil
+}
+
+// Release frees a slot
+func (s *MemorySemaphore) Release(ctx context.Context, name, holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.slots[name], holder)
+	if len(s.slots[name]) == 0 {
+		delete(s.slots, name)
+	}
+	return nil
+}
+
+// Count returns the number of unexpired slots
+func (s *MemorySemaphore) Count(ctx context.Context, name string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.held(name, time.Now())), nil
+}
+
+// held drops the expired slots of name and returns the rest. The caller
+// holds mu.
+func (s *MemorySemaphore) held(name string, now time.Time) map[string]time.Time {
+	holders := s.slots[name]
+	for holder, expiresAt := range holders {
+		if !expiresAt.After(now) {
+			delete(holders, holder)
+		}
+	}
+	return holders
+}
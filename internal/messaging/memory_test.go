@@ -0,0 +1,355 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// testQueue is the queue name used by the tests
+const testQueue = "test"
+
+// deliverOne enqueues a message and dequeues it, failing the test if it is
+// not delivered
+func deliverOne(t *testing.T, q *MemoryQueue, opts EnqueueOptions) *Message {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := q.EnqueueWithOptions(ctx, testQueue, []byte(`{}`), map[string]string{"k": "v"}, opts); err != nil {
+		t.Fatalf("EnqueueWithOptions: %v", err)
+	}
+	msg, err := q.Dequeue(ctx, testQueue, time.Second)
+	if err != nil {
+		t.Fatalf("Dequeue: %v", err)
+	}
+	if msg == nil {
+		t.Fatal("Dequeue returned no message")
+	}
+	return msg
+}
+
+// stats returns the stats of the test queue
+func stats(t *testing.T, q *MemoryQueue) *QueueStats {
+	t.Helper()
+	s, err := q.GetStats(context.Background(), testQueue)
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	return s
+}
+
+func TestMemoryQueueSettle(t *testing.T) {
+	tests := []struct {
+		name            string
+		settle          func(context.Context, *MemoryQueue, *Message) error
+		wantSize        int64
+		wantDelayed     int64
+		wantDeadLetters int64
+	}{
+		{
+			name:   "ack removes the message",
+			settle: func(ctx context.Context, q *MemoryQueue, m *Message) error { return q.Ack(ctx, m) },
+		},
+		{
+			name:     "nack queues it again",
+			settle:   func(ctx context.Context, q *MemoryQueue, m *Message) error { return q.Nack(ctx, m) },
+			wantSize: 1,
+		},
+		{
+			name:     "requeue without delay queues it again",
+			settle:   func(ctx context.Context, q *MemoryQueue, m *Message) error { return q.Requeue(ctx, m, 0) },
+			wantSize: 1,
+		},
+		{
+			name:        "requeue with delay holds it back",
+			settle:      func(ctx context.Context, q *MemoryQueue, m *Message) error { return q.Requeue(ctx, m, time.Hour) },
+			wantDelayed: 1,
+		},
+		{
+			name:            "dead letter moves it to the dead letters",
+			settle:          func(ctx context.Context, q *MemoryQueue, m *Message) error { return q.DeadLetter(ctx, m, "failed") },
+			wantDeadLetters: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewMemoryQueue(time.Minute)
+			msg := deliverOne(t, q, EnqueueOptions{})
+
+			if got := stats(t, q).InFlight; got != 1 {
+				t.Fatalf("InFlight after dequeue = %d, want 1", got)
+			}
+			if err := tt.settle(context.Background(), q, msg); err != nil {
+				t.Fatalf("settle: %v", err)
+			}
+
+			s := stats(t, q)
+			if s.InFlight != 0 {
+				t.Errorf("InFlight = %d, want 0", s.InFlight)
+			}
+			if s.Size != tt.wantSize {
+				t.Errorf("Size = %d, want %d", s.Size, tt.wantSize)
+			}
+			if s.Delayed != tt.wantDelayed {
+				t.Errorf("Delayed = %d, want %d", s.Delayed, tt.wantDelayed)
+			}
+			if s.DeadLetters != tt.wantDeadLetters {
+				t.Errorf("DeadLetters = %d, want %d", s.DeadLetters, tt.wantDeadLetters)
+			}
+		})
+	}
+}
+
+func TestMemoryQueueRedeliveryCountsAttempts(t *testing.T) {
+	tests := []struct {
+		name         string
+		lower        bool // Lower Attempts before requeueing, as a deferral does
+		wantAttempts int
+	}{
+		{name: "requeued delivery counts", wantAttempts: 2},
+		{name: "lowered attempts do not count", lower: true, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			q := NewMemoryQueue(time.Minute)
+
+			msg := deliverOne(t, q, EnqueueOptions{})
+			if msg.Attempts != 1 {
+				t.Fatalf("first delivery Attempts = %d, want 1", msg.Attempts)
+			}
+			if tt.lower {
+				msg.Attempts--
+			}
+			if err := q.Requeue(ctx, msg, 0); err != nil {
+				t.Fatalf("Requeue: %v", err)
+			}
+
+			again, err := q.Dequeue(ctx, testQueue, time.Second)
+			if err != nil || again == nil {
+				t.Fatalf("Dequeue = %v, %v; want the requeued message", again, err)
+			}
+			if again.ID != msg.ID {
+				t.Errorf("ID = %s, want %s", again.ID, msg.ID)
+			}
+			if again.Attempts != tt.wantAttempts {
+				t.Errorf("Attempts = %d, want %d", again.Attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestMemoryQueueLeases(t *testing.T) {
+	const visibility = 20 * time.Millisecond
+
+	tests := []struct {
+		name        string
+		extend      bool // Extend the lease shortly before it expires
+		wantClaimed int
+	}{
+		{name: "expired lease is claimed", wantClaimed: 1},
+		{name: "extended lease is not claimed", extend: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			q := NewMemoryQueue(visibility)
+			msg := deliverOne(t, q, EnqueueOptions{})
+
+			if tt.extend {
+				time.Sleep(visibility / 2)
+				if err := q.ExtendLease(ctx, msg); err != nil {
+					t.Fatalf("ExtendLease: %v", err)
+				}
+				time.Sleep(visibility / 2)
+			} else {
+				time.Sleep(2 * visibility)
+			}
+
+			claimed, err := q.ClaimExpired(ctx, testQueue, 10)
+			if err != nil {
+				t.Fatalf("ClaimExpired: %v", err)
+			}
+			if len(claimed) != tt.wantClaimed {
+				t.Fatalf("claimed %d messages, want %d", len(claimed), tt.wantClaimed)
+			}
+			if tt.wantClaimed == 0 {
+				return
+			}
+
+			// The claim keeps the attempt of the lost delivery
+			if claimed[0].Attempts != msg.Attempts {
+				t.Errorf("claimed Attempts = %d, want %d", claimed[0].Attempts, msg.Attempts)
+			}
+
+			// The consumer that lost the lease can no longer renew or settle it
+			if err := q.ExtendLease(ctx, msg); !errors.Is(err, ErrLeaseLost) {
+				t.Errorf("ExtendLease with a claimed lease = %v, want ErrLeaseLost", err)
+			}
+			if err := q.Ack(ctx, msg); err != nil {
+				t.Fatalf("Ack: %v", err)
+			}
+			if got := stats(t, q).InFlight; got != 1 {
+				t.Errorf("InFlight after stale ack = %d, want 1", got)
+			}
+
+			if err := q.Ack(ctx, claimed[0]); err != nil {
+				t.Fatalf("Ack: %v", err)
+			}
+			if got := stats(t, q).InFlight; got != 0 {
+				t.Errorf("InFlight after ack = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestMemoryQueueDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(time.Minute)
+
+	first := deliverOne(t, q, EnqueueOptions{MessageID: "first"})
+	second := deliverOne(t, q, EnqueueOptions{MessageID: "second"})
+	for _, msg := range []*Message{first, second} {
+		if err := q.DeadLetter(ctx, msg, "boom"); err != nil {
+			t.Fatalf("DeadLetter: %v", err)
+		}
+	}
+
+	deadLetters, err := q.ListDeadLetters(ctx, testQueue)
+	if err != nil {
+		t.Fatalf("ListDeadLetters: %v", err)
+	}
+	if len(deadLetters) != 2 || deadLetters[0].ID != "second" || deadLetters[1].ID != "first" {
+		t.Fatalf("dead letters = %v, want second then first", deadLetters)
+	}
+	if got := deadLetters[0].Headers["dead_letter_reason"]; got != "boom" {
+		t.Errorf("dead_letter_reason = %q, want %q", got, "boom")
+	}
+	if got := deadLetters[0].Headers["k"]; got != "v" {
+		t.Errorf("header k = %q, want the original headers kept", got)
+	}
+
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{id: "first", want: true},
+		{id: "first", want: false},
+		{id: "missing", want: false},
+	}
+	for _, tt := range tests {
+		removed, err := q.RemoveDeadLetter(ctx, testQueue, tt.id)
+		if err != nil {
+			t.Fatalf("RemoveDeadLetter(%s): %v", tt.id, err)
+		}
+		if removed != tt.want {
+			t.Errorf("RemoveDeadLetter(%s) = %v, want %v", tt.id, removed, tt.want)
+		}
+	}
+
+	if got := stats(t, q).DeadLetters; got != 1 {
+		t.Errorf("DeadLetters = %d, want 1", got)
+	}
+}
+
+func TestMemoryQueueDelayedAndRemove(t *testing.T) {
+	tests := []struct {
+		name        string
+		runAt       time.Duration
+		wantRemoved bool
+		wantDelayed int64
+		wantSize    int64
+	}{
+		{name: "queued message", wantRemoved: true},
+		{name: "delayed message", runAt: time.Hour, wantRemoved: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			q := NewMemoryQueue(time.Minute)
+
+			opts := EnqueueOptions{MessageID: "m"}
+			if tt.runAt > 0 {
+				opts.RunAt = time.Now().Add(tt.runAt)
+			}
+			if err := q.EnqueueWithOptions(ctx, testQueue, []byte(`{}`), nil, opts); err != nil {
+				t.Fatalf("EnqueueWithOptions: %v", err)
+			}
+
+			if tt.runAt > 0 {
+				msg, err := q.Dequeue(ctx, testQueue, 10*time.Millisecond)
+				if err != nil || msg != nil {
+					t.Fatalf("Dequeue = %v, %v; want nothing before run_at", msg, err)
+				}
+			}
+
+			removed, err := q.Remove(ctx, testQueue, "m")
+			if err != nil {
+				t.Fatalf("Remove: %v", err)
+			}
+			if removed != tt.wantRemoved {
+				t.Errorf("Remove = %v, want %v", removed, tt.wantRemoved)
+			}
+
+			s := stats(t, q)
+			if s.Size != tt.wantSize || s.Delayed != tt.wantDelayed {
+				t.Errorf("Size, Delayed = %d, %d; want %d, %d", s.Size, s.Delayed, tt.wantSize, tt.wantDelayed)
+			}
+		})
+	}
+}
+
+func TestMemoryQueueDelayedDelivery(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue(time.Minute)
+
+	runAt := time.Now().Add(30 * time.Millisecond)
+	if err := q.EnqueueWithOptions(ctx, testQueue, []byte(`{}`), nil, EnqueueOptions{RunAt: runAt}); err != nil {
+		t.Fatalf("EnqueueWithOptions: %v", err)
+	}
+
+	msg, err := q.Dequeue(ctx, testQueue, time.Second)
+	if err != nil || msg == nil {
+		t.Fatalf("Dequeue = %v, %v; want the delayed message", msg, err)
+	}
+	if time.Now().Before(runAt) {
+		t.Errorf("delivered before run_at")
+	}
+}
+
+func TestMemoryQueueDequeueFirst(t *testing.T) {
+	tests := []struct {
+		name    string
+		enqueue []string
+		order   []string
+		want    string
+	}{
+		{name: "first queue wins", enqueue: []string{"low", "high"}, order: []string{"high", "low"}, want: "high"},
+		{name: "falls through to a later queue", enqueue: []string{"low"}, order: []string{"high", "low"}, want: "low"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			q := NewMemoryQueue(time.Minute)
+			for _, queue := range tt.enqueue {
+				if err := q.Enqueue(ctx, queue, []byte(`{}`), nil); err != nil {
+					t.Fatalf("Enqueue: %v", err)
+				}
+			}
+
+			msg, err := q.DequeueFirst(ctx, tt.order, time.Second)
+			if err != nil || msg == nil {
+				t.Fatalf("DequeueFirst = %v, %v; want a message", msg, err)
+			}
+			if msg.Queue != tt.want {
+				t.Errorf("delivered from %s, want %s", msg.Queue, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,708 @@
+package messaging
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// postgresChannel is the LISTEN/NOTIFY channel on which the name of a queue
+// is published when a message becomes ready on it
+const postgresChannel = "faas_queue"
+
+// States of a row in queue_messages
+const (
+	stateReady     = "ready"
+	stateDelivered = "delivered"
+	stateDead      = "dead"
+)
+
+// minRecheckInterval bounds how often Dequeue looks for messages when a
+// ready message is locked by another consumer that is about to take it
+const minRecheckInterval = 100 * time.Millisecond
+
+const queueMessageColumns = `seq, message_id, queue, payload, headers, attempts, enqueued_at`
+
+// PostgresQueue implements Queue using the queue_messages table. Consumers
+// claim messages with SELECT ... FOR UPDATE SKIP LOCKED, so concurrent
+// dequeues never wait for each other or take the same message. Enqueues
+// NOTIFY the queue's name, which wakes blocked dequeues without polling.
+//
+// A delivered message's lease is its visible_at time. A delivery is
+// identified by the message's row and delivery count, so a consumer whose
+// message was claimed by another can no longer ack or extend it.
+type PostgresQueue struct {
+	db                *sql.DB
+	listener          *pq.Listener
+	consumer          string
+	visibilityTimeout time.Duration
+
+	// Channels closed on the next notification for a queue
+	wakeupsMu sync.Mutex
+	wakeups   map[string]chan struct{}
+}
+
+// NewPostgresQueue creates a new PostgreSQL queue. dsn opens the dedicated
+// connection that listens for notifications; consumer names this process
+// in the pending messages it holds.
+func NewPostgresQueue(db *sql.DB, dsn, consumer string, visibilityTimeout time.Duration) (*PostgresQueue, error) {
+	if visibilityTimeout <= 0 {
+		visibilityTimeout = defaultVisibilityTimeout
+	}
+
+	listener := pq.NewListener(dsn, time.Second, time.Minute, nil)
+	if err := listener.Listen(postgresChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to listen for queue notifications: %w", err)
+	}
+
+	q := &PostgresQueue{
+		db:                db,
+		listener:          listener,
+		consumer:          consumer,
+		visibilityTimeout: visibilityTimeout,
+		wakeups:           make(map[string]chan struct{}),
+	}
+
+	go q.dispatchNotifications()
+
+	return q, nil
+}
+
+// Close stops listening for notifications
+func (q *PostgresQueue) Close() error {
+	return q.listener.Close()
+}
+
+// Enqueue adds a message to the queue
+func (q *PostgresQueue) Enqueue(ctx context.Context, queue string, payload []byte, headers map[string]string) error {
+	return q.EnqueueWithOptions(ctx, queue, payload, headers, EnqueueOptions{})
+}
+
+// EnqueueWithOptions adds a message to the queue, holding it back until
+// opts.RunAt if that is in the future
+func (q *PostgresQueue) EnqueueWithOptions(ctx context.Context, queue string, payload []byte, headers map[string]string, opts EnqueueOptions) error {
+	if opts.MessageID == "" {
+		opts.MessageID = uuid.New().String()
+	}
+
+	now := time.Now()
+	visibleAt := now
+	if opts.RunAt.After(now) {
+		visibleAt = opts.RunAt
+	}
+
+	err := q.insert(ctx, &Message{
+		ID:         opts.MessageID,
+		Queue:      queue,
+		Payload:    payload,
+		Headers:    headers,
+		EnqueuedAt: now,
+	}, stateReady, visibleAt)
+	if err != nil {
+		return err
+	}
+
+	q.countEvent(ctx, queue, eventEnqueued, 1)
+	return nil
+}
+
+// Remove deletes a ready message. Dequeue locks the row it claims, and the
+// delete rechecks the state once the lock is released, so a message is
+// either removed or delivered, never both.
+func (q *PostgresQueue) Remove(ctx context.Context, queue, messageID string) (bool, error) {
+	result, err := q.db.ExecContext(ctx,
+		`DELETE FROM queue_messages WHERE queue = $1 AND message_id = $2 AND state = $3`,
+		queue, messageID, stateReady,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove queued message: %w", err)
+	}
+
+	removed, _ := result.RowsAffected()
+	return removed > 0, nil
+}
+
+// Dequeue claims the next visible message of the queue, waiting up to
+// timeout (forever if zero) for one to be enqueued or become due
+func (q *PostgresQueue) Dequeue(ctx context.Context, queue string, timeout time.Duration) (*Message, error) {
+	return q.DequeueFirst(ctx, []string{queue}, timeout)
+}
+
+// DequeueFirst claims the next visible message of the first of the queues
+// that has one, waiting up to timeout (forever if zero) for a message to be
+// enqueued or become due on any of them
+func (q *PostgresQueue) DequeueFirst(ctx context.Context, queues []string, timeout time.Duration) (*Message, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	for {
+		// Register before looking, so a notification sent in between is
+		// not missed
+		wakeup, stopWaiting := q.wakeupAny(queues)
+
+		message, err := q.claimFirst(ctx, queues)
+		if err != nil || message != nil {
+			stopWaiting()
+			return message, err
+		}
+
+		var wait time.Duration
+		for _, queue := range queues {
+			next, err := q.untilVisible(ctx, queue)
+			if err != nil {
+				stopWaiting()
+				return nil, err
+			}
+			if next > 0 && (wait == 0 || next < wait) {
+				wait = next
+			}
+		}
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				stopWaiting()
+				return nil, nil // No message available
+			}
+			if wait == 0 || remaining < wait {
+				wait = remaining
+			}
+		}
+
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			expired = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-wakeup:
+		case <-expired:
+		}
+
+		stopWaiting()
+		if timer != nil {
+			timer.Stop()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// claimFirst claims the next visible message of the first of the queues
+// that has one, counting the dequeue
+func (q *PostgresQueue) claimFirst(ctx context.Context, queues []string) (*Message, error) {
+	for _, queue := range queues {
+		message, err := q.claimNext(ctx, queue)
+		if err != nil {
+			return nil, err
+		}
+		if message != nil {
+			q.countEvent(ctx, queue, eventDequeued, 1)
+			return message, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ExtendLease pushes back the lease of a delivered message
+func (q *PostgresQueue) ExtendLease(ctx context.Context, message *Message) error {
+	seq, deliveries, ok := parseReceipt(message.receipt)
+	if !ok {
+		return ErrLeaseLost
+	}
+
+	result, err := q.db.ExecContext(ctx,
+		`UPDATE queue_messages SET visible_at = $1
+		 WHERE seq = $2 AND attempts = $3 AND state = $4`,
+		time.Now().Add(q.visibilityTimeout), seq, deliveries, stateDelivered,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to extend lease: %w", err)
+	}
+
+	if extended, _ := result.RowsAffected(); extended == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
+
+// ClaimExpired delivers messages whose lease expired to this consumer
+func (q *PostgresQueue) ClaimExpired(ctx context.Context, queue string, limit int) ([]*Message, error) {
+	now := time.Now()
+	rows, err := q.db.QueryContext(ctx, `
+		UPDATE queue_messages SET attempts = attempts + 1, consumer = $1, visible_at = $2
+		WHERE seq IN (
+			SELECT seq FROM queue_messages
+			WHERE queue = $3 AND state = $4 AND visible_at <= $5
+			ORDER BY visible_at
+			LIMIT $6
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+queueMessageColumns,
+		q.consumer, now.Add(q.visibilityTimeout), queue, stateDelivered, now, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim expired messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]*Message, 0)
+	for rows.Next() {
+		message, err := scanQueueMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		// The receipt keeps the new delivery count; like the other queues,
+		// report the attempt of the delivery whose lease expired
+		message.Attempts--
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(messages) > 0 {
+		q.countEvent(ctx, queue, eventDequeued, len(messages))
+	}
+
+	return messages, nil
+}
+
+// ListPending returns delivered messages by lease expiry
+func (q *PostgresQueue) ListPending(ctx context.Context, queue string, limit int) ([]*PendingMessage, error) {
+	rows, err := q.db.QueryContext(ctx, `
+		SELECT message_id, consumer, attempts, visible_at FROM queue_messages
+		WHERE queue = $1 AND state = $2
+		ORDER BY visible_at
+		LIMIT $3`,
+		queue, stateDelivered, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending messages: %w", err)
+	}
+	defer rows.Close()
+
+	pending := make([]*PendingMessage, 0)
+	for rows.Next() {
+		var p PendingMessage
+		var consumer sql.NullString
+		if err := rows.Scan(&p.ID, &consumer, &p.Attempts, &p.LeaseExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan pending message: %w", err)
+		}
+		p.Consumer = consumer.String
+		pending = append(pending, &p)
+	}
+
+	return pending, rows.Err()
+}
+
+// Ack deletes a delivered message
+func (q *PostgresQueue) Ack(ctx context.Context, message *Message) error {
+	seq, deliveries, ok := parseReceipt(message.receipt)
+	if !ok {
+		return nil
+	}
+
+	_, err := q.db.ExecContext(ctx,
+		`DELETE FROM queue_messages WHERE seq = $1 AND attempts = $2 AND state = $3`,
+		seq, deliveries, stateDelivered,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to acknowledge message: %w", err)
+	}
+
+	return nil
+}
+
+// Nack makes a delivered message ready again immediately
+func (q *PostgresQueue) Nack(ctx context.Context, message *Message) error {
+	return q.Requeue(ctx, message, 0)
+}
+
+// Requeue makes a delivered message ready again after delay. The row keeps
+// its delivery count, which already includes this delivery.
+func (q *PostgresQueue) Requeue(ctx context.Context, message *Message, delay time.Duration) error {
+	visibleAt := time.Now().Add(delay)
+
+	seq, deliveries, ok := parseReceipt(message.receipt)
+	if !ok {
+		return q.insert(ctx, message, stateReady, visibleAt)
+	}
+
+	_, err := q.db.ExecContext(ctx, `
+		WITH requeued AS (
+			UPDATE queue_messages SET state = $1, consumer = NULL, visible_at = $2, attempts = $3
+			WHERE seq = $4 AND attempts = $5 AND state = $6
+			RETURNING queue
+		)
+		SELECT pg_notify($7, queue) FROM requeued`,
+		stateReady, visibleAt, message.Attempts, seq, deliveries, stateDelivered, postgresChannel,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to requeue message: %w", err)
+	}
+
+	return nil
+}
+
+// DeadLetter moves a message to the dead letters, recording the reason in
+// its headers
+func (q *PostgresQueue) DeadLetter(ctx context.Context, message *Message, reason string) error {
+	if message.Headers == nil {
+		message.Headers = make(map[string]string)
+	}
+	message.Headers["dead_letter_reason"] = reason
+	message.Headers["dead_lettered_at"] = time.Now().Format(time.RFC3339)
+
+	seq, deliveries, ok := parseReceipt(message.receipt)
+	if !ok {
+		return q.insert(ctx, message, stateDead, time.Now())
+	}
+
+	headers, err := json.Marshal(message.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal headers: %w", err)
+	}
+
+	_, err = q.db.ExecContext(ctx, `
+		UPDATE queue_messages SET state = $1, consumer = NULL, headers = $2, dead_lettered_at = $3
+		WHERE seq = $4 AND attempts = $5 AND state = $6`,
+		stateDead, headers, time.Now(), seq, deliveries, stateDelivered,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to dead-letter message: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeadLetters returns the dead letters of a queue, most recent first
+func (q *PostgresQueue) ListDeadLetters(ctx context.Context, queue string) ([]*Message, error) {
+	rows, err := q.db.QueryContext(ctx,
+		`SELECT `+queueMessageColumns+` FROM queue_messages
+		 WHERE queue = $1 AND state = $2
+		 ORDER BY dead_lettered_at DESC, seq DESC`,
+		queue, stateDead,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]*Message, 0)
+	for rows.Next() {
+		message, err := scanQueueMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		// Dead letters are not deliveries
+		message.receipt = ""
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+// RemoveDeadLetter deletes a dead letter
+func (q *PostgresQueue) RemoveDeadLetter(ctx context.Context, queue, messageID string) (bool, error) {
+	result, err := q.db.ExecContext(ctx,
+		`DELETE FROM queue_messages WHERE queue = $1 AND message_id = $2 AND state = $3`,
+		queue, messageID, stateDead,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove dead letter: %w", err)
+	}
+
+	removed, _ := result.RowsAffected()
+	return removed > 0, nil
+}
+
+// Heartbeat records that consumer is active on the queue. Heartbeats
+// arrive steadily, so they also prune consumers that timed out and counters
+// that left the rate window.
+func (q *PostgresQueue) Heartbeat(ctx context.Context, queue, consumer string) error {
+	now := time.Now()
+	_, err := q.db.ExecContext(ctx, `
+		INSERT INTO queue_consumers (queue, consumer, last_seen_at) VALUES ($1, $2, $3)
+		ON CONFLICT (queue, consumer) DO UPDATE SET last_seen_at = EXCLUDED.last_seen_at`,
+		queue, consumer, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+
+	_, err = q.db.ExecContext(ctx,
+		`DELETE FROM queue_consumers WHERE queue = $1 AND last_seen_at < $2`,
+		queue, now.Add(-ConsumerTimeout),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to prune consumers: %w", err)
+	}
+
+	_, err = q.db.ExecContext(ctx,
+		`DELETE FROM queue_counters WHERE queue = $1 AND bucket < $2`,
+		queue, rateBuckets(now)[0],
+	)
+	if err != nil {
+		return fmt.Errorf("failed to prune counters: %w", err)
+	}
+
+	return nil
+}
+
+// GetStats returns queue statistics. Size counts messages ready for
+// delivery, and OldestMessageAge is measured from when the oldest of them
+// became visible.
+func (q *PostgresQueue) GetStats(ctx context.Context, queue string) (*QueueStats, error) {
+	now := time.Now()
+	stats := &QueueStats{Name: queue}
+
+	var oldest sql.NullTime
+	err := q.db.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE state = $2 AND visible_at <= $3),
+			COUNT(*) FILTER (WHERE state = $2 AND visible_at > $3),
+			COUNT(*) FILTER (WHERE state = $4),
+			COUNT(*) FILTER (WHERE state = $5),
+			MIN(visible_at) FILTER (WHERE state = $2 AND visible_at <= $3)
+		FROM queue_messages WHERE queue = $1`,
+		queue, stateReady, now, stateDelivered, stateDead,
+	).Scan(&stats.Size, &stats.Delayed, &stats.InFlight, &stats.DeadLetters, &oldest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue size: %w", err)
+	}
+
+	if oldest.Valid {
+		stats.OldestMessageAge = now.Sub(oldest.Time)
+	}
+
+	var enqueued, dequeued int64
+	err = q.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM queue_consumers WHERE queue = $1 AND last_seen_at >= $2),
+			COALESCE(SUM(enqueued), 0),
+			COALESCE(SUM(dequeued), 0)
+		FROM queue_counters WHERE queue = $1 AND bucket >= $3`,
+		queue, now.Add(-ConsumerTimeout), rateBuckets(now)[0],
+	).Scan(&stats.Consumers, &enqueued, &dequeued)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queue activity: %w", err)
+	}
+
+	stats.EnqueueRate = ratePerSecond(enqueued, now)
+	stats.DequeueRate = ratePerSecond(dequeued, now)
+
+	return stats, nil
+}
+
+// claimNext delivers the next visible ready message to this consumer, or
+// returns nil if there is none
+func (q *PostgresQueue) claimNext(ctx context.Context, queue string) (*Message, error) {
+	now := time.Now()
+	row := q.db.QueryRowContext(ctx, `
+		UPDATE queue_messages SET state = $1, attempts = attempts + 1, consumer = $2, visible_at = $3
+		WHERE seq = (
+			SELECT seq FROM queue_messages
+			WHERE queue = $4 AND state = $5 AND visible_at <= $6
+			ORDER BY visible_at, seq
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+queueMessageColumns,
+		stateDelivered, q.consumer, now.Add(q.visibilityTimeout), queue, stateReady, now,
+	)
+
+	message, err := scanQueueMessage(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to dequeue message: %w", err)
+	}
+
+	return message, nil
+}
+
+// untilVisible returns how long until the next ready message of the queue
+// becomes visible, or zero if there is none
+func (q *PostgresQueue) untilVisible(ctx context.Context, queue string) (time.Duration, error) {
+	var next sql.NullTime
+	err := q.db.QueryRowContext(ctx,
+		`SELECT MIN(visible_at) FROM queue_messages WHERE queue = $1 AND state = $2`,
+		queue, stateReady,
+	).Scan(&next)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find next visible message: %w", err)
+	}
+
+	if !next.Valid {
+		return 0, nil
+	}
+
+	wait := time.Until(next.Time)
+	if wait < minRecheckInterval {
+		// Visible but locked by a consumer about to take it
+		wait = minRecheckInterval
+	}
+
+	return wait, nil
+}
+
+// insert adds a message in the given state, notifying if it is ready
+func (q *PostgresQueue) insert(ctx context.Context, message *Message, state string, visibleAt time.Time) error {
+	headers, err := json.Marshal(message.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal headers: %w", err)
+	}
+
+	var deadLetteredAt *time.Time
+	if state == stateDead {
+		deadLetteredAt = &visibleAt
+	}
+
+	_, err = q.db.ExecContext(ctx, `
+		WITH inserted AS (
+			INSERT INTO queue_messages (
+				queue, message_id, payload, headers, attempts, state, enqueued_at, visible_at, dead_lettered_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING queue, state
+		)
+		SELECT pg_notify($10, queue) FROM inserted WHERE state = $11`,
+		message.Queue, message.ID, message.Payload, headers, message.Attempts, state,
+		message.EnqueuedAt, visibleAt, deadLetteredAt, postgresChannel, stateReady,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue message: %w", err)
+	}
+
+	return nil
+}
+
+// countEvent adds n to the current rate bucket of an event, which names
+// the column counting it. The messages were already enqueued or delivered,
+// so a failed count only skews the rate.
+func (q *PostgresQueue) countEvent(ctx context.Context, queue, event string, n int) {
+	q.db.ExecContext(ctx, `
+		INSERT INTO queue_counters (queue, bucket, `+event+`) VALUES ($1, $2, $3)
+		ON CONFLICT (queue, bucket) DO UPDATE SET `+event+` = queue_counters.`+event+` + $3`,
+		queue, time.Now().Truncate(rateBucket), n,
+	)
+}
+
+// wakeup returns a channel that is closed when a message next becomes
+// ready on the queue
+func (q *PostgresQueue) wakeup(queue string) <-chan struct{} {
+	q.wakeupsMu.Lock()
+	defer q.wakeupsMu.Unlock()
+
+	ch, ok := q.wakeups[queue]
+	if !ok {
+		ch = make(chan struct{})
+		q.wakeups[queue] = ch
+	}
+	return ch
+}
+
+// wakeupAny returns a channel that is closed when a message next becomes
+// ready on any of the queues, and a function to call once the channel is
+// no longer watched
+func (q *PostgresQueue) wakeupAny(queues []string) (<-chan struct{}, func()) {
+	if len(queues) == 1 {
+		return q.wakeup(queues[0]), func() {}
+	}
+
+	woken := make(chan struct{})
+	done := make(chan struct{})
+	var once sync.Once
+	for _, queue := range queues {
+		go func(wakeup <-chan struct{}) {
+			select {
+			case <-wakeup:
+				once.Do(func() { close(woken) })
+			case <-done:
+			}
+		}(q.wakeup(queue))
+	}
+
+	return woken, func() { close(done) }
+}
+
+// dispatchNotifications wakes the dequeues waiting on the queues named in
+// notifications, until the listener is closed. After the listener
+// reconnects every waiting dequeue is woken, since notifications may have
+// been missed.
+func (q *PostgresQueue) dispatchNotifications() {
+	for n := range q.listener.Notify {
+		q.wakeupsMu.Lock()
+		for queue, ch := range q.wakeups {
+			if n == nil || n.Extra == queue {
+				close(ch)
+				delete(q.wakeups, queue)
+			}
+		}
+		q.wakeupsMu.Unlock()
+	}
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanQueueMessage scans a row selected with queueMessageColumns. The
+// receipt identifies the delivery by the row and its delivery count.
+func scanQueueMessage(row rowScanner) (*Message, error) {
+	var message Message
+	var seq int64
+	var headers []byte
+
+	err := row.Scan(&seq, &message.ID, &message.Queue, &message.Payload, &headers, &message.Attempts, &message.EnqueuedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(headers) > 0 {
+		if err := json.Unmarshal(headers, &message.Headers); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal headers: %w", err)
+		}
+	}
+
+	message.receipt = fmt.Sprintf("%d:%d", seq, message.Attempts)
+	return &message, nil
+}
+
+// parseReceipt returns the row and delivery count identifying a delivery
+func parseReceipt(receipt string) (int64, int, bool) {
+	seqPart, deliveriesPart, found := strings.Cut(receipt, ":")
+	if !found {
+		return 0, 0, false
+	}
+
+	seq, err := strconv.ParseInt(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	deliveries, err := strconv.Atoi(deliveriesPart)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return seq, deliveries, true
+}
@@ -2,6 +2,8 @@ package messaging
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -10,24 +12,121 @@ import (
 	"github.com/google/uuid"
 )
 
-// RedisQueue implements Queue using Redis
+// promoteBatchSize bounds the delayed messages moved to a queue per dequeue
+const promoteBatchSize = 100
+
+// promoteDelayedScript moves due delayed messages onto the queue list and
+// returns the delivery time (unix ms) of the next delayed message, or -1
+var promoteDelayedScript = redis.NewScript(`
+local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+for _, id in ipairs(due) do
+	local data = redis.call("HGET", KEYS[2], id)
+	redis.call("ZREM", KEYS[1], id)
+	redis.call("HDEL", KEYS[2], id)
+	if data then
+		redis.call("LPUSH", KEYS[3], data)
+	end
+end
+local upcoming = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+if #upcoming == 0 then
+	return -1
+end
+return tonumber(upcoming[2])`)
+
+// removeQueuedScript deletes the message with the given ID from a queue or
+// dead letter list
+var removeQueuedScript = redis.NewScript(`
+local items = redis.call("LRANGE", KEYS[1], 0, -1)
+for _, item in ipairs(items) do
+	local ok, message = pcall(cjson.decode, item)
+	if ok and message.id == ARGV[1] then
+		return redis.call("LREM", KEYS[1], 1, item)
+	end
+end
+return 0`)
+
+// extendLeaseScript pushes back the deadline of a lease that still exists
+var extendLeaseScript = redis.NewScript(`
+if redis.call("ZSCORE", KEYS[1], ARGV[1]) then
+	redis.call("ZADD", KEYS[1], ARGV[2], ARGV[1])
+	return 1
+end
+return 0`)
+
+// claimExpiredScript first gives a lease to processing messages that have
+// none, which happens when a consumer stops between moving a message to the
+// processing list and taking its lease. It then renews up to ARGV[3]
+// expired leases on behalf of the caller and returns their messages.
+var claimExpiredScript = redis.NewScript(`
+local items = redis.call("LRANGE", KEYS[1], 0, -1)
+for _, item in ipairs(items) do
+	local token = redis.sha1hex(item)
+	if redis.call("HSETNX", KEYS[3], token, item) == 1 then
+		redis.call("ZADD", KEYS[2], "NX", ARGV[2], token)
+	end
+end
+local expired = redis.call("ZRANGEBYSCORE", KEYS[2], "-inf", ARGV[1], "LIMIT", 0, ARGV[3])
+local claimed = {}
+for _, token in ipairs(expired) do
+	local item = redis.call("HGET", KEYS[3], token)
+	if item then
+		redis.call("ZADD", KEYS[2], ARGV[2], token)
+		table.insert(claimed, item)
+	else
+		redis.call("ZREM", KEYS[2], token)
+	end
+end
+return claimed`)
+
+// defaultVisibilityTimeout applies when NewRedisQueue is given none
+const defaultVisibilityTimeout = 30 * time.Second
+
+// laneRecheckInterval bounds how long a dequeue from several queues blocks
+// on the first of them before looking at the others again
+const laneRecheckInterval = time.Second
+
+// RedisQueue implements Queue using Redis. Delayed messages are kept in a
+// sorted set scored by delivery time, with their bodies in a hash, and are
+// moved onto the queue list by consumers as they become due.
+//
+// A delivered message stays in a processing list until it is acknowledged.
+// Its lease is an entry in a sorted set scored by expiry, keyed by the
+// SHA-1 of the message as stored in the processing list, with the stored
+// message in a hash under the same key.
 type RedisQueue struct {
-	client *redis.Client
-	prefix string
+	client            *redis.Client
+	prefix            string
+	visibilityTimeout time.Duration
 }
 
-// NewRedisQueue creates a new Redis queue
-func NewRedisQueue(client *redis.Client, prefix string) *RedisQueue {
+// NewRedisQueue creates a new Redis queue. Delivered messages whose lease
+// is not renewed within visibilityTimeout can be claimed by ClaimExpired.
+func NewRedisQueue(client *redis.Client, prefix string, visibilityTimeout time.Duration) *RedisQueue {
+	if visibilityTimeout <= 0 {
+		visibilityTimeout = defaultVisibilityTimeout
+	}
+
 	return &RedisQueue{
-		client: client,
-		prefix: prefix,
+		client:            client,
+		prefix:            prefix,
+		visibilityTimeout: visibilityTimeout,
 	}
 }
 
 // Enqueue adds a message to the queue
 func (q *RedisQueue) Enqueue(ctx context.Context, queue string, payload []byte, headers map[string]string) error {
+	return q.EnqueueWithOptions(ctx, queue, payload, headers, EnqueueOptions{})
+}
+
+// EnqueueWithOptions adds a message to the queue, holding it back until
+// opts.RunAt if that is in the future
+func (q *RedisQueue) EnqueueWithOptions(ctx context.Context, queue string, payload []byte, headers map[string]string, opts EnqueueOptions) error {
+	if opts.MessageID == "" {
+		opts.MessageID = uuid.New().String()
+	}
+
 	message := Message{
-		ID:         uuid.New().String(),
+		ID:         opts.MessageID,
 		Queue:      queue,
 		Payload:    payload,
 		Headers:    headers,
@@ -40,17 +139,88 @@ func (q *RedisQueue) Enqueue(ctx context.Context, queue string, payload []byte,
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	queueKey := q.queueKey(queue)
-	return q.client.LPush(ctx, queueKey, data).Err()
+	pipe := q.client.TxPipeline()
+	if opts.RunAt.After(time.Now()) {
+		pipe.HSet(ctx, q.delayedMessagesKey(queue), message.ID, data)
+		pipe.ZAdd(ctx, q.delayedKey(queue), &redis.Z{
+			Score:  float64(opts.RunAt.UnixMilli()),
+			Member: message.ID,
+		})
+	} else {
+		pipe.LPush(ctx, q.queueKey(queue), data)
+	}
+	countEvent(ctx, pipe, q.statsKey(queue), eventEnqueued)
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Remove deletes a message before it is delivered. Removal and promotion
+// both claim a delayed message by removing it from the sorted set, and a
+// queued message is removed from the list atomically, so a message is
+// either removed or delivered, never both. Finding a queued message scans
+// the list.
+func (q *RedisQueue) Remove(ctx context.Context, queue, messageID string) (bool, error) {
+	removed, err := q.client.ZRem(ctx, q.delayedKey(queue), messageID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove delayed message: %w", err)
+	}
+	if removed > 0 {
+		if err := q.client.HDel(ctx, q.delayedMessagesKey(queue), messageID).Err(); err != nil {
+			return true, fmt.Errorf("failed to delete delayed message body: %w", err)
+		}
+		return true, nil
+	}
+
+	removed, err = removeQueuedScript.Run(ctx, q.client, []string{q.queueKey(queue)}, messageID).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove queued message: %w", err)
+	}
+
+	return removed > 0, nil
 }
 
 // Dequeue removes and returns a message from the queue
 func (q *RedisQueue) Dequeue(ctx context.Context, queue string, timeout time.Duration) (*Message, error) {
-	queueKey := q.queueKey(queue)
-	processingKey := q.processingKey(queue)
+	return q.DequeueFirst(ctx, []string{queue}, timeout)
+}
+
+// DequeueFirst removes and returns a message from the first of the queues
+// that has one. Redis can only block on one list while moving a message to
+// the processing list, so with several queues it blocks on the first for
+// at most laneRecheckInterval.
+func (q *RedisQueue) DequeueFirst(ctx context.Context, queues []string, timeout time.Duration) (*Message, error) {
+	// Deliver delayed messages that are due, and stop blocking in time for
+	// the next one
+	wait := timeout
+	for _, queue := range queues {
+		next, err := q.promoteDelayed(ctx, queue, wait)
+		if err != nil {
+			return nil, err
+		}
+		wait = next
+	}
+
+	if len(queues) > 1 {
+		for _, queue := range queues {
+			result, err := q.client.RPopLPush(ctx, q.queueKey(queue), q.processingKey(queue)).Result()
+			if err == redis.Nil {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to dequeue message: %w", err)
+			}
+			return q.lease(ctx, queue, result)
+		}
+
+		if wait <= 0 || wait > laneRecheckInterval {
+			wait = laneRecheckInterval
+		}
+	}
 
 	// Use BRPOPLPUSH for reliable message processing
-	result, err := q.client.BRPopLPush(ctx, queueKey, processingKey, timeout).Result()
+	queue := queues[0]
+	result, err := q.client.BRPopLPush(ctx, q.queueKey(queue), q.processingKey(queue), wait).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return nil, nil // No message available
@@ -58,42 +228,169 @@ func (q *RedisQueue) Dequeue(ctx context.Context, queue string, timeout time.Dur
 		return nil, fmt.Errorf("failed to dequeue message: %w", err)
 	}
 
-	var message Message
-	if err := json.Unmarshal([]byte(result), &message); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+	return q.lease(ctx, queue, result)
+}
+
+// lease takes the lease of a message just moved to the processing list.
+// If this fails the message has no lease until ClaimExpired gives it one,
+// so it is still recovered.
+func (q *RedisQueue) lease(ctx context.Context, queue, raw string) (*Message, error) {
+	token := leaseToken(raw)
+	pipe := q.client.TxPipeline()
+	pipe.ZAdd(ctx, q.leasesKey(queue), &redis.Z{
+		Score:  float64(time.Now().Add(q.visibilityTimeout).UnixMilli()),
+		Member: token,
+	})
+	pipe.HSet(ctx, q.inflightKey(queue), token, raw)
+	countEvent(ctx, pipe, q.statsKey(queue), eventDequeued)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to lease message: %w", err)
 	}
 
-	message.Attempts++
-	return &message, nil
+	return q.delivered(raw)
+}
+
+// ExtendLease renews the lease of a delivered message for another
+// visibility timeout
+func (q *RedisQueue) ExtendLease(ctx context.Context, message *Message) error {
+	deadline := time.Now().Add(q.visibilityTimeout).UnixMilli()
+	extended, err := extendLeaseScript.Run(ctx, q.client,
+		[]string{q.leasesKey(message.Queue)},
+		leaseToken(message.receipt), deadline,
+	).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to extend lease: %w", err)
+	}
+	if extended == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
+
+// ClaimExpired takes over delivered messages whose lease expired
+func (q *RedisQueue) ClaimExpired(ctx context.Context, queue string, limit int) ([]*Message, error) {
+	now := time.Now()
+	items, err := claimExpiredScript.Run(ctx, q.client,
+		[]string{q.processingKey(queue), q.leasesKey(queue), q.inflightKey(queue)},
+		now.UnixMilli(), now.Add(q.visibilityTimeout).UnixMilli(), limit,
+	).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim expired messages: %w", err)
+	}
+
+	messages := make([]*Message, 0, len(items))
+	for _, item := range items {
+		message, err := q.delivered(item)
+		if err != nil {
+			// Unreadable; drop it rather than claim it forever
+			pipe := q.client.TxPipeline()
+			q.release(ctx, pipe, &Message{Queue: queue, receipt: item})
+			pipe.Exec(ctx)
+			continue
+		}
+		messages = append(messages, message)
+	}
+	countClaimed(ctx, q.client, q.statsKey(queue), len(messages))
+
+	return messages, nil
+}
+
+// ListPending returns delivered messages by lease expiry. A message taken
+// by a consumer that stopped before leasing it is listed once
+// ClaimExpired has given it a lease.
+func (q *RedisQueue) ListPending(ctx context.Context, queue string, limit int) ([]*PendingMessage, error) {
+	leases, err := q.client.ZRangeWithScores(ctx, q.leasesKey(queue), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list leases: %w", err)
+	}
+	if len(leases) == 0 {
+		return []*PendingMessage{}, nil
+	}
+
+	tokens := make([]string, len(leases))
+	for i, lease := range leases {
+		tokens[i] = lease.Member.(string)
+	}
+
+	items, err := q.client.HMGet(ctx, q.inflightKey(queue), tokens...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending messages: %w", err)
+	}
+
+	pending := make([]*PendingMessage, 0, len(leases))
+	for i, item := range items {
+		raw, ok := item.(string)
+		if !ok {
+			continue // Acknowledged in the meantime
+		}
+
+		message, err := q.delivered(raw)
+		if err != nil {
+			continue
+		}
+
+		pending = append(pending, &PendingMessage{
+			ID:             message.ID,
+			Attempts:       message.Attempts,
+			LeaseExpiresAt: time.UnixMilli(int64(leases[i].Score)),
+		})
+	}
+
+	return pending, nil
 }
 
 // Ack acknowledges successful message processing
 func (q *RedisQueue) Ack(ctx context.Context, message *Message) error {
-	processingKey := q.processingKey(message.Queue)
-	data, _ := json.Marshal(message)
+	pipe := q.client.TxPipeline()
+	q.release(ctx, pipe, message)
 
-	return q.client.LRem(ctx, processingKey, 1, string(data)).Err()
+	_, err := pipe.Exec(ctx)
+	return err
 }
 
 // Nack rejects a message and requeues it
 func (q *RedisQueue) Nack(ctx context.Context, message *Message) error {
-	processingKey := q.processingKey(message.Queue)
 	queueKey := q.queueKey(message.Queue)
 
 	data, _ := json.Marshal(message)
 
 	// Remove from processing queue and add back to main queue
-	pipe := q.client.Pipeline()
-	pipe.LRem(ctx, processingKey, 1, string(data))
+	pipe := q.client.TxPipeline()
+	q.release(ctx, pipe, message)
 	pipe.LPush(ctx, queueKey, data)
 
 	_, err := pipe.Exec(ctx)
 	return err
 }
 
+// Requeue removes a message from the processing list and queues it again.
+// With a positive delay it waits in the delayed set like a message enqueued
+// with RunAt, so it can still be removed by ID.
+func (q *RedisQueue) Requeue(ctx context.Context, message *Message, delay time.Duration) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	pipe := q.client.TxPipeline()
+	q.release(ctx, pipe, message)
+	if delay > 0 {
+		pipe.HSet(ctx, q.delayedMessagesKey(message.Queue), message.ID, data)
+		pipe.ZAdd(ctx, q.delayedKey(message.Queue), &redis.Z{
+			Score:  float64(time.Now().Add(delay).UnixMilli()),
+			Member: message.ID,
+		})
+	} else {
+		pipe.LPush(ctx, q.queueKey(message.Queue), data)
+	}
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
 // DeadLetter moves a message to the dead letter queue
 func (q *RedisQueue) DeadLetter(ctx context.Context, message *Message, reason string) error {
-	processingKey := q.processingKey(message.Queue)
 	deadLetterKey := q.deadLetterKey(message.Queue)
 
 	// Add reason to message headers
@@ -105,30 +402,160 @@ func (q *RedisQueue) DeadLetter(ctx context.Context, message *Message, reason st
 
 	data, _ := json.Marshal(message)
 
-	pipe := q.client.Pipeline()
-	pipe.LRem(ctx, processingKey, 1, string(data))
+	pipe := q.client.TxPipeline()
+	q.release(ctx, pipe, message)
 	pipe.LPush(ctx, deadLetterKey, data)
 
 	_, err := pipe.Exec(ctx)
 	return err
 }
 
-// GetStats returns queue statistics
-func (q *RedisQueue) GetStats(ctx context.Context, queue string) (*QueueStats, error) {
-	queueKey := q.queueKey(queue)
+// ListDeadLetters returns the messages in the dead letter list of a queue
+func (q *RedisQueue) ListDeadLetters(ctx context.Context, queue string) ([]*Message, error) {
+	return listDeadLetters(ctx, q.client, q.deadLetterKey(queue))
+}
 
-	size, err := q.client.LLen(ctx, queueKey).Result()
-	if err != nil {
+// RemoveDeadLetter deletes a message from the dead letter list of a queue
+func (q *RedisQueue) RemoveDeadLetter(ctx context.Context, queue, messageID string) (bool, error) {
+	return removeDeadLetter(ctx, q.client, q.deadLetterKey(queue), messageID)
+}
+
+// Heartbeat records that consumer is active on the queue
+func (q *RedisQueue) Heartbeat(ctx context.Context, queue, consumer string) error {
+	return recordHeartbeat(ctx, q.client, q.consumersKey(queue), consumer)
+}
+
+// GetStats returns queue statistics. Messages keep no record of when they
+// became ready, so OldestMessageAge is measured from when the next message
+// to be delivered was first enqueued.
+func (q *RedisQueue) GetStats(ctx context.Context, queue string) (*QueueStats, error) {
+	pipe := q.client.Pipeline()
+	size := pipe.LLen(ctx, q.queueKey(queue))
+	inflight := pipe.LLen(ctx, q.processingKey(queue))
+	delayed := pipe.ZCard(ctx, q.delayedKey(queue))
+	deadLetters := pipe.LLen(ctx, q.deadLetterKey(queue))
+	next := pipe.LIndex(ctx, q.queueKey(queue), -1)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
 		return nil, fmt.Errorf("failed to get queue size: %w", err)
 	}
 
-	return &QueueStats{
+	stats := &QueueStats{
 		Name:        queue,
-		Size:        size,
-		Consumers:   0, // Would need additional tracking
-		EnqueueRate: 0, // Would need time-series data
-		DequeueRate: 0, // Would need time-series data
-	}, nil
+		Size:        size.Val(),
+		InFlight:    inflight.Val(),
+		Delayed:     delayed.Val(),
+		DeadLetters: deadLetters.Val(),
+	}
+
+	var message Message
+	if raw := next.Val(); raw != "" && json.Unmarshal([]byte(raw), &message) == nil {
+		stats.OldestMessageAge = time.Since(message.EnqueuedAt)
+	}
+
+	if err := readActivity(ctx, q.client, q.consumersKey(queue), q.statsKey(queue), stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// promoteDelayed moves due delayed messages onto the queue and returns how
+// long a dequeue may block before the next one becomes due, at most timeout
+func (q *RedisQueue) promoteDelayed(ctx context.Context, queue string, timeout time.Duration) (time.Duration, error) {
+	return promoteDelayed(ctx, q.client, promoteDelayedScript,
+		[]string{q.delayedKey(queue), q.delayedMessagesKey(queue), q.queueKey(queue)},
+		timeout,
+	)
+}
+
+// promoteDelayed runs a script that moves due delayed messages onto a
+// queue and returns the delivery time of the next one, and converts that
+// time to how long a dequeue may block, at most timeout
+func promoteDelayed(ctx context.Context, client *redis.Client, script *redis.Script, keys []string, timeout time.Duration) (time.Duration, error) {
+	now := time.Now()
+	next, err := script.Run(ctx, client, keys, now.UnixMilli(), promoteBatchSize).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to promote delayed messages: %w", err)
+	}
+
+	if next < 0 {
+		return timeout, nil
+	}
+
+	wait := time.UnixMilli(next).Sub(now)
+	if wait < time.Second {
+		// Redis blocking timeouts have a resolution of one second
+		wait = time.Second
+	}
+	if timeout > 0 && wait > timeout {
+		wait = timeout
+	}
+
+	return wait, nil
+}
+
+// delivered decodes a message taken from the processing list and counts
+// the delivery
+func (q *RedisQueue) delivered(raw string) (*Message, error) {
+	var message Message
+	if err := json.Unmarshal([]byte(raw), &message); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+
+	message.receipt = raw
+	message.Attempts++
+	return &message, nil
+}
+
+// release removes a delivered message from the processing list along with
+// its lease. Messages that were not delivered by this queue have nothing to
+// release.
+func (q *RedisQueue) release(ctx context.Context, pipe redis.Pipeliner, message *Message) {
+	if message.receipt == "" {
+		return
+	}
+
+	token := leaseToken(message.receipt)
+	pipe.LRem(ctx, q.processingKey(message.Queue), 1, message.receipt)
+	pipe.ZRem(ctx, q.leasesKey(message.Queue), token)
+	pipe.HDel(ctx, q.inflightKey(message.Queue), token)
+}
+
+// listDeadLetters returns the messages in a dead letter list
+func listDeadLetters(ctx context.Context, client *redis.Client, key string) ([]*Message, error) {
+	items, err := client.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	messages := make([]*Message, 0, len(items))
+	for _, item := range items {
+		var message Message
+		if err := json.Unmarshal([]byte(item), &message); err != nil {
+			continue
+		}
+		messages = append(messages, &message)
+	}
+
+	return messages, nil
+}
+
+// removeDeadLetter deletes the message with the given ID from a dead letter
+// list
+func removeDeadLetter(ctx context.Context, client *redis.Client, key, messageID string) (bool, error) {
+	removed, err := removeQueuedScript.Run(ctx, client, []string{key}, messageID).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove dead letter: %w", err)
+	}
+
+	return removed > 0, nil
+}
+
+// leaseToken identifies the lease of a message as stored in the processing
+// list; claimExpiredScript computes the same value with redis.sha1hex
+func leaseToken(raw string) string {
+	sum := sha1.Sum([]byte(raw))
+	return hex.EncodeToString(sum[:])
 }
 
 func (q *RedisQueue) queueKey(queue string) string {
@@ -142,3 +569,27 @@ func (q *RedisQueue) processingKey(queue string) string {
 func (q *RedisQueue) deadLetterKey(queue string) string {
 	return fmt.Sprintf("%s:dead_letter:%s", q.prefix, queue)
 }
+
+func (q *RedisQueue) delayedKey(queue string) string {
+	return fmt.Sprintf("%s:delayed:%s", q.prefix, queue)
+}
+
+func (q *RedisQueue) delayedMessagesKey(queue string) string {
+	return fmt.Sprintf("%s:delayed_messages:%s", q.prefix, queue)
+}
+
+func (q *RedisQueue) leasesKey(queue string) string {
+	return fmt.Sprintf("%s:leases:%s", q.prefix, queue)
+}
+
+func (q *RedisQueue) inflightKey(queue string) string {
+	return fmt.Sprintf("%s:inflight:%s", q.prefix, queue)
+}
+
+func (q *RedisQueue) consumersKey(queue string) string {
+	return fmt.Sprintf("%s:consumers:%s", q.prefix, queue)
+}
+
+func (q *RedisQueue) statsKey(queue string) string {
+	return fmt.Sprintf("%s:stats:%s", q.prefix, queue)
+}
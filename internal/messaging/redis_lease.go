@@ -0,0 +1,68 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// renewLeaseScript extends the lease only if it is still held by the caller
+var renewLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// releaseLeaseScript deletes the lease only if it is still held by the caller
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// RedisLease implements Lease with a Redis key holding the holder's ID
+type RedisLease struct {
+	client *redis.Client
+	key    string
+	holder string
+	ttl    time.Duration
+}
+
+// NewRedisLease creates a lease named name. holder must be unique per
+// process.
+func NewRedisLease(client *redis.Client, prefix, name, holder string, ttl time.Duration) *RedisLease {
+	return &RedisLease{
+		client: client,
+		key:    fmt.Sprintf("%s:lease:%s", prefix, name),
+		holder: holder,
+		ttl:    ttl,
+	}
+}
+
+// Acquire takes or renews the lease
+func (l *RedisLease) Acquire(ctx context.Context) (bool, error) {
+	renewed, err := renewLeaseScript.Run(ctx, l.client, []string{l.key}, l.holder, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew lease: %w", err)
+	}
+	if renewed == 1 {
+		return true, nil
+	}
+
+	acquired, err := l.client.SetNX(ctx, l.key, l.holder, l.ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+
+	return acquired, nil
+}
+
+// Release gives the lease up if held
+func (l *RedisLease) Release(ctx context.Context) error {
+	if err := releaseLeaseScript.Run(ctx, l.client, []string{l.key}, l.holder).Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisNotifier implements Notifier using Redis pub/sub
+type RedisNotifier struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisNotifier creates a new Redis notifier
+func NewRedisNotifier(client *redis.Client, prefix string) *RedisNotifier {
+	return &RedisNotifier{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Publish sends a payload to all current subscribers of a channel
+func (n *RedisNotifier) Publish(ctx context.Context, channel string, payload []byte) error {
+	if err := n.client.Publish(ctx, n.channelKey(channel), payload).Err(); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+	return nil
+}
+
+// Subscribe subscribes to a channel. The subscription is confirmed by Redis
+// before returning, so events published afterwards are not missed.
+func (n *RedisNotifier) Subscribe(ctx context.Context, channel string) (Subscription, error) {
+	pubsub := n.client.Subscribe(ctx, n.channelKey(channel))
+
+	// Wait for subscription confirmation
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe to channel: %w", err)
+	}
+
+	sub := &redisSubscription{
+		pubsub: pubsub,
+		events: make(chan []byte, 16),
+		done:   make(chan struct{}),
+	}
+	go sub.forward()
+
+	return sub, nil
+}
+
+func (n *RedisNotifier) channelKey(channel string) string {
+	return fmt.Sprintf("%s:events:%s", n.prefix, channel)
+}
+
+// redisSubscription adapts a Redis PubSub to Subscription
+type redisSubscription struct {
+	pubsub *redis.PubSub
+	events chan []byte
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Events returns the channel on which published payloads are delivered
+func (s *redisSubscription) Events() <-chan []byte {
+	return s.events
+}
+
+// Close unsubscribes and releases resources
+func (s *redisSubscription) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return s.pubsub.Close()
+}
+
+// forward copies Redis messages to the events channel until the
+// subscription is closed
+func (s *redisSubscription) forward() {
+	defer close(s.events)
+	for msg := range s.pubsub.Channel() {
+		select {
+		case s.events <- []byte(msg.Payload):
+		case <-s.done:
+			return
+		}
+	}
+}
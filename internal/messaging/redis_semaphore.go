@@ -0,0 +1,73 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// acquireSlotScript drops expired slots, then takes or renews the caller's
+// slot if it already has one or fewer than the limit are held. Slots are
+// the members of a sorted set scored by their expiry.
+var acquireSlotScript = redis.NewScript(`
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", ARGV[1])
+if redis.call("ZSCORE", KEYS[1], ARGV[2]) or redis.call("ZCARD", KEYS[1]) < tonumber(ARGV[4]) then
+	redis.call("ZADD", KEYS[1], ARGV[3], ARGV[2])
+	redis.call("PEXPIRE", KEYS[1], ARGV[5])
+	return 1
+end
+return 0`)
+
+// RedisSemaphore implements Semaphore with a sorted set per name
+type RedisSemaphore struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedisSemaphore creates a semaphore whose slots expire after ttl
+func NewRedisSemaphore(client *redis.Client, prefix string, ttl time.Duration) *RedisSemaphore {
+	return &RedisSemaphore{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+// Acquire takes or renews a slot
+func (s *RedisSemaphore) Acquire(ctx context.Context, name, holder string, limit int) (bool, error) {
+	now := time.Now()
+	acquired, err := acquireSlotScript.Run(ctx, s.client, []string{s.key(name)},
+		now.UnixMilli(), holder, now.Add(s.ttl).UnixMilli(), limit, s.ttl.Milliseconds(),
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire semaphore slot: %w", err)
+	}
+
+	return acquired == 1, nil
+}
+
+// Release frees a slot
+func (s *RedisSemaphore) Release(ctx context.Context, name, holder string) error {
+	if err := s.client.ZRem(ctx, s.key(name), holder).Err(); err != nil {
+		return fmt.Errorf("failed to release semaphore slot: %w", err)
+	}
+	return nil
+}
+
+// Count returns the number of unexpired slots
+func (s *RedisSemaphore) Count(ctx context.Context, name string) (int, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	count, err := s.client.ZCount(ctx, s.key(name), "("+now, "+inf").Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count semaphore slots: %w", err)
+	}
+	return int(count), nil
+}
+
+func (s *RedisSemaphore) key(name string) string {
+	return fmt.Sprintf("%s:semaphore:%s", s.prefix, name)
+}
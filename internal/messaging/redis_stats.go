@@ -0,0 +1,104 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Events counted for the enqueue and dequeue rates
+const (
+	eventEnqueued = "enqueued"
+	eventDequeued = "dequeued"
+)
+
+// countEvent adds one to the current rate bucket of an event
+func countEvent(ctx context.Context, pipe redis.Pipeliner, statsKey, event string) {
+	countEvents(ctx, pipe, statsKey, event, 1)
+}
+
+// countEvents adds n to the current rate bucket of an event. Each bucket
+// is a counter that expires once it leaves the rate window.
+func countEvents(ctx context.Context, pipe redis.Pipeliner, statsKey, event string, n int) {
+	key := rateBucketKey(statsKey, event, time.Now().Truncate(rateBucket))
+	pipe.IncrBy(ctx, key, int64(n))
+	pipe.Expire(ctx, key, rateWindow+rateBucket)
+}
+
+// countClaimed counts messages taken over from lost consumers as
+// dequeued. They were already claimed, so a failed count only skews the
+// rate.
+func countClaimed(ctx context.Context, client *redis.Client, statsKey string, n int) {
+	if n == 0 {
+		return
+	}
+	pipe := client.Pipeline()
+	countEvents(ctx, pipe, statsKey, eventDequeued, n)
+	pipe.Exec(ctx)
+}
+
+// recordHeartbeat marks a consumer as seen now in a sorted set scored by
+// the time of its last heartbeat, dropping consumers that timed out
+func recordHeartbeat(ctx context.Context, client *redis.Client, consumersKey, consumer string) error {
+	now := time.Now()
+
+	pipe := client.TxPipeline()
+	pipe.ZAdd(ctx, consumersKey, &redis.Z{
+		Score:  float64(now.UnixMilli()),
+		Member: consumer,
+	})
+	pipe.ZRemRangeByScore(ctx, consumersKey, "-inf", fmt.Sprintf("(%d", now.Add(-ConsumerTimeout).UnixMilli()))
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+
+	return nil
+}
+
+// readActivity fills in the active consumers and the enqueue and dequeue
+// rates of a queue
+func readActivity(ctx context.Context, client *redis.Client, consumersKey, statsKey string, stats *QueueStats) error {
+	now := time.Now()
+	buckets := rateBuckets(now)
+	enqueuedKeys := make([]string, len(buckets))
+	dequeuedKeys := make([]string, len(buckets))
+	for i, bucket := range buckets {
+		enqueuedKeys[i] = rateBucketKey(statsKey, eventEnqueued, bucket)
+		dequeuedKeys[i] = rateBucketKey(statsKey, eventDequeued, bucket)
+	}
+
+	pipe := client.Pipeline()
+	consumers := pipe.ZCount(ctx, consumersKey, strconv.FormatInt(now.Add(-ConsumerTimeout).UnixMilli(), 10), "+inf")
+	enqueued := pipe.MGet(ctx, enqueuedKeys...)
+	dequeued := pipe.MGet(ctx, dequeuedKeys...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to read queue activity: %w", err)
+	}
+
+	stats.Consumers = int(consumers.Val())
+	stats.EnqueueRate = ratePerSecond(sumCounters(enqueued.Val()), now)
+	stats.DequeueRate = ratePerSecond(sumCounters(dequeued.Val()), now)
+	return nil
+}
+
+// sumCounters adds up counters read with MGET; missing counters are nil
+func sumCounters(values []interface{}) int64 {
+	var total int64
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			n, _ := strconv.ParseInt(s, 10, 64)
+			total += n
+		}
+	}
+	return total
+}
+
+// rateBucketKey returns the key counting an event in the bucket starting at
+// the given time
+func rateBucketKey(statsKey, event string, bucket time.Time) string {
+	return fmt.Sprintf("%s:%s:%d", statsKey, event, bucket.Unix())
+}
@@ -0,0 +1,601 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// streamGroup is the consumer group all consumers of a stream queue join
+const streamGroup = "faas"
+
+// streamField is the stream entry field holding the message
+const streamField = "message"
+
+// streamAddScript appends a message to a stream and records its entry ID
+// under the message ID
+var streamAddScript = redis.NewScript(`
+local entry = redis.call("XADD", KEYS[1], "*", "message", ARGV[2])
+redis.call("HSET", KEYS[2], ARGV[1], entry)
+return entry`)
+
+// streamPromoteScript moves due delayed messages onto a stream and returns
+// the delivery time (unix ms) of the next delayed message, or -1
+var streamPromoteScript = redis.NewScript(`
+local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[2])
+for _, id in ipairs(due) do
+	local data = redis.call("HGET", KEYS[2], id)
+	redis.call("ZREM", KEYS[1], id)
+	redis.call("HDEL", KEYS[2], id)
+	if data then
+		local entry = redis.call("XADD", KEYS[3], "*", "message", data)
+		redis.call("HSET", KEYS[4], id, entry)
+	end
+end
+local upcoming = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+if #upcoming == 0 then
+	return -1
+end
+return tonumber(upcoming[2])`)
+
+// streamRemoveScript deletes the entry of a message that has not been
+// delivered yet; delivered entries are in the pending entries list
+var streamRemoveScript = redis.NewScript(`
+local entry = redis.call("HGET", KEYS[2], ARGV[2])
+if not entry then
+	return 0
+end
+if #redis.call("XPENDING", KEYS[1], ARGV[1], entry, entry, 1) > 0 then
+	return 0
+end
+redis.call("HDEL", KEYS[2], ARGV[2])
+return redis.call("XDEL", KEYS[1], entry)`)
+
+// streamReleaseScript acknowledges and deletes a delivered entry, and
+// forgets its message ID unless the message was added again since
+var streamReleaseScript = redis.NewScript(`
+redis.call("XACK", KEYS[1], ARGV[1], ARGV[2])
+redis.call("XDEL", KEYS[1], ARGV[2])
+if redis.call("HGET", KEYS[2], ARGV[3]) == ARGV[2] then
+	redis.call("HDEL", KEYS[2], ARGV[3])
+end
+return 1`)
+
+// streamExtendScript resets the idle time of a pending entry if it is
+// still held by the given consumer
+var streamExtendScript = redis.NewScript(`
+local pending = redis.call("XPENDING", KEYS[1], ARGV[1], ARGV[3], ARGV[3], 1)
+if #pending == 0 or pending[1][2] ~= ARGV[2] then
+	return 0
+end
+redis.call("XCLAIM", KEYS[1], ARGV[1], ARGV[2], 0, ARGV[3], "JUSTID")
+return 1`)
+
+// streamClaimScript claims up to ARGV[4] entries idle for at least ARGV[3]
+// ms and returns the ID, message and delivery count of each, which
+// includes the claim. Entries deleted while pending are skipped.
+var streamClaimScript = redis.NewScript(`
+local claimed = redis.call("XAUTOCLAIM", KEYS[1], ARGV[1], ARGV[2], ARGV[3], "0-0", "COUNT", ARGV[4])
+local result = {}
+for _, entry in ipairs(claimed[2]) do
+	if entry and entry[2] then
+		local pending = redis.call("XPENDING", KEYS[1], ARGV[1], entry[1], entry[1], 1)
+		local fields = entry[2]
+		for i = 1, #fields, 2 do
+			if fields[i] == "message" and #pending > 0 then
+				table.insert(result, entry[1])
+				table.insert(result, fields[i + 1])
+				table.insert(result, pending[1][4])
+			end
+		end
+	end
+end
+return result`)
+
+// RedisStreamQueue implements Queue using Redis Streams. Each queue is a
+// stream read by one consumer group, so Redis tracks which consumer holds
+// each delivered message and how often it was delivered. A delivered
+// message's lease is its idle time in the group's pending entries list:
+// it can be claimed once it has been idle for the visibility timeout.
+//
+// Acknowledged entries are deleted, keeping the stream to messages that
+// are queued or in flight. Delayed messages and dead letters use the same
+// keys as RedisQueue.
+type RedisStreamQueue struct {
+	client            *redis.Client
+	prefix            string
+	consumer          string
+	visibilityTimeout time.Duration
+
+	// Streams whose consumer group is known to exist
+	groups sync.Map
+}
+
+// NewRedisStreamQueue creates a new Redis Streams queue. consumer names
+// this process within the consumer groups and must be unique among the
+// processes reading the queues.
+func NewRedisStreamQueue(client *redis.Client, prefix, consumer string, visibilityTimeout time.Duration) *RedisStreamQueue {
+	if visibilityTimeout <= 0 {
+		visibilityTimeout = defaultVisibilityTimeout
+	}
+
+	return &RedisStreamQueue{
+		client:            client,
+		prefix:            prefix,
+		consumer:          consumer,
+		visibilityTimeout: visibilityTimeout,
+	}
+}
+
+// Enqueue adds a message to the queue
+func (q *RedisStreamQueue) Enqueue(ctx context.Context, queue string, payload []byte, headers map[string]string) error {
+	return q.EnqueueWithOptions(ctx, queue, payload, headers, EnqueueOptions{})
+}
+
+// EnqueueWithOptions adds a message to the queue, holding it back until
+// opts.RunAt if that is in the future
+func (q *RedisStreamQueue) EnqueueWithOptions(ctx context.Context, queue string, payload []byte, headers map[string]string, opts EnqueueOptions) error {
+	if opts.MessageID == "" {
+		opts.MessageID = uuid.New().String()
+	}
+
+	message := Message{
+		ID:         opts.MessageID,
+		Queue:      queue,
+		Payload:    payload,
+		Headers:    headers,
+		Attempts:   0,
+		EnqueuedAt: time.Now(),
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	pipe := q.client.TxPipeline()
+	if opts.RunAt.After(time.Now()) {
+		pipe.HSet(ctx, q.delayedMessagesKey(queue), message.ID, data)
+		pipe.ZAdd(ctx, q.delayedKey(queue), &redis.Z{
+			Score:  float64(opts.RunAt.UnixMilli()),
+			Member: message.ID,
+		})
+	} else {
+		streamAddScript.Eval(ctx, pipe, q.addKeys(queue), message.ID, data)
+	}
+	countEvent(ctx, pipe, q.statsKey(queue), eventEnqueued)
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Remove deletes a message before it is delivered
+func (q *RedisStreamQueue) Remove(ctx context.Context, queue, messageID string) (bool, error) {
+	removed, err := q.client.ZRem(ctx, q.delayedKey(queue), messageID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove delayed message: %w", err)
+	}
+	if removed > 0 {
+		if err := q.client.HDel(ctx, q.delayedMessagesKey(queue), messageID).Err(); err != nil {
+			return true, fmt.Errorf("failed to delete delayed message body: %w", err)
+		}
+		return true, nil
+	}
+
+	if err := q.ensureGroup(ctx, queue); err != nil {
+		return false, err
+	}
+
+	removed, err = streamRemoveScript.Run(ctx, q.client, q.addKeys(queue), streamGroup, messageID).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove queued message: %w", err)
+	}
+
+	return removed > 0, nil
+}
+
+// Dequeue reads the next undelivered message of the queue for this
+// consumer
+func (q *RedisStreamQueue) Dequeue(ctx context.Context, queue string, timeout time.Duration) (*Message, error) {
+	return q.DequeueFirst(ctx, []string{queue}, timeout)
+}
+
+// DequeueFirst reads the next undelivered message of the first of the
+// queues that has one. With several queues it blocks on the first for at
+// most laneRecheckInterval, so a read of one stream cannot hide messages
+// added to the others.
+func (q *RedisStreamQueue) DequeueFirst(ctx context.Context, queues []string, timeout time.Duration) (*Message, error) {
+	// Deliver delayed messages that are due, and stop blocking in time for
+	// the next one
+	wait := timeout
+	for _, queue := range queues {
+		if err := q.ensureGroup(ctx, queue); err != nil {
+			return nil, err
+		}
+
+		next, err := promoteDelayed(ctx, q.client, streamPromoteScript,
+			[]string{q.delayedKey(queue), q.delayedMessagesKey(queue), q.streamKey(queue), q.entryIDsKey(queue)},
+			wait,
+		)
+		if err != nil {
+			return nil, err
+		}
+		wait = next
+	}
+
+	if len(queues) > 1 {
+		for _, queue := range queues {
+			// A negative block reads without blocking
+			message, err := q.read(ctx, queue, -1)
+			if err != nil || message != nil {
+				return message, err
+			}
+		}
+
+		if wait <= 0 || wait > laneRecheckInterval {
+			wait = laneRecheckInterval
+		}
+	}
+
+	return q.read(ctx, queues[0], wait)
+}
+
+// read delivers the next undelivered entry of a queue's stream to this
+// consumer, blocking up to block for one
+func (q *RedisStreamQueue) read(ctx context.Context, queue string, block time.Duration) (*Message, error) {
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    streamGroup,
+		Consumer: q.consumer,
+		Streams:  []string{q.streamKey(queue), ">"},
+		Count:    1,
+		Block:    block,
+	}).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil // No message available
+		}
+		return nil, fmt.Errorf("failed to dequeue message: %w", err)
+	}
+	if len(streams) == 0 || len(streams[0].Messages) == 0 {
+		return nil, nil
+	}
+
+	entry := streams[0].Messages[0]
+	data, _ := entry.Values[streamField].(string)
+
+	// The entry is already delivered; a failed count only skews the rate
+	pipe := q.client.Pipeline()
+	countEvent(ctx, pipe, q.statsKey(queue), eventDequeued)
+	pipe.Exec(ctx)
+
+	return q.delivered(ctx, queue, entry.ID, data, 1)
+}
+
+// ExtendLease resets the idle time of a delivered message, provided this
+// consumer still holds it
+func (q *RedisStreamQueue) ExtendLease(ctx context.Context, message *Message) error {
+	extended, err := streamExtendScript.Run(ctx, q.client,
+		[]string{q.streamKey(message.Queue)},
+		streamGroup, q.consumer, message.receipt,
+	).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to extend lease: %w", err)
+	}
+	if extended == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
+
+// ClaimExpired transfers messages idle for the visibility timeout to this
+// consumer
+func (q *RedisStreamQueue) ClaimExpired(ctx context.Context, queue string, limit int) ([]*Message, error) {
+	if err := q.ensureGroup(ctx, queue); err != nil {
+		return nil, err
+	}
+
+	result, err := streamClaimScript.Run(ctx, q.client,
+		[]string{q.streamKey(queue)},
+		streamGroup, q.consumer, q.visibilityTimeout.Milliseconds(), limit,
+	).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim expired messages: %w", err)
+	}
+
+	messages := make([]*Message, 0, len(result)/3)
+	for i := 0; i+2 < len(result); i += 3 {
+		entryID, _ := result[i].(string)
+		data, _ := result[i+1].(string)
+		deliveries, _ := result[i+2].(int64)
+
+		// XAUTOCLAIM counted the claim as a delivery; like the other
+		// queues, report the attempt of the delivery whose lease expired
+		message, err := q.delivered(ctx, queue, entryID, data, int(deliveries)-1)
+		if err != nil {
+			continue
+		}
+		messages = append(messages, message)
+	}
+	countClaimed(ctx, q.client, q.statsKey(queue), len(messages))
+
+	return messages, nil
+}
+
+// ListPending returns the consumer group's pending entries, soonest lease
+// expiry first
+func (q *RedisStreamQueue) ListPending(ctx context.Context, queue string, limit int) ([]*PendingMessage, error) {
+	if err := q.ensureGroup(ctx, queue); err != nil {
+		return nil, err
+	}
+
+	entries, err := q.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: q.streamKey(queue),
+		Group:  streamGroup,
+		Start:  "-",
+		End:    "+",
+		Count:  int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending entries: %w", err)
+	}
+
+	// Read the messages to report their IDs and earlier deliveries
+	pipe := q.client.Pipeline()
+	reads := make([]*redis.XMessageSliceCmd, len(entries))
+	for i, entry := range entries {
+		reads[i] = pipe.XRange(ctx, q.streamKey(queue), entry.ID, entry.ID)
+	}
+	if len(entries) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, fmt.Errorf("failed to read pending entries: %w", err)
+		}
+	}
+
+	now := time.Now()
+	pending := make([]*PendingMessage, 0, len(entries))
+	for i, entry := range entries {
+		read, err := reads[i].Result()
+		if err != nil || len(read) == 0 {
+			continue // Acknowledged in the meantime
+		}
+
+		var message Message
+		data, _ := read[0].Values[streamField].(string)
+		if err := json.Unmarshal([]byte(data), &message); err != nil {
+			continue
+		}
+
+		pending = append(pending, &PendingMessage{
+			ID:             message.ID,
+			Consumer:       entry.Consumer,
+			Attempts:       message.Attempts + int(entry.RetryCount),
+			LeaseExpiresAt: now.Add(q.visibilityTimeout - entry.Idle),
+		})
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].LeaseExpiresAt.Before(pending[j].LeaseExpiresAt)
+	})
+
+	return pending, nil
+}
+
+// Ack acknowledges successful message processing
+func (q *RedisStreamQueue) Ack(ctx context.Context, message *Message) error {
+	if message.receipt == "" {
+		return nil
+	}
+
+	return streamReleaseScript.Run(ctx, q.client, q.addKeys(message.Queue),
+		streamGroup, message.receipt, message.ID,
+	).Err()
+}
+
+// Nack rejects a message and requeues it
+func (q *RedisStreamQueue) Nack(ctx context.Context, message *Message) error {
+	return q.Requeue(ctx, message, 0)
+}
+
+// Requeue acknowledges a delivered message and adds it to the queue again
+// with its attempt count, in the delayed set if delay is positive
+func (q *RedisStreamQueue) Requeue(ctx context.Context, message *Message, delay time.Duration) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	pipe := q.client.TxPipeline()
+	q.release(ctx, pipe, message)
+	if delay > 0 {
+		pipe.HSet(ctx, q.delayedMessagesKey(message.Queue), message.ID, data)
+		pipe.ZAdd(ctx, q.delayedKey(message.Queue), &redis.Z{
+			Score:  float64(time.Now().Add(delay).UnixMilli()),
+			Member: message.ID,
+		})
+	} else {
+		streamAddScript.Eval(ctx, pipe, q.addKeys(message.Queue), message.ID, data)
+	}
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// DeadLetter acknowledges a message and moves it to the dead letter list
+func (q *RedisStreamQueue) DeadLetter(ctx context.Context, message *Message, reason string) error {
+	if message.Headers == nil {
+		message.Headers = make(map[string]string)
+	}
+	message.Headers["dead_letter_reason"] = reason
+	message.Headers["dead_lettered_at"] = time.Now().Format(time.RFC3339)
+
+	data, _ := json.Marshal(message)
+
+	pipe := q.client.TxPipeline()
+	q.release(ctx, pipe, message)
+	pipe.LPush(ctx, q.deadLetterKey(message.Queue), data)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ListDeadLetters returns the messages in the dead letter list of a queue
+func (q *RedisStreamQueue) ListDeadLetters(ctx context.Context, queue string) ([]*Message, error) {
+	return listDeadLetters(ctx, q.client, q.deadLetterKey(queue))
+}
+
+// RemoveDeadLetter deletes a message from the dead letter list of a queue
+func (q *RedisStreamQueue) RemoveDeadLetter(ctx context.Context, queue, messageID string) (bool, error) {
+	return removeDeadLetter(ctx, q.client, q.deadLetterKey(queue), messageID)
+}
+
+// Heartbeat records that consumer is active on the queue
+func (q *RedisStreamQueue) Heartbeat(ctx context.Context, queue, consumer string) error {
+	return recordHeartbeat(ctx, q.client, q.consumersKey(queue), consumer)
+}
+
+// GetStats returns queue statistics. Size counts messages not delivered
+// yet; the oldest of them is the first entry after the last one delivered
+// to the group, and its entry ID holds the time it was added.
+func (q *RedisStreamQueue) GetStats(ctx context.Context, queue string) (*QueueStats, error) {
+	if err := q.ensureGroup(ctx, queue); err != nil {
+		return nil, err
+	}
+
+	pipe := q.client.Pipeline()
+	length := pipe.XLen(ctx, q.streamKey(queue))
+	pending := pipe.XPending(ctx, q.streamKey(queue), streamGroup)
+	groups := pipe.XInfoGroups(ctx, q.streamKey(queue))
+	delayed := pipe.ZCard(ctx, q.delayedKey(queue))
+	deadLetters := pipe.LLen(ctx, q.deadLetterKey(queue))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get queue size: %w", err)
+	}
+
+	stats := &QueueStats{
+		Name:        queue,
+		Size:        length.Val() - pending.Val().Count,
+		InFlight:    pending.Val().Count,
+		Delayed:     delayed.Val(),
+		DeadLetters: deadLetters.Val(),
+	}
+
+	for _, group := range groups.Val() {
+		if group.Name != streamGroup || stats.Size == 0 {
+			continue
+		}
+
+		next, err := q.client.XRangeN(ctx, q.streamKey(queue), "("+group.LastDeliveredID, "+", 1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read next entry: %w", err)
+		}
+		if len(next) > 0 {
+			if addedAt, ok := entryTime(next[0].ID); ok {
+				stats.OldestMessageAge = time.Since(addedAt)
+			}
+		}
+	}
+
+	if err := readActivity(ctx, q.client, q.consumersKey(queue), q.statsKey(queue), stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// ensureGroup creates the queue's stream and consumer group if they do not
+// exist. The group starts at the beginning of the stream, so messages
+// added before it was created are delivered.
+func (q *RedisStreamQueue) ensureGroup(ctx context.Context, queue string) error {
+	key := q.streamKey(queue)
+	if _, ok := q.groups.Load(key); ok {
+		return nil
+	}
+
+	err := q.client.XGroupCreateMkStream(ctx, key, streamGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+
+	q.groups.Store(key, struct{}{})
+	return nil
+}
+
+// delivered decodes the message of a stream entry. deliveries counts the
+// deliveries of this entry; the message's own count covers earlier
+// entries of a requeued message.
+func (q *RedisStreamQueue) delivered(ctx context.Context, queue, entryID, data string, deliveries int) (*Message, error) {
+	var message Message
+	if err := json.Unmarshal([]byte(data), &message); err != nil {
+		// It can never be processed; drop it so it is not redelivered
+		streamReleaseScript.Run(ctx, q.client, q.addKeys(queue), streamGroup, entryID, "")
+		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+
+	message.receipt = entryID
+	message.Attempts += deliveries
+	return &message, nil
+}
+
+// release acknowledges and deletes the entry of a delivered message.
+// Messages that were not delivered by this queue have nothing to release.
+func (q *RedisStreamQueue) release(ctx context.Context, pipe redis.Pipeliner, message *Message) {
+	if message.receipt == "" {
+		return
+	}
+
+	streamReleaseScript.Eval(ctx, pipe, q.addKeys(message.Queue), streamGroup, message.receipt, message.ID)
+}
+
+// entryTime returns the time a stream entry was added, which is the first
+// part of its ID
+func entryTime(entryID string) (time.Time, bool) {
+	timestamp, _, _ := strings.Cut(entryID, "-")
+	ms, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// addKeys returns the keys of the queue's stream and of its index of entry
+// IDs by message ID
+func (q *RedisStreamQueue) addKeys(queue string) []string {
+	return []string{q.streamKey(queue), q.entryIDsKey(queue)}
+}
+
+func (q *RedisStreamQueue) streamKey(queue string) string {
+	return fmt.Sprintf("%s:stream:%s", q.prefix, queue)
+}
+
+func (q *RedisStreamQueue) entryIDsKey(queue string) string {
+	return fmt.Sprintf("%s:stream_ids:%s", q.prefix, queue)
+}
+
+func (q *RedisStreamQueue) deadLetterKey(queue string) string {
+	return fmt.Sprintf("%s:dead_letter:%s", q.prefix, queue)
+}
+
+func (q *RedisStreamQueue) delayedKey(queue string) string {
+	return fmt.Sprintf("%s:delayed:%s", q.prefix, queue)
+}
+
+func (q *RedisStreamQueue) delayedMessagesKey(queue string) string {
+	return fmt.Sprintf("%s:delayed_messages:%s", q.prefix, queue)
+}
+
+func (q *RedisStreamQueue) consumersKey(queue string) string {
+	return fmt.Sprintf("%s:consumers:%s", q.prefix, queue)
+}
+
+func (q *RedisStreamQueue) statsKey(queue string) string {
+	return fmt.Sprintf("%s:stats:%s", q.prefix, queue)
+}
@@ -0,0 +1,31 @@
+package messaging
+
+import "time"
+
+// Enqueues and dequeues are counted in buckets of rateBucket, and their
+// rates averaged over the buckets of the last rateWindow
+const (
+	rateBucket = 10 * time.Second
+	rateWindow = time.Minute
+)
+
+// rateBuckets returns the starts of the buckets covering the rate window
+// that ends at now, oldest first. The last one is the current bucket.
+func rateBuckets(now time.Time) []time.Time {
+	count := int(rateWindow / rateBucket)
+	current := now.Truncate(rateBucket)
+
+	buckets := make([]time.Time, count)
+	for i := range buckets {
+		buckets[i] = current.Add(-time.Duration(count-1-i) * rateBucket)
+	}
+	return buckets
+}
+
+// ratePerSecond converts a count over the buckets of the rate window to a
+// rate. The current bucket has only partly elapsed, so the window spans
+// the completed buckets and the elapsed part of the current one.
+func ratePerSecond(count int64, now time.Time) float64 {
+	span := rateWindow - rateBucket + now.Sub(now.Truncate(rateBucket))
+	return float64(count) / span.Seconds()
+}
@@ -5,6 +5,8 @@ import "context"
 // Storage defines function code storage operations
 type Storage interface {
 	Store(ctx context.Context, functionID string, code []byte) (string, error)
+	StoreArtifact(ctx context.Context, functionID, buildID string, artifact []byte) (string, error)
+	StoreVersion(ctx context.Context, functionID, versionID, name string, data []byte) (string, error)
 	Retrieve(ctx context.Context, location string) ([]byte, error)
 	Delete(ctx context.Context, location string) error
 }
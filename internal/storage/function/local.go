@@ -42,6 +42,39 @@ func (s *LocalStorage) Store(ctx context.Context, functionID string, code []byte
 	return filepath.Join(functionID, "code"), nil
 }
 
+// StoreArtifact saves a build artifact to local filesystem. Each build gets
+// its own directory so Delete on the returned location removes only that
+// build.
+func (s *LocalStorage) StoreArtifact(ctx context.Context, functionID, buildID string, artifact []byte) (string, error) {
+	buildDir := filepath.Join(s.basePath, functionID, "builds", buildID)
+	if err := os.MkdirAll(buildDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create build directory: %w", err)
+	}
+
+	artifactPath := filepath.Join(buildDir, "artifact")
+	if err := os.WriteFile(artifactPath, artifact, 0644); err != nil {
+		return "", fmt.Errorf("failed to write build artifact: %w", err)
+	}
+
+	return filepath.Join(functionID, "builds", buildID, "artifact"), nil
+}
+
+// StoreVersion saves a file of a published function version. Each version
+// gets its own directory, which later updates of the function leave alone;
+// Delete on a returned location removes the whole version.
+func (s *LocalStorage) StoreVersion(ctx context.Context, functionID, versionID, name string, data []byte) (string, error) {
+	versionDir := filepath.Join(s.basePath, functionID, "versions", versionID)
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create version directory: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(versionDir, name), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write version file: %w", err)
+	}
+
+	return filepath.Join(functionID, "versions", versionID, name), nil
+}
+
 // Retrieve reads function code from local filesystem
 func (s *LocalStorage) Retrieve(ctx context.Context, location string) ([]byte, error) {
 	codePath := filepath.Join(s.basePath, location)
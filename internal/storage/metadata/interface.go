@@ -2,6 +2,7 @@ package metadata
 
 import (
 	"context"
+	"time"
 
 	"GoFaas/pkg/types"
 )
@@ -12,21 +13,87 @@ type FunctionRepository interface {
 	GetByID(ctx context.Context, id string) (*types.Function, error)
 	GetByName(ctx context.Context, name, version string) (*types.Function, error)
 	Update(ctx context.Context, fn *types.Function) error
+	CompleteBuild(ctx context.Context, fn *types.Function) (bool, error)
+	// RestartBuild stores the new build of fn if the function is still
+	// building since staleStart, and reports whether it did, so only one
+	// process takes over an abandoned build
+	RestartBuild(ctx context.Context, fn *types.Function, staleStart time.Time) (bool, error)
 	Delete(ctx context.Context, id string) error
 	List(ctx context.Context, filter FunctionFilter) ([]*types.Function, error)
+	ListPlacements(ctx context.Context) ([]*types.Placement, error)
 }
 
 // InvocationRepository defines invocation storage operations
 type InvocationRepository interface {
 	CreateInvocation(ctx context.Context, inv *types.Invocation) error
 	GetInvocationByID(ctx context.Context, id string) (*types.Invocation, error)
+	GetInvocationByIdempotencyKey(ctx context.Context, functionID, key string) (*types.Invocation, error)
+	ReleaseIdempotencyKey(ctx context.Context, id string) error
 	UpdateInvocation(ctx context.Context, inv *types.Invocation) error
+	CancelInvocation(ctx context.Context, id string, at time.Time) (bool, error)
+	RetryInvocation(ctx context.Context, id string, runAt time.Time) (bool, error)
+	CreateAttempt(ctx context.Context, a *types.InvocationAttempt) error
+	ListAttempts(ctx context.Context, invocationID string) ([]*types.InvocationAttempt, error)
 	ListInvocations(ctx context.Context, filter InvocationFilter) ([]*types.Invocation, error)
+	CountVersionOutcomes(ctx context.Context, functionID string, version int, since time.Time) (*OutcomeCounts, error)
+}
+
+// VersionRepository defines function version and alias storage operations
+type VersionRepository interface {
+	CreateVersion(ctx context.Context, v *types.FunctionVersion) error
+	GetVersion(ctx context.Context, functionName string, version int) (*types.FunctionVersion, error)
+	GetVersionByID(ctx context.Context, id string) (*types.FunctionVersion, error)
+	ListVersions(ctx context.Context, functionName string) ([]*types.FunctionVersion, error)
+
+	PutAlias(ctx context.Context, alias *types.FunctionAlias) error
+	GetAlias(ctx context.Context, functionName, name string) (*types.FunctionAlias, error)
+	ListAliases(ctx context.Context, functionName string) ([]*types.FunctionAlias, error)
+	DeleteAlias(ctx context.Context, functionName, name string) error
+	ListRoutedAliases(ctx context.Context) ([]*types.FunctionAlias, error)
+	RollbackAliasRouting(ctx context.Context, alias *types.FunctionAlias, reason string) (bool, error)
+}
+
+// ScheduleRepository defines function schedule storage operations
+type ScheduleRepository interface {
+	CreateSchedule(ctx context.Context, s *types.Schedule) error
+	GetSchedule(ctx context.Context, id string) (*types.Schedule, error)
+	ListSchedules(ctx context.Context, functionID string) ([]*types.Schedule, error)
+	UpdateSchedule(ctx context.Context, s *types.Schedule) error
+	DeleteSchedule(ctx context.Context, id string) error
+
+	ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]*types.Schedule, error)
+	ClaimScheduleRun(ctx context.Context, id string, due, next time.Time) (bool, error)
+	RecordScheduleRun(ctx context.Context, id, invocationID, runError string) error
+}
+
+// CallbackRepository defines callback delivery storage operations
+type CallbackRepository interface {
+	CreateCallbackDelivery(ctx context.Context, d *types.CallbackDelivery) error
+	ListCallbackDeliveries(ctx context.Context, invocationID string) ([]*types.CallbackDelivery, error)
+}
+
+// WorkerRepository defines worker registry operations
+type WorkerRepository interface {
+	// RegisterWorker adds a worker, or replaces a worker registered with the
+	// same ID, which then no longer drains
+	RegisterWorker(ctx context.Context, w *types.Worker) error
+	// HeartbeatWorker records a heartbeat, the executions in progress and
+	// whether the worker finished draining, and reports whether it was asked
+	// to drain
+	HeartbeatWorker(ctx context.Context, id string, inFlight int, drained bool, at time.Time) (bool, error)
+	DrainWorker(ctx context.Context, id string) error
+	DeregisterWorker(ctx context.Context, id string) error
+	GetWorker(ctx context.Context, id string) (*types.Worker, error)
+	ListWorkers(ctx context.Context) ([]*types.Worker, error)
+	// PruneWorkers deletes the workers whose last heartbeat is before the
+	// given time
+	PruneWorkers(ctx context.Context, before time.Time) error
 }
 
 // FunctionFilter represents function query filters
 type FunctionFilter struct {
 	Runtime *types.RuntimeType
+	Status  *types.FunctionStatus
 	Limit   int
 	Offset  int
 }
@@ -38,3 +105,9 @@ type InvocationFilter struct {
 	Limit      int
 	Offset     int
 }
+
+// OutcomeCounts summarizes the terminal invocations of a function version
+type OutcomeCounts struct {
+	Total  int // Invocations that reached a terminal status
+	Failed int // Of those, invocations that failed or timed out
+}
@@ -0,0 +1,610 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"GoFaas/pkg/errors"
+	"GoFaas/pkg/types"
+)
+
+// MemoryRepository implements the metadata repositories in process memory,
+// for tests and the single-process development binary. It enforces the
+// constraints of the PostgreSQL schema, including cascading deletes, and
+// hands out copies so callers cannot modify stored records in place. Data
+// is lost when the process exits.
+type MemoryRepository struct {
+	mu          sync.RWMutex
+	functions   map[string]*types.Function
+	invocations map[string]*types.Invocation
+	attempts    map[string][]*types.InvocationAttempt // By invocation ID
+	versions    map[string]*types.FunctionVersion     // By version ID
+	aliases     map[aliasKey]*types.FunctionAlias
+	schedules   map[string]*types.Schedule
+	deliveries  map[string][]*types.CallbackDelivery // By invocation ID
+	workers     map[string]*types.Worker
+}
+
+// aliasKey identifies a function alias
+type aliasKey struct {
+	functionName, name string
+}
+
+// NewMemoryRepository creates a new in-memory repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		functions:   make(map[string]*types.Function),
+		invocations: make(map[string]*types.Invocation),
+		attempts:    make(map[string][]*types.InvocationAttempt),
+		versions:    make(map[string]*types.FunctionVersion),
+		aliases:     make(map[aliasKey]*types.FunctionAlias),
+		schedules:   make(map[string]*types.Schedule),
+		deliveries:  make(map[string][]*types.CallbackDelivery),
+		workers:     make(map[string]*types.Worker),
+	}
+}
+
+// Create implements FunctionRepository.Create
+func (r *MemoryRepository) Create(ctx context.Context, fn *types.Function) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.functions[fn.ID]; ok {
+		return errors.InternalError(fmt.Sprintf("failed to create function: duplicate id %s", fn.ID))
+	}
+	for _, existing := range r.functions {
+		if existing.Name == fn.Name && existing.Version == fn.Version {
+			return errors.Conflict(fmt.Sprintf("function %s version %s already exists", fn.Name, fn.Version))
+		}
+	}
+
+	r.functions[fn.ID] = cloneFunction(fn)
+	return nil
+}
+
+// GetByID implements FunctionRepository.GetByID
+func (r *MemoryRepository) GetByID(ctx context.Context, id string) (*types.Function, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	fn, ok := r.functions[id]
+	if !ok {
+		return nil, errors.NotFound("function", id)
+	}
+
+	return cloneFunction(fn), nil
+}
+
+// GetByName implements FunctionRepository.GetByName
+func (r *MemoryRepository) GetByName(ctx context.Context, name, version string) (*types.Function, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, fn := range r.functions {
+		if fn.Name == name && fn.Version == version {
+			return cloneFunction(fn), nil
+		}
+	}
+
+	return nil, errors.NotFound("function", fmt.Sprintf("%s:%s", name, version))
+}
+
+// Update implements FunctionRepository.Update. As with PostgreSQL, the
+// build state is only written when fn carries a new build.
+func (r *MemoryRepository) Update(ctx context.Context, fn *types.Function) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.functions[fn.ID]
+	if !ok {
+		return errors.NotFound("function", fn.ID)
+	}
+
+	updated := cloneFunction(fn)
+	updated.Name = stored.Name
+	updated.Version = stored.Version
+	updated.Runtime = stored.Runtime
+	updated.CreatedAt = stored.CreatedAt
+
+	if sameTime(buildStartedAt(stored.Build), buildStartedAt(fn.Build)) {
+		updated.Status = stored.Status
+		updated.Code.Artifact = stored.Code.Artifact
+		updated.Build = cloneBuild(stored.Build)
+	}
+
+	r.functions[fn.ID] = updated
+	return nil
+}
+
+// CompleteBuild implements FunctionRepository.CompleteBuild, storing the
+// build result only if the function still has the code and handler that
+// were built
+func (r *MemoryRepository) CompleteBuild(ctx context.Context, fn *types.Function) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.functions[fn.ID]
+	if !ok || stored.Code.Checksum != fn.Code.Checksum || stored.Handler != fn.Handler {
+		return false, nil
+	}
+
+	stored.Status = fn.Status
+	stored.Code.Artifact = fn.Code.Artifact
+	stored.Build = cloneBuild(fn.Build)
+	stored.UpdatedAt = fn.UpdatedAt
+
+	return true, nil
+}
+
+// RestartBuild implements FunctionRepository.RestartBuild
+func (r *MemoryRepository) RestartBuild(ctx context.Context, fn *types.Function, staleStart time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.functions[fn.ID]
+	if !ok || stored.Status != types.FunctionBuilding || stored.Build == nil ||
+		stored.Build.StartedAt == nil || !stored.Build.StartedAt.Equal(staleStart) {
+		return false, nil
+	}
+
+	stored.Status = fn.Status
+	stored.Code.Artifact = ""
+	stored.Build = cloneBuild(fn.Build)
+	stored.UpdatedAt = fn.UpdatedAt
+
+	return true, nil
+}
+
+// Delete implements FunctionRepository.Delete. The function's invocations,
+// versions and schedules are deleted with it.
+func (r *MemoryRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.functions[id]; !ok {
+		return errors.NotFound("function", id)
+	}
+	delete(r.functions, id)
+
+	for invID, inv := range r.invocations {
+		if inv.FunctionID == id {
+			r.deleteInvocation(invID)
+		}
+	}
+	for versionID, v := range r.versions {
+		if v.FunctionID == id {
+			r.deleteVersion(versionID)
+		}
+	}
+	for scheduleID, s := range r.schedules {
+		if s.FunctionID == id {
+			delete(r.schedules, scheduleID)
+		}
+	}
+
+	return nil
+}
+
+// List implements FunctionRepository.List, newest first
+func (r *MemoryRepository) List(ctx context.Context, filter FunctionFilter) ([]*types.Function, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	functions := make([]*types.Function, 0)
+	for _, fn := range r.functions {
+		if filter.Runtime != nil && fn.Runtime != *filter.Runtime {
+			continue
+		}
+		if filter.Status != nil && fn.Status != *filter.Status {
+			continue
+		}
+		functions = append(functions, cloneFunction(fn))
+	}
+
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].CreatedAt.After(functions[j].CreatedAt)
+	})
+	lo, hi := page(len(functions), filter.Limit, filter.Offset)
+
+	return functions[lo:hi], nil
+}
+
+// ListPlacements implements FunctionRepository.ListPlacements, returning
+// each distinct placement of a function, published version or unfinished
+// invocation once
+func (r *MemoryRepository) ListPlacements(ctx context.Context) ([]*types.Placement, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	all := make([]*types.Placement, 0, len(r.functions)+len(r.versions))
+	for _, fn := range r.functions {
+		all = append(all, fn.Config.Placement)
+	}
+	for _, v := range r.versions {
+		all = append(all, v.Config.Placement)
+	}
+	for _, inv := range r.invocations {
+		if !inv.Status.IsTerminal() {
+			all = append(all, inv.Placement)
+		}
+	}
+
+	placements := make([]*types.Placement, 0)
+	seen := make(map[string]bool)
+	for _, placement := range all {
+		key := placement.Key()
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		placements = append(placements, clonePlacement(placement))
+	}
+
+	return placements, nil
+}
+
+// CreateInvocation creates a new invocation record
+func (r *MemoryRepository) CreateInvocation(ctx context.Context, inv *types.Invocation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.invocations[inv.ID]; ok {
+		return errors.InternalError(fmt.Sprintf("failed to create invocation: duplicate id %s", inv.ID))
+	}
+	if _, ok := r.functions[inv.FunctionID]; !ok {
+		return errors.InternalError(fmt.Sprintf("failed to create invocation: function %s does not exist", inv.FunctionID))
+	}
+	if inv.IdempotencyKey != "" {
+		for _, existing := range r.invocations {
+			if existing.FunctionID == inv.FunctionID && existing.IdempotencyKey == inv.IdempotencyKey {
+				return errors.Conflict(fmt.Sprintf("idempotency key %s is already in use", inv.IdempotencyKey))
+			}
+		}
+	}
+
+	stored := cloneInvocation(inv)
+	stored.Result = nil
+	stored.Error = nil
+	stored.Metrics = nil
+	stored.Logs = nil
+	stored.StartedAt = nil
+	stored.CompletedAt = nil
+
+	r.invocations[inv.ID] = stored
+	return nil
+}
+
+// GetInvocationByID retrieves an invocation by ID
+func (r *MemoryRepository) GetInvocationByID(ctx context.Context, id string) (*types.Invocation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inv, ok := r.invocations[id]
+	if !ok {
+		return nil, errors.NotFound("invocation", id)
+	}
+
+	return cloneInvocation(inv), nil
+}
+
+// GetInvocationByIdempotencyKey implements
+// InvocationRepository.GetInvocationByIdempotencyKey
+func (r *MemoryRepository) GetInvocationByIdempotencyKey(ctx context.Context, functionID, key string) (*types.Invocation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, inv := range r.invocations {
+		if inv.FunctionID == functionID && inv.IdempotencyKey == key && key != "" {
+			return cloneInvocation(inv), nil
+		}
+	}
+
+	return nil, errors.NotFound("idempotency key", key)
+}
+
+// ReleaseIdempotencyKey implements InvocationRepository.ReleaseIdempotencyKey
+func (r *MemoryRepository) ReleaseIdempotencyKey(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if inv, ok := r.invocations[id]; ok {
+		inv.IdempotencyKey = ""
+		inv.IdempotencyHash = ""
+	}
+
+	return nil
+}
+
+// UpdateInvocation updates the outcome of an invocation. Cancelled
+// invocations are final: updating one returns a Conflict error.
+func (r *MemoryRepository) UpdateInvocation(ctx context.Context, inv *types.Invocation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.invocations[inv.ID]
+	if !ok {
+		return errors.NotFound("invocation", inv.ID)
+	}
+	if stored.Status == types.StatusCancelled {
+		return errors.Conflict(fmt.Sprintf("invocation %s was cancelled", inv.ID))
+	}
+
+	update := cloneInvocation(inv)
+	stored.Status = update.Status
+	stored.Result = update.Result
+	stored.Error = update.Error
+	stored.Metrics = update.Metrics
+	stored.StartedAt = update.StartedAt
+	stored.CompletedAt = update.CompletedAt
+	stored.Logs = update.Logs
+
+	return nil
+}
+
+// CancelInvocation implements InvocationRepository.CancelInvocation,
+// cancelling the invocation only if it has not reached a terminal status
+func (r *MemoryRepository) CancelInvocation(ctx context.Context, id string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inv, ok := r.invocations[id]
+	if !ok || inv.Status.IsTerminal() {
+		return false, nil
+	}
+
+	inv.Status = types.StatusCancelled
+	inv.CompletedAt = &at
+	return true, nil
+}
+
+// RetryInvocation implements InvocationRepository.RetryInvocation. A
+// cancelled invocation is not retried.
+func (r *MemoryRepository) RetryInvocation(ctx context.Context, id string, runAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	inv, ok := r.invocations[id]
+	if !ok || inv.Status == types.StatusCancelled {
+		return false, nil
+	}
+
+	inv.Status = types.StatusDelayed
+	inv.RunAt = &runAt
+	return true, nil
+}
+
+// CreateAttempt implements InvocationRepository.CreateAttempt
+func (r *MemoryRepository) CreateAttempt(ctx context.Context, a *types.InvocationAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.invocations[a.InvocationID]; !ok {
+		return errors.InternalError(fmt.Sprintf("failed to create invocation attempt: invocation %s does not exist", a.InvocationID))
+	}
+
+	r.attempts[a.InvocationID] = append(r.attempts[a.InvocationID], cloneAttempt(a))
+	return nil
+}
+
+// ListAttempts implements InvocationRepository.ListAttempts, returning the
+// attempts of an invocation in order
+func (r *MemoryRepository) ListAttempts(ctx context.Context, invocationID string) ([]*types.InvocationAttempt, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	attempts := make([]*types.InvocationAttempt, 0, len(r.attempts[invocationID]))
+	for _, a := range r.attempts[invocationID] {
+		attempts = append(attempts, cloneAttempt(a))
+	}
+
+	sort.SliceStable(attempts, func(i, j int) bool {
+		if attempts[i].Attempt != attempts[j].Attempt {
+			return attempts[i].Attempt < attempts[j].Attempt
+		}
+		return attempts[i].StartedAt.Before(attempts[j].StartedAt)
+	})
+
+	return attempts, nil
+}
+
+// ListInvocations lists invocations with filters, newest first
+func (r *MemoryRepository) ListInvocations(ctx context.Context, filter InvocationFilter) ([]*types.Invocation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	invocations := make([]*types.Invocation, 0)
+	for _, inv := range r.invocations {
+		if filter.FunctionID != nil && inv.FunctionID != *filter.FunctionID {
+			continue
+		}
+		if filter.Status != nil && inv.Status != *filter.Status {
+			continue
+		}
+		invocations = append(invocations, cloneInvocation(inv))
+	}
+
+	sort.Slice(invocations, func(i, j int) bool {
+		return invocations[i].CreatedAt.After(invocations[j].CreatedAt)
+	})
+	lo, hi := page(len(invocations), filter.Limit, filter.Offset)
+
+	return invocations[lo:hi], nil
+}
+
+// CountVersionOutcomes implements InvocationRepository.CountVersionOutcomes
+func (r *MemoryRepository) CountVersionOutcomes(ctx context.Context, functionID string, version int, since time.Time) (*OutcomeCounts, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var counts OutcomeCounts
+	for _, inv := range r.invocations {
+		if inv.FunctionID != functionID || inv.FunctionVersion != version || inv.CreatedAt.Before(since) {
+			continue
+		}
+
+		switch inv.Status {
+		case types.StatusCompleted:
+			counts.Total++
+		case types.StatusFailed, types.StatusTimeout:
+			counts.Total++
+			counts.Failed++
+		}
+	}
+
+	return &counts, nil
+}
+
+// deleteInvocation deletes an invocation with its attempts and callback
+// deliveries, and unlinks replays of it. The caller must hold r.mu.
+func (r *MemoryRepository) deleteInvocation(id string) {
+	delete(r.invocations, id)
+	delete(r.attempts, id)
+	delete(r.deliveries, id)
+
+	for _, inv := range r.invocations {
+		if inv.ReplayOf == id {
+			inv.ReplayOf = ""
+		}
+	}
+}
+
+// page returns the bounds of the page selected by limit and offset in a
+// list of n records
+func page(n, limit, offset int) (int, int) {
+	if offset > n {
+		offset = n
+	}
+	end := n
+	if limit > 0 && offset+limit < n {
+		end = offset + limit
+	}
+	return offset, end
+}
+
+// sameTime reports whether two optional times are both unset or equal
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// buildStartedAt returns the start time of a build, if there is one
+func buildStartedAt(build *types.BuildInfo) *time.Time {
+	if build == nil {
+		return nil
+	}
+	return build.StartedAt
+}
+
+// cloneFunction copies a function and the records it points to
+func cloneFunction(fn *types.Function) *types.Function {
+	clone := *fn
+	clone.Config = cloneConfig(fn.Config)
+	clone.Metadata = cloneStrings(fn.Metadata)
+	clone.Build = cloneBuild(fn.Build)
+	return &clone
+}
+
+// cloneConfig copies a function configuration
+func cloneConfig(config types.FunctionConfig) types.FunctionConfig {
+	config.Environment = cloneStrings(config.Environment)
+	if config.Retry != nil {
+		retry := *config.Retry
+		retry.RetryOn = append([]string(nil), config.Retry.RetryOn...)
+		config.Retry = &retry
+	}
+	config.Placement = clonePlacement(config.Placement)
+	return config
+}
+
+// clonePlacement copies a placement
+func clonePlacement(p *types.Placement) *types.Placement {
+	if p == nil {
+		return nil
+	}
+	clone := *p
+	clone.Labels = cloneStrings(p.Labels)
+	return &clone
+}
+
+// cloneBuild copies build info
+func cloneBuild(build *types.BuildInfo) *types.BuildInfo {
+	if build == nil {
+		return nil
+	}
+	clone := *build
+	clone.StartedAt = cloneTime(build.StartedAt)
+	clone.CompletedAt = cloneTime(build.CompletedAt)
+	return &clone
+}
+
+// cloneInvocation copies an invocation and the records it points to
+func cloneInvocation(inv *types.Invocation) *types.Invocation {
+	clone := *inv
+	clone.Payload = cloneBytes(inv.Payload)
+	clone.Headers = cloneStrings(inv.Headers)
+	clone.Result = cloneBytes(inv.Result)
+	clone.Error = cloneError(inv.Error)
+	if inv.Metrics != nil {
+		metrics := *inv.Metrics
+		clone.Metrics = &metrics
+	}
+	if inv.Logs != nil {
+		clone.Logs = append([]types.LogEntry(nil), inv.Logs...)
+	}
+	clone.Placement = clonePlacement(inv.Placement)
+	clone.RunAt = cloneTime(inv.RunAt)
+	clone.StartedAt = cloneTime(inv.StartedAt)
+	clone.CompletedAt = cloneTime(inv.CompletedAt)
+	return &clone
+}
+
+// cloneAttempt copies an invocation attempt
+func cloneAttempt(a *types.InvocationAttempt) *types.InvocationAttempt {
+	clone := *a
+	clone.Error = cloneError(a.Error)
+	clone.RetryAt = cloneTime(a.RetryAt)
+	return &clone
+}
+
+// cloneError copies an execution error
+func cloneError(err *types.ExecutionError) *types.ExecutionError {
+	if err == nil {
+		return nil
+	}
+	clone := *err
+	return &clone
+}
+
+// cloneStrings copies a string map, keeping nil as nil
+func cloneStrings(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[string]string, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+// cloneBytes copies a byte slice, keeping nil as nil
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+// cloneTime copies an optional time
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	clone := *t
+	return &clone
+}
@@ -0,0 +1,51 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"GoFaas/pkg/errors"
+	"GoFaas/pkg/types"
+)
+
+// CreateCallbackDelivery implements CallbackRepository.CreateCallbackDelivery
+func (r *MemoryRepository) CreateCallbackDelivery(ctx context.Context, d *types.CallbackDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.invocations[d.InvocationID]; !ok {
+		return errors.InternalError(fmt.Sprintf("failed to create callback delivery: invocation %s does not exist", d.InvocationID))
+	}
+
+	r.deliveries[d.InvocationID] = append(r.deliveries[d.InvocationID], cloneDelivery(d))
+	return nil
+}
+
+// ListCallbackDeliveries implements CallbackRepository.ListCallbackDeliveries,
+// returning the delivery attempts of an invocation in order
+func (r *MemoryRepository) ListCallbackDeliveries(ctx context.Context, invocationID string) ([]*types.CallbackDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := make([]*types.CallbackDelivery, 0, len(r.deliveries[invocationID]))
+	for _, d := range r.deliveries[invocationID] {
+		deliveries = append(deliveries, cloneDelivery(d))
+	}
+
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		if deliveries[i].Attempt != deliveries[j].Attempt {
+			return deliveries[i].Attempt < deliveries[j].Attempt
+		}
+		return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt)
+	})
+
+	return deliveries, nil
+}
+
+// cloneDelivery copies a callback delivery
+func cloneDelivery(d *types.CallbackDelivery) *types.CallbackDelivery {
+	clone := *d
+	clone.NextAttemptAt = cloneTime(d.NextAttemptAt)
+	return &clone
+}
@@ -0,0 +1,164 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"GoFaas/pkg/errors"
+	"GoFaas/pkg/types"
+)
+
+// CreateSchedule implements ScheduleRepository.CreateSchedule
+func (r *MemoryRepository) CreateSchedule(ctx context.Context, s *types.Schedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.functions[s.FunctionID]; !ok {
+		return errors.InternalError(fmt.Sprintf("failed to create schedule: function %s does not exist", s.FunctionID))
+	}
+
+	stored := cloneSchedule(s)
+	stored.LastRunAt = nil
+	stored.LastInvocationID = ""
+	stored.LastError = ""
+
+	r.schedules[s.ID] = stored
+	return nil
+}
+
+// GetSchedule implements ScheduleRepository.GetSchedule
+func (r *MemoryRepository) GetSchedule(ctx context.Context, id string) (*types.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.schedules[id]
+	if !ok {
+		return nil, errors.NotFound("schedule", id)
+	}
+
+	return cloneSchedule(s), nil
+}
+
+// ListSchedules implements ScheduleRepository.ListSchedules
+func (r *MemoryRepository) ListSchedules(ctx context.Context, functionID string) ([]*types.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schedules := make([]*types.Schedule, 0)
+	for _, s := range r.schedules {
+		if s.FunctionID == functionID {
+			schedules = append(schedules, cloneSchedule(s))
+		}
+	}
+
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+	})
+
+	return schedules, nil
+}
+
+// UpdateSchedule implements ScheduleRepository.UpdateSchedule
+func (r *MemoryRepository) UpdateSchedule(ctx context.Context, s *types.Schedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.schedules[s.ID]
+	if !ok {
+		return errors.NotFound("schedule", s.ID)
+	}
+
+	update := cloneSchedule(s)
+	stored.Cron = update.Cron
+	stored.Timezone = update.Timezone
+	stored.Payload = update.Payload
+	stored.Enabled = update.Enabled
+	stored.NextRunAt = update.NextRunAt
+	stored.UpdatedAt = update.UpdatedAt
+
+	return nil
+}
+
+// DeleteSchedule implements ScheduleRepository.DeleteSchedule
+func (r *MemoryRepository) DeleteSchedule(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.schedules[id]; !ok {
+		return errors.NotFound("schedule", id)
+	}
+
+	delete(r.schedules, id)
+	return nil
+}
+
+// ListDueSchedules implements ScheduleRepository.ListDueSchedules,
+// returning enabled schedules whose next run is at or before now, oldest
+// first
+func (r *MemoryRepository) ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]*types.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	schedules := make([]*types.Schedule, 0)
+	for _, s := range r.schedules {
+		if s.Enabled && s.NextRunAt != nil && !s.NextRunAt.After(now) {
+			schedules = append(schedules, cloneSchedule(s))
+		}
+	}
+
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].NextRunAt.Before(*schedules[j].NextRunAt)
+	})
+	if limit > 0 && len(schedules) > limit {
+		schedules = schedules[:limit]
+	}
+
+	return schedules, nil
+}
+
+// ClaimScheduleRun implements ScheduleRepository.ClaimScheduleRun. The
+// schedule is moved to its next run only if it is still due at the time
+// the caller read. A zero next time leaves the schedule without further
+// runs.
+func (r *MemoryRepository) ClaimScheduleRun(ctx context.Context, id string, due, next time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.schedules[id]
+	if !ok || !s.Enabled || s.NextRunAt == nil || !s.NextRunAt.Equal(due) {
+		return false, nil
+	}
+
+	s.LastRunAt = &due
+	s.NextRunAt = nil
+	if !next.IsZero() {
+		s.NextRunAt = &next
+	}
+
+	return true, nil
+}
+
+// RecordScheduleRun implements ScheduleRepository.RecordScheduleRun,
+// storing the outcome of the last claimed run
+func (r *MemoryRepository) RecordScheduleRun(ctx context.Context, id, invocationID, runError string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s, ok := r.schedules[id]; ok {
+		s.LastInvocationID = invocationID
+		s.LastError = runError
+	}
+
+	return nil
+}
+
+// cloneSchedule copies a schedule
+func cloneSchedule(s *types.Schedule) *types.Schedule {
+	clone := *s
+	clone.Payload = cloneBytes(s.Payload)
+	clone.NextRunAt = cloneTime(s.NextRunAt)
+	clone.LastRunAt = cloneTime(s.LastRunAt)
+	return &clone
+}
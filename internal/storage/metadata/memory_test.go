@@ -0,0 +1,512 @@
+package metadata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"GoFaas/pkg/errors"
+	"GoFaas/pkg/types"
+)
+
+// newTestFunction returns a ready function with the given ID and name
+func newTestFunction(id, name string) *types.Function {
+	now := time.Now()
+	return &types.Function{
+		ID:       id,
+		Name:     name,
+		Version:  "1.0.0",
+		Runtime:  types.RuntimePython,
+		Handler:  "main.handler",
+		Code:     types.FunctionCode{Source: "code/" + id, Checksum: "sum-1"},
+		Config:   types.FunctionConfig{Timeout: time.Second, Memory: 128, Concurrency: 1},
+		Metadata: map[string]string{"team": "a"},
+		Status:   types.FunctionReady,
+
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// errorCode returns the code of an application error, or "" for others
+func errorCode(err error) errors.ErrorCode {
+	if appErr, ok := err.(*errors.AppError); ok {
+		return appErr.Code
+	}
+	return ""
+}
+
+func TestMemoryFunctionCreate(t *testing.T) {
+	tests := []struct {
+		name     string
+		fn       *types.Function
+		wantCode errors.ErrorCode
+	}{
+		{name: "new function", fn: newTestFunction("f2", "other")},
+		{name: "same name and version", fn: newTestFunction("f2", "hello"), wantCode: errors.ErrCodeConflict},
+		{name: "duplicate id", fn: newTestFunction("f1", "other"), wantCode: errors.ErrCodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			r := NewMemoryRepository()
+			if err := r.Create(ctx, newTestFunction("f1", "hello")); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			err := r.Create(ctx, tt.fn)
+			if got := errorCode(err); got != tt.wantCode || (tt.wantCode == "" && err != nil) {
+				t.Fatalf("Create = %v, want code %q", err, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestMemoryFunctionIsolation(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryRepository()
+
+	fn := newTestFunction("f1", "hello")
+	if err := r.Create(ctx, fn); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Changes to the caller's copies must not reach the stored function
+	fn.Metadata["team"] = "changed"
+	got, err := r.GetByID(ctx, "f1")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	got.Metadata["team"] = "changed too"
+
+	again, _ := r.GetByID(ctx, "f1")
+	if again.Metadata["team"] != "a" {
+		t.Errorf("stored metadata = %q, want it unaffected by callers", again.Metadata["team"])
+	}
+
+	if _, err := r.GetByName(ctx, "hello", "1.0.0"); err != nil {
+		t.Errorf("GetByName: %v", err)
+	}
+	if _, err := r.GetByID(ctx, "missing"); errorCode(err) != errors.ErrCodeNotFound {
+		t.Errorf("GetByID(missing) = %v, want NotFound", err)
+	}
+}
+
+func TestMemoryFunctionUpdateKeepsFinishedBuild(t *testing.T) {
+	started := time.Now().Add(-time.Minute)
+	completed := time.Now()
+
+	tests := []struct {
+		name       string
+		build      *types.BuildInfo // Build carried by the update
+		wantStatus types.FunctionStatus
+	}{
+		{
+			name:       "same build keeps the stored result",
+			build:      &types.BuildInfo{StartedAt: &started},
+			wantStatus: types.FunctionReady,
+		},
+		{
+			name:       "new build replaces it",
+			build:      &types.BuildInfo{StartedAt: &completed},
+			wantStatus: types.FunctionBuilding,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			r := NewMemoryRepository()
+
+			fn := newTestFunction("f1", "hello")
+			fn.Runtime = types.RuntimeGo
+			fn.Status = types.FunctionBuilding
+			fn.Build = &types.BuildInfo{StartedAt: &started}
+			if err := r.Create(ctx, fn); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			// The build finishes while an update is in progress
+			done := *fn
+			done.Status = types.FunctionReady
+			done.Code.Artifact = "artifact"
+			done.Build = &types.BuildInfo{StartedAt: &started, CompletedAt: &completed}
+			if applied, err := r.CompleteBuild(ctx, &done); err != nil || !applied {
+				t.Fatalf("CompleteBuild = %v, %v; want applied", applied, err)
+			}
+
+			update := *fn
+			update.Status = types.FunctionBuilding
+			update.Build = tt.build
+			update.Config.Memory = 256
+			if err := r.Update(ctx, &update); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+
+			got, _ := r.GetByID(ctx, "f1")
+			if got.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if got.Config.Memory != 256 {
+				t.Errorf("Memory = %d, want the update applied", got.Config.Memory)
+			}
+		})
+	}
+}
+
+func TestMemoryFunctionBuildGuards(t *testing.T) {
+	started := time.Now().Add(-time.Hour)
+	other := started.Add(time.Second)
+
+	tests := []struct {
+		name string
+		run  func(context.Context, *MemoryRepository, types.Function) (bool, error)
+		want bool
+	}{
+		{
+			name: "complete build of the current code",
+			run: func(ctx context.Context, r *MemoryRepository, fn types.Function) (bool, error) {
+				fn.Status = types.FunctionReady
+				return r.CompleteBuild(ctx, &fn)
+			},
+			want: true,
+		},
+		{
+			name: "complete build of replaced code",
+			run: func(ctx context.Context, r *MemoryRepository, fn types.Function) (bool, error) {
+				fn.Code.Checksum = "sum-old"
+				fn.Status = types.FunctionReady
+				return r.CompleteBuild(ctx, &fn)
+			},
+		},
+		{
+			name: "restart the stale build",
+			run: func(ctx context.Context, r *MemoryRepository, fn types.Function) (bool, error) {
+				now := time.Now()
+				fn.Build = &types.BuildInfo{StartedAt: &now}
+				return r.RestartBuild(ctx, &fn, started)
+			},
+			want: true,
+		},
+		{
+			name: "restart a build that was already taken over",
+			run: func(ctx context.Context, r *MemoryRepository, fn types.Function) (bool, error) {
+				now := time.Now()
+				fn.Build = &types.BuildInfo{StartedAt: &now}
+				return r.RestartBuild(ctx, &fn, other)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			r := NewMemoryRepository()
+
+			fn := newTestFunction("f1", "hello")
+			fn.Status = types.FunctionBuilding
+			fn.Build = &types.BuildInfo{StartedAt: &started}
+			if err := r.Create(ctx, fn); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			got, err := tt.run(ctx, r, *fn)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("applied = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryFunctionList(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryRepository()
+
+	for i, spec := range []struct {
+		id      string
+		runtime types.RuntimeType
+		status  types.FunctionStatus
+	}{
+		{"a", types.RuntimePython, types.FunctionReady},
+		{"b", types.RuntimeGo, types.FunctionBuilding},
+		{"c", types.RuntimeGo, types.FunctionReady},
+	} {
+		fn := newTestFunction(spec.id, spec.id)
+		fn.Runtime = spec.runtime
+		fn.Status = spec.status
+		fn.CreatedAt = time.Now().Add(time.Duration(i) * time.Second)
+		if err := r.Create(ctx, fn); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	goRuntime := types.RuntimeGo
+	building := types.FunctionBuilding
+
+	tests := []struct {
+		name   string
+		filter FunctionFilter
+		want   []string
+	}{
+		{name: "all, newest first", want: []string{"c", "b", "a"}},
+		{name: "by runtime", filter: FunctionFilter{Runtime: &goRuntime}, want: []string{"c", "b"}},
+		{name: "by status", filter: FunctionFilter{Status: &building}, want: []string{"b"}},
+		{name: "paged", filter: FunctionFilter{Limit: 1, Offset: 1}, want: []string{"b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fns, err := r.List(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+
+			ids := make([]string, len(fns))
+			for i, fn := range fns {
+				ids[i] = fn.ID
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("List = %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("List = %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryFunctionDeleteCascades(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryRepository()
+
+	if err := r.Create(ctx, newTestFunction("f1", "hello")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := r.CreateInvocation(ctx, &types.Invocation{ID: "i1", FunctionID: "f1", Status: types.StatusPending}); err != nil {
+		t.Fatalf("CreateInvocation: %v", err)
+	}
+	v := &types.FunctionVersion{ID: "v1", FunctionID: "f1", FunctionName: "hello"}
+	if err := r.CreateVersion(ctx, v); err != nil {
+		t.Fatalf("CreateVersion: %v", err)
+	}
+
+	if err := r.Delete(ctx, "f1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if _, err := r.GetInvocationByID(ctx, "i1"); errorCode(err) != errors.ErrCodeNotFound {
+		t.Errorf("invocation after delete: %v, want NotFound", err)
+	}
+	if _, err := r.GetVersionByID(ctx, "v1"); errorCode(err) != errors.ErrCodeNotFound {
+		t.Errorf("version after delete: %v, want NotFound", err)
+	}
+	if err := r.Delete(ctx, "f1"); errorCode(err) != errors.ErrCodeNotFound {
+		t.Errorf("second Delete = %v, want NotFound", err)
+	}
+}
+
+func TestMemoryVersionNumbers(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryRepository()
+
+	if err := r.Create(ctx, newTestFunction("f1", "hello")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	for want := 1; want <= 3; want++ {
+		v := &types.FunctionVersion{ID: "v" + string(rune('0'+want)), FunctionID: "f1", FunctionName: "hello"}
+		if err := r.CreateVersion(ctx, v); err != nil {
+			t.Fatalf("CreateVersion: %v", err)
+		}
+		if v.Version != want {
+			t.Errorf("Version = %d, want %d", v.Version, want)
+		}
+	}
+
+	got, err := r.GetVersion(ctx, "hello", 2)
+	if err != nil || got.ID != "v2" {
+		t.Errorf("GetVersion(hello, 2) = %v, %v; want v2", got, err)
+	}
+}
+
+func TestMemoryInvocationIdempotencyKey(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryRepository()
+
+	if err := r.Create(ctx, newTestFunction("f1", "hello")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	first := &types.Invocation{ID: "i1", FunctionID: "f1", Status: types.StatusPending, IdempotencyKey: "key"}
+	if err := r.CreateInvocation(ctx, first); err != nil {
+		t.Fatalf("CreateInvocation: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		release  bool
+		wantCode errors.ErrorCode
+	}{
+		{name: "key in use", wantCode: errors.ErrCodeConflict},
+		{name: "released key", release: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.release {
+				if err := r.ReleaseIdempotencyKey(ctx, "i1"); err != nil {
+					t.Fatalf("ReleaseIdempotencyKey: %v", err)
+				}
+			}
+
+			inv := &types.Invocation{ID: "i" + string(rune('2'+i)), FunctionID: "f1", Status: types.StatusPending, IdempotencyKey: "key"}
+			err := r.CreateInvocation(ctx, inv)
+			if got := errorCode(err); got != tt.wantCode || (tt.wantCode == "" && err != nil) {
+				t.Fatalf("CreateInvocation = %v, want code %q", err, tt.wantCode)
+			}
+		})
+	}
+
+	got, err := r.GetInvocationByIdempotencyKey(ctx, "f1", "key")
+	if err != nil || got.ID == "i1" {
+		t.Errorf("GetInvocationByIdempotencyKey = %v, %v; want the invocation created after the release", got, err)
+	}
+}
+
+func TestMemoryInvocationTransitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     types.ExecutionStatus
+		run        func(context.Context, *MemoryRepository) (bool, error)
+		want       bool
+		wantStatus types.ExecutionStatus
+	}{
+		{
+			name:   "cancel a pending invocation",
+			status: types.StatusPending,
+			run: func(ctx context.Context, r *MemoryRepository) (bool, error) {
+				return r.CancelInvocation(ctx, "i1", time.Now())
+			},
+			want:       true,
+			wantStatus: types.StatusCancelled,
+		},
+		{
+			name:   "cancel a finished invocation",
+			status: types.StatusCompleted,
+			run: func(ctx context.Context, r *MemoryRepository) (bool, error) {
+				return r.CancelInvocation(ctx, "i1", time.Now())
+			},
+			wantStatus: types.StatusCompleted,
+		},
+		{
+			name:   "retry a running invocation",
+			status: types.StatusRunning,
+			run: func(ctx context.Context, r *MemoryRepository) (bool, error) {
+				return r.RetryInvocation(ctx, "i1", time.Now().Add(time.Second))
+			},
+			want:       true,
+			wantStatus: types.StatusDelayed,
+		},
+		{
+			name:   "retry a cancelled invocation",
+			status: types.StatusCancelled,
+			run: func(ctx context.Context, r *MemoryRepository) (bool, error) {
+				return r.RetryInvocation(ctx, "i1", time.Now().Add(time.Second))
+			},
+			wantStatus: types.StatusCancelled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			r := NewMemoryRepository()
+			if err := r.Create(ctx, newTestFunction("f1", "hello")); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if err := r.CreateInvocation(ctx, &types.Invocation{ID: "i1", FunctionID: "f1", Status: tt.status}); err != nil {
+				t.Fatalf("CreateInvocation: %v", err)
+			}
+
+			got, err := tt.run(ctx, r)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("applied = %v, want %v", got, tt.want)
+			}
+
+			inv, _ := r.GetInvocationByID(ctx, "i1")
+			if inv.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", inv.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestMemoryInvocationUpdateAfterCancel(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryRepository()
+
+	if err := r.Create(ctx, newTestFunction("f1", "hello")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	inv := &types.Invocation{ID: "i1", FunctionID: "f1", Status: types.StatusRunning}
+	if err := r.CreateInvocation(ctx, inv); err != nil {
+		t.Fatalf("CreateInvocation: %v", err)
+	}
+	if _, err := r.CancelInvocation(ctx, "i1", time.Now()); err != nil {
+		t.Fatalf("CancelInvocation: %v", err)
+	}
+
+	// A worker reporting its result late must not undo the cancellation
+	inv.Status = types.StatusCompleted
+	if err := r.UpdateInvocation(ctx, inv); errorCode(err) != errors.ErrCodeConflict {
+		t.Errorf("UpdateInvocation = %v, want Conflict", err)
+	}
+}
+
+func TestMemoryWorkerLifecycle(t *testing.T) {
+	ctx := context.Background()
+	r := NewMemoryRepository()
+
+	now := time.Now()
+	if err := r.RegisterWorker(ctx, &types.Worker{ID: "w1", StartedAt: now, LastHeartbeatAt: now}); err != nil {
+		t.Fatalf("RegisterWorker: %v", err)
+	}
+
+	steps := []struct {
+		name         string
+		drain        bool
+		wantDraining bool
+	}{
+		{name: "heartbeat"},
+		{name: "heartbeat after drain", drain: true, wantDraining: true},
+	}
+	for _, step := range steps {
+		if step.drain {
+			if err := r.DrainWorker(ctx, "w1"); err != nil {
+				t.Fatalf("DrainWorker: %v", err)
+			}
+		}
+		draining, err := r.HeartbeatWorker(ctx, "w1", 2, false, time.Now())
+		if err != nil {
+			t.Fatalf("%s: HeartbeatWorker: %v", step.name, err)
+		}
+		if draining != step.wantDraining {
+			t.Errorf("%s: draining = %v, want %v", step.name, draining, step.wantDraining)
+		}
+	}
+
+	if err := r.PruneWorkers(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("PruneWorkers: %v", err)
+	}
+	if _, err := r.GetWorker(ctx, "w1"); errorCode(err) != errors.ErrCodeNotFound {
+		t.Errorf("GetWorker after prune = %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,262 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"GoFaas/pkg/errors"
+	"GoFaas/pkg/types"
+)
+
+// CreateVersion implements VersionRepository.CreateVersion, assigning the
+// next version number for the function name to v.Version
+func (r *MemoryRepository) CreateVersion(ctx context.Context, v *types.FunctionVersion) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.versions[v.ID]; ok {
+		return errors.InternalError(fmt.Sprintf("failed to create function version: duplicate id %s", v.ID))
+	}
+	if _, ok := r.functions[v.FunctionID]; !ok {
+		return errors.InternalError(fmt.Sprintf("failed to create function version: function %s does not exist", v.FunctionID))
+	}
+
+	latest := 0
+	for _, existing := range r.versions {
+		if existing.FunctionName == v.FunctionName && existing.Version > latest {
+			latest = existing.Version
+		}
+	}
+
+	v.Version = latest + 1
+	r.versions[v.ID] = cloneVersion(v)
+	return nil
+}
+
+// GetVersion implements VersionRepository.GetVersion
+func (r *MemoryRepository) GetVersion(ctx context.Context, functionName string, version int) (*types.FunctionVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	v := r.findVersion(functionName, version)
+	if v == nil {
+		return nil, errors.NotFound("function version", fmt.Sprintf("%s:%d", functionName, version))
+	}
+
+	return cloneVersion(v), nil
+}
+
+// GetVersionByID implements VersionRepository.GetVersionByID
+func (r *MemoryRepository) GetVersionByID(ctx context.Context, id string) (*types.FunctionVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	v, ok := r.versions[id]
+	if !ok {
+		return nil, errors.NotFound("function version", id)
+	}
+
+	return cloneVersion(v), nil
+}
+
+// ListVersions implements VersionRepository.ListVersions, newest first
+func (r *MemoryRepository) ListVersions(ctx context.Context, functionName string) ([]*types.FunctionVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := make([]*types.FunctionVersion, 0)
+	for _, v := range r.versions {
+		if v.FunctionName == functionName {
+			versions = append(versions, cloneVersion(v))
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version > versions[j].Version
+	})
+
+	return versions, nil
+}
+
+// PutAlias implements VersionRepository.PutAlias. The alias's routing is
+// replaced as well, clearing any earlier rollback.
+func (r *MemoryRepository) PutAlias(ctx context.Context, alias *types.FunctionAlias) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.findVersion(alias.FunctionName, alias.Version) == nil {
+		return errors.NotFound("function version", fmt.Sprintf("%s:%d", alias.FunctionName, alias.Version))
+	}
+
+	stored := cloneAlias(alias)
+	if stored.Routing != nil {
+		if stored.Routing.Version == 0 {
+			stored.Routing = nil
+		} else if r.findVersion(alias.FunctionName, stored.Routing.Version) == nil {
+			return errors.NotFound("function version", fmt.Sprintf("%s:%d", alias.FunctionName, stored.Routing.Version))
+		} else {
+			stored.Routing.RolledBackAt = nil
+			stored.Routing.RollbackReason = ""
+		}
+	}
+
+	key := aliasKey{alias.FunctionName, alias.Name}
+	stored.CreatedAt = alias.UpdatedAt
+	if existing, ok := r.aliases[key]; ok {
+		stored.CreatedAt = existing.CreatedAt
+	}
+	r.aliases[key] = stored
+
+	alias.CreatedAt = stored.CreatedAt
+	alias.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// GetAlias implements VersionRepository.GetAlias
+func (r *MemoryRepository) GetAlias(ctx context.Context, functionName, name string) (*types.FunctionAlias, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	alias, ok := r.aliases[aliasKey{functionName, name}]
+	if !ok {
+		return nil, errors.NotFound("function alias", fmt.Sprintf("%s:%s", functionName, name))
+	}
+
+	return cloneAlias(alias), nil
+}
+
+// ListAliases implements VersionRepository.ListAliases
+func (r *MemoryRepository) ListAliases(ctx context.Context, functionName string) ([]*types.FunctionAlias, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	aliases := make([]*types.FunctionAlias, 0)
+	for key, alias := range r.aliases {
+		if key.functionName == functionName {
+			aliases = append(aliases, cloneAlias(alias))
+		}
+	}
+	sortAliases(aliases)
+
+	return aliases, nil
+}
+
+// DeleteAlias implements VersionRepository.DeleteAlias
+func (r *MemoryRepository) DeleteAlias(ctx context.Context, functionName, name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := aliasKey{functionName, name}
+	if _, ok := r.aliases[key]; !ok {
+		return errors.NotFound("function alias", fmt.Sprintf("%s:%s", functionName, name))
+	}
+
+	delete(r.aliases, key)
+	return nil
+}
+
+// ListRoutedAliases implements VersionRepository.ListRoutedAliases
+func (r *MemoryRepository) ListRoutedAliases(ctx context.Context) ([]*types.FunctionAlias, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	aliases := make([]*types.FunctionAlias, 0)
+	for _, alias := range r.aliases {
+		if alias.Routing != nil && alias.Routing.Weight > 0 {
+			aliases = append(aliases, cloneAlias(alias))
+		}
+	}
+	sortAliases(aliases)
+
+	return aliases, nil
+}
+
+// RollbackAliasRouting implements VersionRepository.RollbackAliasRouting.
+// The weight is only reset if the alias still routes to the version read by
+// the caller, so a rollback never undoes a newer PutAlias.
+func (r *MemoryRepository) RollbackAliasRouting(ctx context.Context, alias *types.FunctionAlias, reason string) (bool, error) {
+	if alias.Routing == nil {
+		return false, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.aliases[aliasKey{alias.FunctionName, alias.Name}]
+	if !ok || stored.Routing == nil || stored.Routing.Weight <= 0 ||
+		stored.Routing.Version != alias.Routing.Version ||
+		!stored.Routing.StartedAt.Equal(alias.Routing.StartedAt) {
+		return false, nil
+	}
+
+	now := time.Now()
+	stored.Routing.Weight = 0
+	stored.Routing.RolledBackAt = &now
+	stored.Routing.RollbackReason = reason
+	stored.UpdatedAt = now
+
+	rolledBackAt := now
+	alias.Routing.Weight = 0
+	alias.Routing.RolledBackAt = &rolledBackAt
+	alias.Routing.RollbackReason = reason
+	alias.UpdatedAt = now
+
+	return true, nil
+}
+
+// findVersion returns the stored version of a function name, or nil. The
+// caller must hold r.mu.
+func (r *MemoryRepository) findVersion(functionName string, version int) *types.FunctionVersion {
+	for _, v := range r.versions {
+		if v.FunctionName == functionName && v.Version == version {
+			return v
+		}
+	}
+	return nil
+}
+
+// deleteVersion deletes a version along with the aliases pointing or
+// routing to it. The caller must hold r.mu.
+func (r *MemoryRepository) deleteVersion(id string) {
+	v := r.versions[id]
+	delete(r.versions, id)
+
+	for key, alias := range r.aliases {
+		if key.functionName != v.FunctionName {
+			continue
+		}
+		if alias.Version == v.Version || (alias.Routing != nil && alias.Routing.Version == v.Version) {
+			delete(r.aliases, key)
+		}
+	}
+}
+
+// sortAliases orders aliases by function name, then name
+func sortAliases(aliases []*types.FunctionAlias) {
+	sort.Slice(aliases, func(i, j int) bool {
+		if aliases[i].FunctionName != aliases[j].FunctionName {
+			return aliases[i].FunctionName < aliases[j].FunctionName
+		}
+		return aliases[i].Name < aliases[j].Name
+	})
+}
+
+// cloneVersion copies a function version
+func cloneVersion(v *types.FunctionVersion) *types.FunctionVersion {
+	clone := *v
+	clone.Config = cloneConfig(v.Config)
+	return &clone
+}
+
+// cloneAlias copies a function alias and its routing
+func cloneAlias(alias *types.FunctionAlias) *types.FunctionAlias {
+	clone := *alias
+	if alias.Routing != nil {
+		routing := *alias.Routing
+		routing.RolledBackAt = cloneTime(alias.Routing.RolledBackAt)
+		clone.Routing = &routing
+	}
+	return &clone
+}
@@ -0,0 +1,121 @@
+package metadata
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"GoFaas/pkg/errors"
+	"GoFaas/pkg/types"
+)
+
+// RegisterWorker implements WorkerRepository.RegisterWorker
+func (r *MemoryRepository) RegisterWorker(ctx context.Context, w *types.Worker) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := cloneWorker(w)
+	stored.Draining = false
+	stored.Drained = false
+	stored.Status = ""
+
+	r.workers[w.ID] = stored
+	return nil
+}
+
+// HeartbeatWorker implements WorkerRepository.HeartbeatWorker
+func (r *MemoryRepository) HeartbeatWorker(ctx context.Context, id string, inFlight int, drained bool, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, ok := r.workers[id]
+	if !ok {
+		return false, errors.NotFound("worker", id)
+	}
+
+	w.InFlight = inFlight
+	w.Drained = drained
+	w.LastHeartbeatAt = at
+	return w.Draining, nil
+}
+
+// DrainWorker implements WorkerRepository.DrainWorker
+func (r *MemoryRepository) DrainWorker(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, ok := r.workers[id]
+	if !ok {
+		return errors.NotFound("worker", id)
+	}
+
+	w.Draining = true
+	return nil
+}
+
+// DeregisterWorker implements WorkerRepository.DeregisterWorker
+func (r *MemoryRepository) DeregisterWorker(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.workers[id]; !ok {
+		return errors.NotFound("worker", id)
+	}
+
+	delete(r.workers, id)
+	return nil
+}
+
+// GetWorker implements WorkerRepository.GetWorker
+func (r *MemoryRepository) GetWorker(ctx context.Context, id string) (*types.Worker, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.workers[id]
+	if !ok {
+		return nil, errors.NotFound("worker", id)
+	}
+
+	return cloneWorker(w), nil
+}
+
+// ListWorkers implements WorkerRepository.ListWorkers
+func (r *MemoryRepository) ListWorkers(ctx context.Context) ([]*types.Worker, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	workers := make([]*types.Worker, 0, len(r.workers))
+	for _, w := range r.workers {
+		workers = append(workers, cloneWorker(w))
+	}
+
+	sort.Slice(workers, func(i, j int) bool {
+		if !workers[i].StartedAt.Equal(workers[j].StartedAt) {
+			return workers[i].StartedAt.Before(workers[j].StartedAt)
+		}
+		return workers[i].ID < workers[j].ID
+	})
+
+	return workers, nil
+}
+
+// PruneWorkers implements WorkerRepository.PruneWorkers
+func (r *MemoryRepository) PruneWorkers(ctx context.Context, before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, w := range r.workers {
+		if w.LastHeartbeatAt.Before(before) {
+			delete(r.workers, id)
+		}
+	}
+
+	return nil
+}
+
+// cloneWorker copies a worker
+func cloneWorker(w *types.Worker) *types.Worker {
+	clone := *w
+	clone.Labels = cloneStrings(w.Labels)
+	return &clone
+}
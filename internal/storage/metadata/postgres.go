@@ -23,23 +23,42 @@ func NewPostgresRepository(db *sql.DB) *PostgresRepository {
 	return &PostgresRepository{db: db}
 }
 
+// functionColumns lists the functions table columns read by scanFunction
+const functionColumns = `
+		id, name, version, runtime, handler, code_source, code_source_type,
+		code_checksum, code_size, code_format, code_artifact, timeout_seconds, memory_mb,
+		max_concurrency, environment, metadata, status, build_logs, build_error,
+		build_started_at, build_completed_at, created_at, updated_at, retry_policy, placement,
+		created_by`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
 // Create implements FunctionRepository.Create
 func (r *PostgresRepository) Create(ctx context.Context, fn *types.Function) error {
 	query := `
 		INSERT INTO functions (
 			id, name, version, runtime, handler, code_source, code_source_type,
-			code_checksum, code_size, timeout_seconds, memory_mb, max_concurrency,
-			environment, metadata, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+			code_checksum, code_size, code_format, code_artifact, timeout_seconds, memory_mb,
+			max_concurrency, environment, metadata, status, build_logs, build_error,
+			build_started_at, build_completed_at, created_at, updated_at, retry_policy, placement,
+			created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16,
+			$17, $18, $19, $20, $21, $22, $23, $24, $25, $26)`
 
 	envJSON, _ := json.Marshal(fn.Config.Environment)
 	metaJSON, _ := json.Marshal(fn.Metadata)
+	build := buildColumns(fn.Build)
 
 	_, err := r.db.ExecContext(ctx, query,
 		fn.ID, fn.Name, fn.Version, fn.Runtime, fn.Handler,
-		fn.Code.Source, fn.Code.SourceType, fn.Code.Checksum, fn.Code.Size,
-		int(fn.Config.Timeout.Seconds()), fn.Config.Memory, fn.Config.Concurrency,
-		envJSON, metaJSON, fn.CreatedAt, fn.UpdatedAt,
+		fn.Code.Source, fn.Code.SourceType, fn.Code.Checksum, fn.Code.Size, fn.Code.Format,
+		nullString(fn.Code.Artifact), int(fn.Config.Timeout.Seconds()), fn.Config.Memory, fn.Config.Concurrency,
+		envJSON, metaJSON, fn.Status, build.logs, build.err, build.startedAt, build.completedAt,
+		fn.CreatedAt, fn.UpdatedAt, retryPolicyJSON(fn.Config.Retry), placementJSON(fn.Config.Placement),
+		nullString(fn.CreatedBy),
 	)
 
 	if err != nil {
@@ -54,23 +73,9 @@ func (r *PostgresRepository) Create(ctx context.Context, fn *types.Function) err
 
 // GetByID implements FunctionRepository.GetByID
 func (r *PostgresRepository) GetByID(ctx context.Context, id string) (*types.Function, error) {
-	query := `
-		SELECT id, name, version, runtime, handler, code_source, code_source_type,
-		       code_checksum, code_size, timeout_seconds, memory_mb, max_concurrency,
-		       environment, metadata, created_at, updated_at
-		FROM functions WHERE id = $1`
-
-	var fn types.Function
-	var envJSON, metaJSON []byte
-	var timeoutSeconds int
-
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&fn.ID, &fn.Name, &fn.Version, &fn.Runtime, &fn.Handler,
-		&fn.Code.Source, &fn.Code.SourceType, &fn.Code.Checksum, &fn.Code.Size,
-		&timeoutSeconds, &fn.Config.Memory, &fn.Config.Concurrency,
-		&envJSON, &metaJSON, &fn.CreatedAt, &fn.UpdatedAt,
-	)
+	query := `SELECT ` + functionColumns + ` FROM functions WHERE id = $1`
 
+	fn, err := scanFunction(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.NotFound("function", id)
@@ -78,32 +83,14 @@ func (r *PostgresRepository) GetByID(ctx context.Context, id string) (*types.Fun
 		return nil, errors.InternalError(fmt.Sprintf("failed to get function: %v", err))
 	}
 
-	fn.Config.Timeout = time.Duration(timeoutSeconds) * time.Second
-	json.Unmarshal(envJSON, &fn.Config.Environment)
-	json.Unmarshal(metaJSON, &fn.Metadata)
-
-	return &fn, nil
+	return fn, nil
 }
 
 // GetByName implements FunctionRepository.GetByName
 func (r *PostgresRepository) GetByName(ctx context.Context, name, version string) (*types.Function, error) {
-	query := `
-		SELECT id, name, version, runtime, handler, code_source, code_source_type,
-		       code_checksum, code_size, timeout_seconds, memory_mb, max_concurrency,
-		       environment, metadata, created_at, updated_at
-		FROM functions WHERE name = $1 AND version = $2`
-
-	var fn types.Function
-	var envJSON, metaJSON []byte
-	var timeoutSeconds int
-
-	err := r.db.QueryRowContext(ctx, query, name, version).Scan(
-		&fn.ID, &fn.Name, &fn.Version, &fn.Runtime, &fn.Handler,
-		&fn.Code.Source, &fn.Code.SourceType, &fn.Code.Checksum, &fn.Code.Size,
-		&timeoutSeconds, &fn.Config.Memory, &fn.Config.Concurrency,
-		&envJSON, &metaJSON, &fn.CreatedAt, &fn.UpdatedAt,
-	)
+	query := `SELECT ` + functionColumns + ` FROM functions WHERE name = $1 AND version = $2`
 
+	fn, err := scanFunction(r.db.QueryRowContext(ctx, query, name, version))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.NotFound("function", fmt.Sprintf("%s:%s", name, version))
@@ -111,30 +98,38 @@ func (r *PostgresRepository) GetByName(ctx context.Context, name, version string
 		return nil, errors.InternalError(fmt.Sprintf("failed to get function: %v", err))
 	}
 
-	fn.Config.Timeout = time.Duration(timeoutSeconds) * time.Second
-	json.Unmarshal(envJSON, &fn.Config.Environment)
-	json.Unmarshal(metaJSON, &fn.Metadata)
-
-	return &fn, nil
+	return fn, nil
 }
 
-// Update implements FunctionRepository.Update
+// Update implements FunctionRepository.Update. The build state is only
+// written when fn carries a new build (a different build start time), so an
+// update racing with CompleteBuild does not undo the finished build.
 func (r *PostgresRepository) Update(ctx context.Context, fn *types.Function) error {
 	query := `
 		UPDATE functions SET
 			handler = $2, code_source = $3, code_source_type = $4,
-			code_checksum = $5, code_size = $6, timeout_seconds = $7,
-			memory_mb = $8, max_concurrency = $9, environment = $10,
-			metadata = $11, updated_at = $12
+			code_checksum = $5, code_size = $6, code_format = $19,
+			timeout_seconds = $8, memory_mb = $9, max_concurrency = $10,
+			environment = $11, metadata = $12, retry_policy = $20, placement = $21,
+			status = CASE WHEN build_started_at IS NOT DISTINCT FROM $16 THEN status ELSE $13 END,
+			code_artifact = CASE WHEN build_started_at IS NOT DISTINCT FROM $16 THEN code_artifact ELSE $7 END,
+			build_logs = CASE WHEN build_started_at IS NOT DISTINCT FROM $16 THEN build_logs ELSE $14 END,
+			build_error = CASE WHEN build_started_at IS NOT DISTINCT FROM $16 THEN build_error ELSE $15 END,
+			build_completed_at = CASE WHEN build_started_at IS NOT DISTINCT FROM $16 THEN build_completed_at ELSE $17 END,
+			build_started_at = $16, updated_at = $18
 		WHERE id = $1`
 
 	envJSON, _ := json.Marshal(fn.Config.Environment)
 	metaJSON, _ := json.Marshal(fn.Metadata)
+	build := buildColumns(fn.Build)
 
 	result, err := r.db.ExecContext(ctx, query,
 		fn.ID, fn.Handler, fn.Code.Source, fn.Code.SourceType,
-		fn.Code.Checksum, fn.Code.Size, int(fn.Config.Timeout.Seconds()),
-		fn.Config.Memory, fn.Config.Concurrency, envJSON, metaJSON, fn.UpdatedAt,
+		fn.Code.Checksum, fn.Code.Size, nullString(fn.Code.Artifact),
+		int(fn.Config.Timeout.Seconds()), fn.Config.Memory, fn.Config.Concurrency,
+		envJSON, metaJSON, fn.Status,
+		build.logs, build.err, build.startedAt, build.completedAt, fn.UpdatedAt,
+		fn.Code.Format, retryPolicyJSON(fn.Config.Retry), placementJSON(fn.Config.Placement),
 	)
 
 	if err != nil {
@@ -149,6 +144,52 @@ func (r *PostgresRepository) Update(ctx context.Context, fn *types.Function) err
 	return nil
 }
 
+// CompleteBuild implements FunctionRepository.CompleteBuild. The build
+// result is only stored if the function still has the code and handler that
+// were built; it reports whether the row was updated.
+func (r *PostgresRepository) CompleteBuild(ctx context.Context, fn *types.Function) (bool, error) {
+	query := `
+		UPDATE functions SET
+			status = $4, code_artifact = $5, build_logs = $6, build_error = $7,
+			build_started_at = $8, build_completed_at = $9, updated_at = $10
+		WHERE id = $1 AND code_checksum = $2 AND handler = $3`
+
+	build := buildColumns(fn.Build)
+
+	result, err := r.db.ExecContext(ctx, query,
+		fn.ID, fn.Code.Checksum, fn.Handler,
+		fn.Status, nullString(fn.Code.Artifact), build.logs, build.err,
+		build.startedAt, build.completedAt, fn.UpdatedAt,
+	)
+	if err != nil {
+		return false, errors.InternalError(fmt.Sprintf("failed to complete function build: %v", err))
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// RestartBuild implements FunctionRepository.RestartBuild
+func (r *PostgresRepository) RestartBuild(ctx context.Context, fn *types.Function, staleStart time.Time) (bool, error) {
+	query := `
+		UPDATE functions SET
+			status = $2, code_artifact = NULL, build_logs = NULL, build_error = NULL,
+			build_started_at = $3, build_completed_at = NULL, updated_at = $4
+		WHERE id = $1 AND status = $5 AND build_started_at = $6`
+
+	build := buildColumns(fn.Build)
+
+	result, err := r.db.ExecContext(ctx, query,
+		fn.ID, fn.Status, build.startedAt, fn.UpdatedAt, types.FunctionBuilding, staleStart,
+	)
+	if err != nil {
+		return false, errors.InternalError(fmt.Sprintf("failed to restart function build: %v", err))
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
 // Delete implements FunctionRepository.Delete
 func (r *PostgresRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM functions WHERE id = $1`
@@ -168,12 +209,7 @@ func (r *PostgresRepository) Delete(ctx context.Context, id string) error {
 
 // List implements FunctionRepository.List
 func (r *PostgresRepository) List(ctx context.Context, filter FunctionFilter) ([]*types.Function, error) {
-	query := `
-		SELECT id, name, version, runtime, handler, code_source, code_source_type,
-		       code_checksum, code_size, timeout_seconds, memory_mb, max_concurrency,
-		       environment, metadata, created_at, updated_at
-		FROM functions
-		WHERE 1=1`
+	query := `SELECT ` + functionColumns + ` FROM functions WHERE 1=1`
 
 	var args []interface{}
 	argPos := 1
@@ -184,6 +220,12 @@ func (r *PostgresRepository) List(ctx context.Context, filter FunctionFilter) ([
 		argPos++
 	}
 
+	if filter.Status != nil {
+		query += fmt.Sprintf(" AND status = $%d", argPos)
+		args = append(args, *filter.Status)
+		argPos++
+	}
+
 	query += " ORDER BY created_at DESC"
 
 	if filter.Limit > 0 {
@@ -205,45 +247,215 @@ func (r *PostgresRepository) List(ctx context.Context, filter FunctionFilter) ([
 
 	functions := make([]*types.Function, 0)
 	for rows.Next() {
-		var fn types.Function
-		var envJSON, metaJSON []byte
-		var timeoutSeconds int
-
-		err := rows.Scan(
-			&fn.ID, &fn.Name, &fn.Version, &fn.Runtime, &fn.Handler,
-			&fn.Code.Source, &fn.Code.SourceType, &fn.Code.Checksum, &fn.Code.Size,
-			&timeoutSeconds, &fn.Config.Memory, &fn.Config.Concurrency,
-			&envJSON, &metaJSON, &fn.CreatedAt, &fn.UpdatedAt,
-		)
+		fn, err := scanFunction(rows)
 		if err != nil {
 			return nil, errors.InternalError(fmt.Sprintf("failed to scan function: %v", err))
 		}
 
-		fn.Config.Timeout = time.Duration(timeoutSeconds) * time.Second
-		json.Unmarshal(envJSON, &fn.Config.Environment)
-		json.Unmarshal(metaJSON, &fn.Metadata)
-
-		functions = append(functions, &fn)
+		functions = append(functions, fn)
 	}
 
 	return functions, nil
 }
 
+// ListPlacements implements FunctionRepository.ListPlacements, returning
+// each distinct placement of a function, published version or unfinished
+// invocation once
+func (r *PostgresRepository) ListPlacements(ctx context.Context) ([]*types.Placement, error) {
+	query := `
+		SELECT placement FROM functions WHERE placement IS NOT NULL
+		UNION
+		SELECT placement FROM function_versions WHERE placement IS NOT NULL
+		UNION
+		SELECT placement FROM invocations
+		WHERE placement IS NOT NULL AND status IN ('pending', 'delayed', 'running')`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to list placements: %v", err))
+	}
+	defer rows.Close()
+
+	placements := make([]*types.Placement, 0)
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, errors.InternalError(fmt.Sprintf("failed to scan placement: %v", err))
+		}
+
+		placement := scanPlacement(data)
+		key := placement.Key()
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		placements = append(placements, placement)
+	}
+
+	return placements, nil
+}
+
+// scanFunction reads a row selected with functionColumns
+func scanFunction(scanner rowScanner) (*types.Function, error) {
+	var fn types.Function
+	var envJSON, metaJSON, retryJSON, placementJSON []byte
+	var timeoutSeconds int
+	var artifact, buildLogs, buildError, createdBy sql.NullString
+	var buildStartedAt, buildCompletedAt sql.NullTime
+
+	err := scanner.Scan(
+		&fn.ID, &fn.Name, &fn.Version, &fn.Runtime, &fn.Handler,
+		&fn.Code.Source, &fn.Code.SourceType, &fn.Code.Checksum, &fn.Code.Size, &fn.Code.Format, &artifact,
+		&timeoutSeconds, &fn.Config.Memory, &fn.Config.Concurrency,
+		&envJSON, &metaJSON, &fn.Status, &buildLogs, &buildError,
+		&buildStartedAt, &buildCompletedAt, &fn.CreatedAt, &fn.UpdatedAt, &retryJSON, &placementJSON,
+		&createdBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	fn.Code.Artifact = artifact.String
+	fn.CreatedBy = createdBy.String
+	fn.Config.Timeout = time.Duration(timeoutSeconds) * time.Second
+	json.Unmarshal(envJSON, &fn.Config.Environment)
+	json.Unmarshal(metaJSON, &fn.Metadata)
+	fn.Config.Retry = scanRetryPolicy(retryJSON)
+	fn.Config.Placement = scanPlacement(placementJSON)
+
+	if buildStartedAt.Valid {
+		fn.Build = &types.BuildInfo{
+			Logs:      buildLogs.String,
+			Error:     buildError.String,
+			StartedAt: &buildStartedAt.Time,
+		}
+		if buildCompletedAt.Valid {
+			fn.Build.CompletedAt = &buildCompletedAt.Time
+		}
+	}
+
+	return &fn, nil
+}
+
+// buildRow holds the nullable build columns of a function
+type buildRow struct {
+	logs, err              sql.NullString
+	startedAt, completedAt sql.NullTime
+}
+
+// buildColumns converts build info to nullable column values
+func buildColumns(build *types.BuildInfo) buildRow {
+	var row buildRow
+	if build == nil {
+		return row
+	}
+
+	row.logs = nullString(build.Logs)
+	row.err = nullString(build.Error)
+	if build.StartedAt != nil {
+		row.startedAt = sql.NullTime{Time: *build.StartedAt, Valid: true}
+	}
+	if build.CompletedAt != nil {
+		row.completedAt = sql.NullTime{Time: *build.CompletedAt, Valid: true}
+	}
+
+	return row
+}
+
+// retryPolicyJSON encodes a retry policy, mapping nil to NULL
+func retryPolicyJSON(p *types.RetryPolicy) []byte {
+	if p == nil {
+		return nil
+	}
+	data, _ := json.Marshal(p)
+	return data
+}
+
+// scanRetryPolicy decodes a retry policy column, nil if it is NULL
+func scanRetryPolicy(data []byte) *types.RetryPolicy {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var p types.RetryPolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil
+	}
+	return &p
+}
+
+// placementJSON encodes a placement, mapping one that allows every worker
+// to NULL
+func placementJSON(p *types.Placement) []byte {
+	if p.IsZero() {
+		return nil
+	}
+	data, _ := json.Marshal(p)
+	return data
+}
+
+// scanPlacement decodes a placement column, nil if it is NULL
+func scanPlacement(data []byte) *types.Placement {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var p types.Placement
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil
+	}
+	return &p
+}
+
+// nullString maps an empty string to NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullJSON maps an empty JSON document to NULL
+func nullJSON(data json.RawMessage) []byte {
+	if len(data) == 0 {
+		return nil
+	}
+	return data
+}
+
+// nullInt maps zero to NULL
+func nullInt(n int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(n), Valid: n != 0}
+}
+
+// invocationColumns lists the invocations table columns read by scanInvocation
+const invocationColumns = `
+		id, function_id, function_version, payload, headers, status, result,
+		error_type, error_message, error_stack,
+		duration_ns, cpu_time_ns, memory_peak, network_in, network_out,
+		logs, created_at, run_at, started_at, completed_at,
+		idempotency_key, idempotency_hash, callback_url, callback_secret, replay_of, priority, placement`
+
 // CreateInvocation creates a new invocation record
 func (r *PostgresRepository) CreateInvocation(ctx context.Context, inv *types.Invocation) error {
 	query := `
 		INSERT INTO invocations (
-			id, function_id, payload, headers, status, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6)`
+			id, function_id, function_version, payload, headers, status, created_at, run_at,
+			idempotency_key, idempotency_hash, callback_url, callback_secret, replay_of, priority, placement
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
 
 	payloadJSON, _ := json.Marshal(inv.Payload)
 	headersJSON, _ := json.Marshal(inv.Headers)
 
 	_, err := r.db.ExecContext(ctx, query,
-		inv.ID, inv.FunctionID, payloadJSON, headersJSON, inv.Status, inv.CreatedAt,
+		inv.ID, inv.FunctionID, nullInt(inv.FunctionVersion), payloadJSON, headersJSON, inv.Status, inv.CreatedAt, inv.RunAt,
+		nullString(inv.IdempotencyKey), nullString(inv.IdempotencyHash),
+		nullString(inv.CallbackURL), nullString(inv.CallbackSecret), nullString(inv.ReplayOf), inv.Priority,
+		placementJSON(inv.Placement),
 	)
 
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return errors.Conflict(fmt.Sprintf("idempotency key %s is already in use", inv.IdempotencyKey))
+		}
 		return errors.InternalError(fmt.Sprintf("failed to create invocation: %v", err))
 	}
 
@@ -252,25 +464,9 @@ func (r *PostgresRepository) CreateInvocation(ctx context.Context, inv *types.In
 
 // GetInvocationByID retrieves an invocation by ID
 func (r *PostgresRepository) GetInvocationByID(ctx context.Context, id string) (*types.Invocation, error) {
-	query := `
-		SELECT id, function_id, payload, headers, status, result,
-		       error_type, error_message, error_stack,
-		       duration_ns, cpu_time_ns, memory_peak, network_in, network_out,
-		       created_at, started_at, completed_at
-		FROM invocations WHERE id = $1`
-
-	var inv types.Invocation
-	var payloadJSON, headersJSON, resultJSON []byte
-	var errorType, errorMessage, errorStack sql.NullString
-	var durationNs, cpuTimeNs, memoryPeak, networkIn, networkOut sql.NullInt64
-
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&inv.ID, &inv.FunctionID, &payloadJSON, &headersJSON, &inv.Status, &resultJSON,
-		&errorType, &errorMessage, &errorStack,
-		&durationNs, &cpuTimeNs, &memoryPeak, &networkIn, &networkOut,
-		&inv.CreatedAt, &inv.StartedAt, &inv.CompletedAt,
-	)
+	query := `SELECT ` + invocationColumns + ` FROM invocations WHERE id = $1`
 
+	inv, err := scanInvocation(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.NotFound("invocation", id)
@@ -278,34 +474,41 @@ func (r *PostgresRepository) GetInvocationByID(ctx context.Context, id string) (
 		return nil, errors.InternalError(fmt.Sprintf("failed to get invocation: %v", err))
 	}
 
-	json.Unmarshal(payloadJSON, &inv.Payload)
-	json.Unmarshal(headersJSON, &inv.Headers)
-	if len(resultJSON) > 0 {
-		json.Unmarshal(resultJSON, &inv.Result)
-	}
+	return inv, nil
+}
 
-	if errorType.Valid {
-		inv.Error = &types.ExecutionError{
-			Type:    errorType.String,
-			Message: errorMessage.String,
-			Stack:   errorStack.String,
+// GetInvocationByIdempotencyKey implements
+// InvocationRepository.GetInvocationByIdempotencyKey, returning the
+// invocation of a function that holds the key
+func (r *PostgresRepository) GetInvocationByIdempotencyKey(ctx context.Context, functionID, key string) (*types.Invocation, error) {
+	query := `SELECT ` + invocationColumns + ` FROM invocations WHERE function_id = $1 AND idempotency_key = $2`
+
+	inv, err := scanInvocation(r.db.QueryRowContext(ctx, query, functionID, key))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NotFound("idempotency key", key)
 		}
+		return nil, errors.InternalError(fmt.Sprintf("failed to get invocation: %v", err))
 	}
 
-	if durationNs.Valid {
-		inv.Metrics = &types.ExecutionMetrics{
-			Duration:   time.Duration(durationNs.Int64),
-			CPUTime:    time.Duration(cpuTimeNs.Int64),
-			MemoryPeak: memoryPeak.Int64,
-			NetworkIn:  networkIn.Int64,
-			NetworkOut: networkOut.Int64,
-		}
+	return inv, nil
+}
+
+// ReleaseIdempotencyKey implements InvocationRepository.ReleaseIdempotencyKey,
+// clearing an invocation's key so it can be used by a new invocation
+func (r *PostgresRepository) ReleaseIdempotencyKey(ctx context.Context, id string) error {
+	query := `UPDATE invocations SET idempotency_key = NULL, idempotency_hash = NULL WHERE id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, id); err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to release idempotency key: %v", err))
 	}
 
-	return &inv, nil
+	return nil
 }
 
-// UpdateInvocation updates an invocation record
+// UpdateInvocation updates an invocation record. Cancelled invocations are
+// final: updating one returns a Conflict error, so a worker finishing
+// concurrently with a cancellation cannot overwrite it.
 func (r *PostgresRepository) UpdateInvocation(ctx context.Context, inv *types.Invocation) error {
 	query := `
 		UPDATE invocations SET
@@ -313,14 +516,19 @@ func (r *PostgresRepository) UpdateInvocation(ctx context.Context, inv *types.In
 			error_type = $4, error_message = $5, error_stack = $6,
 			duration_ns = $7, cpu_time_ns = $8, memory_peak = $9,
 			network_in = $10, network_out = $11,
-			started_at = $12, completed_at = $13
-		WHERE id = $1`
+			started_at = $12, completed_at = $13, logs = $14
+		WHERE id = $1 AND status <> 'cancelled'`
 
 	var resultJSON []byte
 	if inv.Result != nil {
 		resultJSON, _ = json.Marshal(inv.Result)
 	}
 
+	var logsJSON []byte
+	if inv.Logs != nil {
+		logsJSON, _ = json.Marshal(inv.Logs)
+	}
+
 	var errorType, errorMessage, errorStack sql.NullString
 	if inv.Error != nil {
 		errorType = sql.NullString{String: inv.Error.Type, Valid: true}
@@ -341,7 +549,7 @@ func (r *PostgresRepository) UpdateInvocation(ctx context.Context, inv *types.In
 		inv.ID, inv.Status, resultJSON,
 		errorType, errorMessage, errorStack,
 		durationNs, cpuTimeNs, memoryPeak, networkIn, networkOut,
-		inv.StartedAt, inv.CompletedAt,
+		inv.StartedAt, inv.CompletedAt, logsJSON,
 	)
 
 	if err != nil {
@@ -350,21 +558,120 @@ func (r *PostgresRepository) UpdateInvocation(ctx context.Context, inv *types.In
 
 	rows, _ := result.RowsAffected()
 	if rows == 0 {
-		return errors.NotFound("invocation", inv.ID)
+		if _, err := r.GetInvocationByID(ctx, inv.ID); err != nil {
+			return err
+		}
+		return errors.Conflict(fmt.Sprintf("invocation %s was cancelled", inv.ID))
+	}
+
+	return nil
+}
+
+// CancelInvocation implements InvocationRepository.CancelInvocation. The
+// invocation is only cancelled if it has not reached a terminal status;
+// the returned flag reports whether it was.
+func (r *PostgresRepository) CancelInvocation(ctx context.Context, id string, at time.Time) (bool, error) {
+	query := `
+		UPDATE invocations SET status = 'cancelled', completed_at = $2
+		WHERE id = $1 AND status IN ('pending', 'delayed', 'running')`
+
+	result, err := r.db.ExecContext(ctx, query, id, at)
+	if err != nil {
+		return false, errors.InternalError(fmt.Sprintf("failed to cancel invocation: %v", err))
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// RetryInvocation implements InvocationRepository.RetryInvocation, moving
+// an invocation back to the delayed status until its next attempt. A
+// cancelled invocation is not retried; the returned flag reports whether
+// the invocation was updated.
+func (r *PostgresRepository) RetryInvocation(ctx context.Context, id string, runAt time.Time) (bool, error) {
+	query := `
+		UPDATE invocations SET status = 'delayed', run_at = $2
+		WHERE id = $1 AND status <> 'cancelled'`
+
+	result, err := r.db.ExecContext(ctx, query, id, runAt)
+	if err != nil {
+		return false, errors.InternalError(fmt.Sprintf("failed to retry invocation: %v", err))
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// CreateAttempt implements InvocationRepository.CreateAttempt
+func (r *PostgresRepository) CreateAttempt(ctx context.Context, a *types.InvocationAttempt) error {
+	query := `
+		INSERT INTO invocation_attempts (
+			id, invocation_id, attempt, worker_id, status, error_type, error_message,
+			duration_ns, started_at, completed_at, retry_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	var errorType, errorMessage sql.NullString
+	if a.Error != nil {
+		errorType = sql.NullString{String: a.Error.Type, Valid: true}
+		errorMessage = sql.NullString{String: a.Error.Message, Valid: true}
+	}
+
+	_, err := r.db.ExecContext(ctx, query,
+		a.ID, a.InvocationID, a.Attempt, nullString(a.WorkerID), a.Status, errorType, errorMessage,
+		int64(a.Duration), a.StartedAt, a.CompletedAt, a.RetryAt,
+	)
+
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to create invocation attempt: %v", err))
 	}
 
 	return nil
 }
 
+// ListAttempts implements InvocationRepository.ListAttempts, returning the
+// attempts of an invocation in order
+func (r *PostgresRepository) ListAttempts(ctx context.Context, invocationID string) ([]*types.InvocationAttempt, error) {
+	query := `
+		SELECT id, invocation_id, attempt, worker_id, status, error_type, error_message,
+			duration_ns, started_at, completed_at, retry_at
+		FROM invocation_attempts
+		WHERE invocation_id = $1
+		ORDER BY attempt, started_at`
+
+	rows, err := r.db.QueryContext(ctx, query, invocationID)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to list invocation attempts: %v", err))
+	}
+	defer rows.Close()
+
+	attempts := make([]*types.InvocationAttempt, 0)
+	for rows.Next() {
+		var a types.InvocationAttempt
+		var workerID, errorType, errorMessage sql.NullString
+		var durationNs int64
+
+		err := rows.Scan(
+			&a.ID, &a.InvocationID, &a.Attempt, &workerID, &a.Status, &errorType, &errorMessage,
+			&durationNs, &a.StartedAt, &a.CompletedAt, &a.RetryAt,
+		)
+		if err != nil {
+			return nil, errors.InternalError(fmt.Sprintf("failed to scan invocation attempt: %v", err))
+		}
+
+		a.WorkerID = workerID.String
+		a.Duration = time.Duration(durationNs)
+		if errorType.Valid {
+			a.Error = &types.ExecutionError{Type: errorType.String, Message: errorMessage.String}
+		}
+		attempts = append(attempts, &a)
+	}
+
+	return attempts, nil
+}
+
 // ListInvocations lists invocations with filters
 func (r *PostgresRepository) ListInvocations(ctx context.Context, filter InvocationFilter) ([]*types.Invocation, error) {
-	query := `
-		SELECT id, function_id, payload, headers, status, result,
-		       error_type, error_message, error_stack,
-		       duration_ns, cpu_time_ns, memory_peak, network_in, network_out,
-		       created_at, started_at, completed_at
-		FROM invocations
-		WHERE 1=1`
+	query := `SELECT ` + invocationColumns + ` FROM invocations WHERE 1=1`
 
 	args := []interface{}{}
 	argPos := 1
@@ -402,47 +709,88 @@ func (r *PostgresRepository) ListInvocations(ctx context.Context, filter Invocat
 
 	invocations := make([]*types.Invocation, 0)
 	for rows.Next() {
-		var inv types.Invocation
-		var payloadJSON, headersJSON, resultJSON []byte
-		var errorType, errorMessage, errorStack sql.NullString
-		var durationNs, cpuTimeNs, memoryPeak, networkIn, networkOut sql.NullInt64
-
-		err := rows.Scan(
-			&inv.ID, &inv.FunctionID, &payloadJSON, &headersJSON, &inv.Status, &resultJSON,
-			&errorType, &errorMessage, &errorStack,
-			&durationNs, &cpuTimeNs, &memoryPeak, &networkIn, &networkOut,
-			&inv.CreatedAt, &inv.StartedAt, &inv.CompletedAt,
-		)
+		inv, err := scanInvocation(rows)
 		if err != nil {
 			return nil, errors.InternalError(fmt.Sprintf("failed to scan invocation: %v", err))
 		}
 
-		json.Unmarshal(payloadJSON, &inv.Payload)
-		json.Unmarshal(headersJSON, &inv.Headers)
-		if len(resultJSON) > 0 {
-			json.Unmarshal(resultJSON, &inv.Result)
-		}
+		invocations = append(invocations, inv)
+	}
 
-		if errorType.Valid {
-			inv.Error = &types.ExecutionError{
-				Type:    errorType.String,
-				Message: errorMessage.String,
-				Stack:   errorStack.String,
-			}
-		}
+	return invocations, nil
+}
+
+// CountVersionOutcomes implements InvocationRepository.CountVersionOutcomes
+func (r *PostgresRepository) CountVersionOutcomes(ctx context.Context, functionID string, version int, since time.Time) (*OutcomeCounts, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE status IN ('completed', 'failed', 'timeout')),
+			COUNT(*) FILTER (WHERE status IN ('failed', 'timeout'))
+		FROM invocations
+		WHERE function_id = $1 AND function_version = $2 AND created_at >= $3`
+
+	var counts OutcomeCounts
+	err := r.db.QueryRowContext(ctx, query, functionID, version, since).Scan(&counts.Total, &counts.Failed)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to count invocation outcomes: %v", err))
+	}
+
+	return &counts, nil
+}
+
+// scanInvocation reads a row selected with invocationColumns
+func scanInvocation(scanner rowScanner) (*types.Invocation, error) {
+	var inv types.Invocation
+	var functionVersion sql.NullInt64
+	var payloadJSON, headersJSON, resultJSON, logsJSON, placementJSON []byte
+	var errorType, errorMessage, errorStack sql.NullString
+	var durationNs, cpuTimeNs, memoryPeak, networkIn, networkOut sql.NullInt64
+	var idempotencyKey, idempotencyHash, callbackURL, callbackSecret, replayOf sql.NullString
+
+	err := scanner.Scan(
+		&inv.ID, &inv.FunctionID, &functionVersion, &payloadJSON, &headersJSON, &inv.Status, &resultJSON,
+		&errorType, &errorMessage, &errorStack,
+		&durationNs, &cpuTimeNs, &memoryPeak, &networkIn, &networkOut,
+		&logsJSON, &inv.CreatedAt, &inv.RunAt, &inv.StartedAt, &inv.CompletedAt,
+		&idempotencyKey, &idempotencyHash, &callbackURL, &callbackSecret, &replayOf, &inv.Priority, &placementJSON,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	inv.FunctionVersion = int(functionVersion.Int64)
+	inv.IdempotencyKey = idempotencyKey.String
+	inv.IdempotencyHash = idempotencyHash.String
+	inv.CallbackURL = callbackURL.String
+	inv.CallbackSecret = callbackSecret.String
+	inv.ReplayOf = replayOf.String
+	inv.Placement = scanPlacement(placementJSON)
+	json.Unmarshal(payloadJSON, &inv.Payload)
+	json.Unmarshal(headersJSON, &inv.Headers)
+	if len(resultJSON) > 0 {
+		json.Unmarshal(resultJSON, &inv.Result)
+	}
+	if len(logsJSON) > 0 {
+		json.Unmarshal(logsJSON, &inv.Logs)
+	}
 
-		if durationNs.Valid {
-			inv.Metrics = &types.ExecutionMetrics{
-				Duration:   time.Duration(durationNs.Int64),
-				CPUTime:    time.Duration(cpuTimeNs.Int64),
-				MemoryPeak: memoryPeak.Int64,
-				NetworkIn:  networkIn.Int64,
-				NetworkOut: networkOut.Int64,
-			}
+	if errorType.Valid {
+		inv.Error = &types.ExecutionError{
+			Type:    errorType.String,
+			Message: errorMessage.String,
+			Stack:   errorStack.String,
 		}
+	}
 
-		invocations = append(invocations, &inv)
+	if durationNs.Valid {
+		inv.Metrics = &types.ExecutionMetrics{
+			Duration:   time.Duration(durationNs.Int64),
+			CPUTime:    time.Duration(cpuTimeNs.Int64),
+			MemoryPeak: memoryPeak.Int64,
+			NetworkIn:  networkIn.Int64,
+			NetworkOut: networkOut.Int64,
+		}
 	}
 
-	return invocations, nil
+	return &inv, nil
 }
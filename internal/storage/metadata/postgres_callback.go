@@ -0,0 +1,71 @@
+package metadata
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"GoFaas/pkg/errors"
+	"GoFaas/pkg/types"
+)
+
+// CreateCallbackDelivery implements CallbackRepository.CreateCallbackDelivery
+func (r *PostgresRepository) CreateCallbackDelivery(ctx context.Context, d *types.CallbackDelivery) error {
+	query := `
+		INSERT INTO callback_deliveries (
+			id, invocation_id, attempt, url, status_code, error, succeeded,
+			duration_ns, next_attempt_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		d.ID, d.InvocationID, d.Attempt, d.URL, nullInt(d.StatusCode), nullString(d.Error), d.Succeeded,
+		int64(d.Duration), d.NextAttemptAt, d.CreatedAt,
+	)
+
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to create callback delivery: %v", err))
+	}
+
+	return nil
+}
+
+// ListCallbackDeliveries implements CallbackRepository.ListCallbackDeliveries,
+// returning the delivery attempts of an invocation in order
+func (r *PostgresRepository) ListCallbackDeliveries(ctx context.Context, invocationID string) ([]*types.CallbackDelivery, error) {
+	query := `
+		SELECT id, invocation_id, attempt, url, status_code, error, succeeded,
+			duration_ns, next_attempt_at, created_at
+		FROM callback_deliveries
+		WHERE invocation_id = $1
+		ORDER BY attempt, created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, invocationID)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to list callback deliveries: %v", err))
+	}
+	defer rows.Close()
+
+	deliveries := make([]*types.CallbackDelivery, 0)
+	for rows.Next() {
+		var d types.CallbackDelivery
+		var statusCode sql.NullInt64
+		var deliveryError sql.NullString
+		var durationNs int64
+
+		err := rows.Scan(
+			&d.ID, &d.InvocationID, &d.Attempt, &d.URL, &statusCode, &deliveryError, &d.Succeeded,
+			&durationNs, &d.NextAttemptAt, &d.CreatedAt,
+		)
+		if err != nil {
+			return nil, errors.InternalError(fmt.Sprintf("failed to scan callback delivery: %v", err))
+		}
+
+		d.StatusCode = int(statusCode.Int64)
+		d.Error = deliveryError.String
+		d.Duration = time.Duration(durationNs)
+		deliveries = append(deliveries, &d)
+	}
+
+	return deliveries, nil
+}
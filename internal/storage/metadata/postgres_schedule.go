@@ -0,0 +1,190 @@
+package metadata
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"GoFaas/pkg/errors"
+	"GoFaas/pkg/types"
+)
+
+// scheduleColumns lists the function_schedules columns read by scanSchedule
+const scheduleColumns = `
+		id, function_id, cron, timezone, payload, enabled, next_run_at,
+		last_run_at, last_invocation_id, last_error, created_at, updated_at`
+
+// CreateSchedule implements ScheduleRepository.CreateSchedule
+func (r *PostgresRepository) CreateSchedule(ctx context.Context, s *types.Schedule) error {
+	query := `
+		INSERT INTO function_schedules (
+			id, function_id, cron, timezone, payload, enabled, next_run_at, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		s.ID, s.FunctionID, s.Cron, s.Timezone, nullJSON(s.Payload), s.Enabled, s.NextRunAt,
+		s.CreatedAt, s.UpdatedAt,
+	)
+
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to create schedule: %v", err))
+	}
+
+	return nil
+}
+
+// GetSchedule implements ScheduleRepository.GetSchedule
+func (r *PostgresRepository) GetSchedule(ctx context.Context, id string) (*types.Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM function_schedules WHERE id = $1`
+
+	s, err := scanSchedule(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NotFound("schedule", id)
+		}
+		return nil, errors.InternalError(fmt.Sprintf("failed to get schedule: %v", err))
+	}
+
+	return s, nil
+}
+
+// ListSchedules implements ScheduleRepository.ListSchedules
+func (r *PostgresRepository) ListSchedules(ctx context.Context, functionID string) ([]*types.Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM function_schedules WHERE function_id = $1 ORDER BY created_at`
+
+	return r.querySchedules(ctx, query, functionID)
+}
+
+// UpdateSchedule implements ScheduleRepository.UpdateSchedule
+func (r *PostgresRepository) UpdateSchedule(ctx context.Context, s *types.Schedule) error {
+	query := `
+		UPDATE function_schedules SET
+			cron = $2, timezone = $3, payload = $4, enabled = $5,
+			next_run_at = $6, updated_at = $7
+		WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query,
+		s.ID, s.Cron, s.Timezone, nullJSON(s.Payload), s.Enabled, s.NextRunAt, s.UpdatedAt,
+	)
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to update schedule: %v", err))
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.NotFound("schedule", s.ID)
+	}
+
+	return nil
+}
+
+// DeleteSchedule implements ScheduleRepository.DeleteSchedule
+func (r *PostgresRepository) DeleteSchedule(ctx context.Context, id string) error {
+	query := `DELETE FROM function_schedules WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to delete schedule: %v", err))
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.NotFound("schedule", id)
+	}
+
+	return nil
+}
+
+// ListDueSchedules implements ScheduleRepository.ListDueSchedules,
+// returning enabled schedules whose next run is at or before now, oldest
+// first
+func (r *PostgresRepository) ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]*types.Schedule, error) {
+	query := `
+		SELECT ` + scheduleColumns + ` FROM function_schedules
+		WHERE enabled AND next_run_at <= $1
+		ORDER BY next_run_at
+		LIMIT $2`
+
+	return r.querySchedules(ctx, query, now, limit)
+}
+
+// ClaimScheduleRun implements ScheduleRepository.ClaimScheduleRun. The
+// schedule is moved to its next run only if it is still due at the time
+// the caller read, so a run is claimed by at most one scheduler. A zero
+// next time leaves the schedule without further runs.
+func (r *PostgresRepository) ClaimScheduleRun(ctx context.Context, id string, due, next time.Time) (bool, error) {
+	query := `
+		UPDATE function_schedules SET
+			next_run_at = $3, last_run_at = $2
+		WHERE id = $1 AND enabled AND next_run_at = $2`
+
+	var nextRun *time.Time
+	if !next.IsZero() {
+		nextRun = &next
+	}
+
+	result, err := r.db.ExecContext(ctx, query, id, due, nextRun)
+	if err != nil {
+		return false, errors.InternalError(fmt.Sprintf("failed to claim schedule run: %v", err))
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// RecordScheduleRun implements ScheduleRepository.RecordScheduleRun,
+// storing the outcome of the last claimed run
+func (r *PostgresRepository) RecordScheduleRun(ctx context.Context, id, invocationID, runError string) error {
+	query := `UPDATE function_schedules SET last_invocation_id = $2, last_error = $3 WHERE id = $1`
+
+	_, err := r.db.ExecContext(ctx, query, id, nullString(invocationID), nullString(runError))
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to record schedule run: %v", err))
+	}
+
+	return nil
+}
+
+// querySchedules runs a query selecting scheduleColumns
+func (r *PostgresRepository) querySchedules(ctx context.Context, query string, args ...interface{}) ([]*types.Schedule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to list schedules: %v", err))
+	}
+	defer rows.Close()
+
+	schedules := make([]*types.Schedule, 0)
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, errors.InternalError(fmt.Sprintf("failed to scan schedule: %v", err))
+		}
+		schedules = append(schedules, s)
+	}
+
+	return schedules, nil
+}
+
+// scanSchedule reads a row selected with scheduleColumns
+func scanSchedule(scanner rowScanner) (*types.Schedule, error) {
+	var s types.Schedule
+	var payload []byte
+	var lastInvocationID, lastError sql.NullString
+
+	err := scanner.Scan(
+		&s.ID, &s.FunctionID, &s.Cron, &s.Timezone, &payload, &s.Enabled, &s.NextRunAt,
+		&s.LastRunAt, &lastInvocationID, &lastError, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(payload) > 0 {
+		s.Payload = payload
+	}
+	s.LastInvocationID = lastInvocationID.String
+	s.LastError = lastError.String
+
+	return &s, nil
+}
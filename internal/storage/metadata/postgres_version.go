@@ -0,0 +1,350 @@
+package metadata
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"GoFaas/pkg/errors"
+	"GoFaas/pkg/types"
+)
+
+// versionColumns lists the function_versions columns read by scanVersion
+const versionColumns = `
+		id, function_id, function_name, version, runtime, handler, code_source,
+		code_source_type, code_checksum, code_size, code_format, code_artifact,
+		timeout_seconds, memory_mb, max_concurrency, environment, description, created_at,
+		retry_policy, placement`
+
+// aliasColumns lists the function_aliases columns read by scanAlias
+const aliasColumns = `
+		function_name, name, version, description, routing_version, routing_weight,
+		routing_started_at, routing_rolled_back_at, routing_rollback_reason, created_at, updated_at`
+
+// maxVersionAttempts bounds retries when concurrent publishes pick the
+// same version number
+const maxVersionAttempts = 3
+
+// CreateVersion implements VersionRepository.CreateVersion. The next
+// version number for the function name is assigned by the insert and stored
+// in v.Version.
+func (r *PostgresRepository) CreateVersion(ctx context.Context, v *types.FunctionVersion) error {
+	query := `
+		INSERT INTO function_versions (
+			id, function_id, function_name, version, runtime, handler, code_source,
+			code_source_type, code_checksum, code_size, code_format, code_artifact,
+			timeout_seconds, memory_mb, max_concurrency, environment, description, created_at,
+			retry_policy, placement
+		)
+		VALUES (
+			$1, $2, $3,
+			(SELECT COALESCE(MAX(version), 0) + 1 FROM function_versions WHERE function_name = $3),
+			$4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19
+		)
+		RETURNING version`
+
+	envJSON, _ := json.Marshal(v.Config.Environment)
+
+	var err error
+	for attempt := 0; attempt < maxVersionAttempts; attempt++ {
+		err = r.db.QueryRowContext(ctx, query,
+			v.ID, v.FunctionID, v.FunctionName, v.Runtime, v.Handler, v.Code.Source,
+			v.Code.SourceType, v.Code.Checksum, v.Code.Size, v.Code.Format, nullString(v.Code.Artifact),
+			int(v.Config.Timeout.Seconds()), v.Config.Memory, v.Config.Concurrency,
+			envJSON, nullString(v.Description), v.CreatedAt, retryPolicyJSON(v.Config.Retry),
+			placementJSON(v.Config.Placement),
+		).Scan(&v.Version)
+
+		pqErr, ok := err.(*pq.Error)
+		if !ok || pqErr.Code != "23505" {
+			break
+		}
+	}
+
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return errors.Conflict(fmt.Sprintf("concurrent publish of function %s, retry", v.FunctionName))
+		}
+		return errors.InternalError(fmt.Sprintf("failed to create function version: %v", err))
+	}
+
+	return nil
+}
+
+// GetVersion implements VersionRepository.GetVersion
+func (r *PostgresRepository) GetVersion(ctx context.Context, functionName string, version int) (*types.FunctionVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM function_versions WHERE function_name = $1 AND version = $2`
+
+	v, err := scanVersion(r.db.QueryRowContext(ctx, query, functionName, version))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NotFound("function version", fmt.Sprintf("%s:%d", functionName, version))
+		}
+		return nil, errors.InternalError(fmt.Sprintf("failed to get function version: %v", err))
+	}
+
+	return v, nil
+}
+
+// GetVersionByID implements VersionRepository.GetVersionByID
+func (r *PostgresRepository) GetVersionByID(ctx context.Context, id string) (*types.FunctionVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM function_versions WHERE id = $1`
+
+	v, err := scanVersion(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NotFound("function version", id)
+		}
+		return nil, errors.InternalError(fmt.Sprintf("failed to get function version: %v", err))
+	}
+
+	return v, nil
+}
+
+// ListVersions implements VersionRepository.ListVersions, newest first
+func (r *PostgresRepository) ListVersions(ctx context.Context, functionName string) ([]*types.FunctionVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM function_versions WHERE function_name = $1 ORDER BY version DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, functionName)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to list function versions: %v", err))
+	}
+	defer rows.Close()
+
+	versions := make([]*types.FunctionVersion, 0)
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, errors.InternalError(fmt.Sprintf("failed to scan function version: %v", err))
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, nil
+}
+
+// PutAlias implements VersionRepository.PutAlias. Creating and moving an
+// alias is a single upsert, so invocations never see a partial update.
+// The alias's routing is replaced as well, clearing any earlier rollback.
+func (r *PostgresRepository) PutAlias(ctx context.Context, alias *types.FunctionAlias) error {
+	query := `
+		INSERT INTO function_aliases (
+			function_name, name, version, description,
+			routing_version, routing_weight, routing_started_at, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		ON CONFLICT (function_name, name) DO UPDATE SET
+			version = EXCLUDED.version,
+			description = EXCLUDED.description,
+			routing_version = EXCLUDED.routing_version,
+			routing_weight = EXCLUDED.routing_weight,
+			routing_started_at = EXCLUDED.routing_started_at,
+			routing_rolled_back_at = NULL,
+			routing_rollback_reason = NULL,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at, updated_at`
+
+	var routingVersion sql.NullInt64
+	var routingWeight int
+	var routingStartedAt *time.Time
+	if alias.Routing != nil {
+		routingVersion = nullInt(alias.Routing.Version)
+		routingWeight = alias.Routing.Weight
+		routingStartedAt = &alias.Routing.StartedAt
+	}
+
+	err := r.db.QueryRowContext(ctx, query,
+		alias.FunctionName, alias.Name, alias.Version, nullString(alias.Description),
+		routingVersion, routingWeight, routingStartedAt, alias.UpdatedAt,
+	).Scan(&alias.CreatedAt, &alias.UpdatedAt)
+
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			missing := alias.Version
+			if pqErr.Constraint == "fk_alias_routing_version" {
+				missing = alias.Routing.Version
+			}
+			return errors.NotFound("function version", fmt.Sprintf("%s:%d", alias.FunctionName, missing))
+		}
+		return errors.InternalError(fmt.Sprintf("failed to save function alias: %v", err))
+	}
+
+	return nil
+}
+
+// ListRoutedAliases implements VersionRepository.ListRoutedAliases. It
+// returns the aliases currently sending traffic to an additional version.
+func (r *PostgresRepository) ListRoutedAliases(ctx context.Context) ([]*types.FunctionAlias, error) {
+	query := `SELECT ` + aliasColumns + ` FROM function_aliases WHERE routing_weight > 0 ORDER BY function_name, name`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to list routed function aliases: %v", err))
+	}
+	defer rows.Close()
+
+	aliases := make([]*types.FunctionAlias, 0)
+	for rows.Next() {
+		alias, err := scanAlias(rows)
+		if err != nil {
+			return nil, errors.InternalError(fmt.Sprintf("failed to scan function alias: %v", err))
+		}
+		aliases = append(aliases, alias)
+	}
+
+	return aliases, nil
+}
+
+// RollbackAliasRouting implements VersionRepository.RollbackAliasRouting.
+// The weight is only reset if the alias still routes to the version read by
+// the caller, so a rollback never undoes a newer PutAlias. The returned
+// flag reports whether the rollback was applied.
+func (r *PostgresRepository) RollbackAliasRouting(ctx context.Context, alias *types.FunctionAlias, reason string) (bool, error) {
+	if alias.Routing == nil {
+		return false, nil
+	}
+
+	query := `
+		UPDATE function_aliases SET
+			routing_weight = 0,
+			routing_rolled_back_at = $5,
+			routing_rollback_reason = $6,
+			updated_at = $5
+		WHERE function_name = $1 AND name = $2
+			AND routing_version = $3 AND routing_started_at = $4 AND routing_weight > 0`
+
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx, query,
+		alias.FunctionName, alias.Name, alias.Routing.Version, alias.Routing.StartedAt, now, reason,
+	)
+	if err != nil {
+		return false, errors.InternalError(fmt.Sprintf("failed to roll back function alias: %v", err))
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return false, nil
+	}
+
+	alias.Routing.Weight = 0
+	alias.Routing.RolledBackAt = &now
+	alias.Routing.RollbackReason = reason
+	alias.UpdatedAt = now
+
+	return true, nil
+}
+
+// GetAlias implements VersionRepository.GetAlias
+func (r *PostgresRepository) GetAlias(ctx context.Context, functionName, name string) (*types.FunctionAlias, error) {
+	query := `SELECT ` + aliasColumns + ` FROM function_aliases WHERE function_name = $1 AND name = $2`
+
+	alias, err := scanAlias(r.db.QueryRowContext(ctx, query, functionName, name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NotFound("function alias", fmt.Sprintf("%s:%s", functionName, name))
+		}
+		return nil, errors.InternalError(fmt.Sprintf("failed to get function alias: %v", err))
+	}
+
+	return alias, nil
+}
+
+// ListAliases implements VersionRepository.ListAliases
+func (r *PostgresRepository) ListAliases(ctx context.Context, functionName string) ([]*types.FunctionAlias, error) {
+	query := `SELECT ` + aliasColumns + ` FROM function_aliases WHERE function_name = $1 ORDER BY name`
+
+	rows, err := r.db.QueryContext(ctx, query, functionName)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to list function aliases: %v", err))
+	}
+	defer rows.Close()
+
+	aliases := make([]*types.FunctionAlias, 0)
+	for rows.Next() {
+		alias, err := scanAlias(rows)
+		if err != nil {
+			return nil, errors.InternalError(fmt.Sprintf("failed to scan function alias: %v", err))
+		}
+		aliases = append(aliases, alias)
+	}
+
+	return aliases, nil
+}
+
+// DeleteAlias implements VersionRepository.DeleteAlias
+func (r *PostgresRepository) DeleteAlias(ctx context.Context, functionName, name string) error {
+	query := `DELETE FROM function_aliases WHERE function_name = $1 AND name = $2`
+
+	result, err := r.db.ExecContext(ctx, query, functionName, name)
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to delete function alias: %v", err))
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.NotFound("function alias", fmt.Sprintf("%s:%s", functionName, name))
+	}
+
+	return nil
+}
+
+// scanVersion reads a row selected with versionColumns
+func scanVersion(scanner rowScanner) (*types.FunctionVersion, error) {
+	var v types.FunctionVersion
+	var envJSON, retryJSON, placementJSON []byte
+	var timeoutSeconds int
+	var artifact, description sql.NullString
+
+	err := scanner.Scan(
+		&v.ID, &v.FunctionID, &v.FunctionName, &v.Version, &v.Runtime, &v.Handler, &v.Code.Source,
+		&v.Code.SourceType, &v.Code.Checksum, &v.Code.Size, &v.Code.Format, &artifact,
+		&timeoutSeconds, &v.Config.Memory, &v.Config.Concurrency, &envJSON, &description, &v.CreatedAt,
+		&retryJSON, &placementJSON,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	v.Code.Artifact = artifact.String
+	v.Description = description.String
+	v.Config.Timeout = time.Duration(timeoutSeconds) * time.Second
+	json.Unmarshal(envJSON, &v.Config.Environment)
+	v.Config.Retry = scanRetryPolicy(retryJSON)
+	v.Config.Placement = scanPlacement(placementJSON)
+
+	return &v, nil
+}
+
+// scanAlias reads a row selected with aliasColumns
+func scanAlias(scanner rowScanner) (*types.FunctionAlias, error) {
+	var alias types.FunctionAlias
+	var description, rollbackReason sql.NullString
+	var routingVersion sql.NullInt64
+	var routingWeight int
+	var routingStartedAt sql.NullTime
+	var rolledBackAt *time.Time
+
+	err := scanner.Scan(
+		&alias.FunctionName, &alias.Name, &alias.Version, &description, &routingVersion, &routingWeight,
+		&routingStartedAt, &rolledBackAt, &rollbackReason, &alias.CreatedAt, &alias.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	alias.Description = description.String
+	if routingVersion.Valid {
+		alias.Routing = &types.AliasRouting{
+			Version:        int(routingVersion.Int64),
+			Weight:         routingWeight,
+			StartedAt:      routingStartedAt.Time,
+			RolledBackAt:   rolledBackAt,
+			RollbackReason: rollbackReason.String,
+		}
+	}
+
+	return &alias, nil
+}
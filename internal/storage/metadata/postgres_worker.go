@@ -0,0 +1,171 @@
+package metadata
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"GoFaas/pkg/errors"
+	"GoFaas/pkg/types"
+)
+
+// workerColumns lists the workers columns read by scanWorker
+const workerColumns = `
+		id, hostname, runtime, capabilities, slots, in_flight, labels, draining,
+		drained, started_at, last_heartbeat_at`
+
+// RegisterWorker implements WorkerRepository.RegisterWorker
+func (r *PostgresRepository) RegisterWorker(ctx context.Context, w *types.Worker) error {
+	query := `
+		INSERT INTO workers (
+			id, hostname, runtime, capabilities, slots, in_flight, labels, draining,
+			drained, started_at, last_heartbeat_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, FALSE, FALSE, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET
+			hostname = EXCLUDED.hostname,
+			runtime = EXCLUDED.runtime,
+			capabilities = EXCLUDED.capabilities,
+			slots = EXCLUDED.slots,
+			in_flight = EXCLUDED.in_flight,
+			labels = EXCLUDED.labels,
+			draining = FALSE,
+			drained = FALSE,
+			started_at = EXCLUDED.started_at,
+			last_heartbeat_at = EXCLUDED.last_heartbeat_at`
+
+	capabilitiesJSON, _ := json.Marshal(w.Capabilities)
+	labelsJSON, _ := json.Marshal(w.Labels)
+
+	_, err := r.db.ExecContext(ctx, query,
+		w.ID, w.Hostname, w.Runtime, capabilitiesJSON, w.Slots, w.InFlight, labelsJSON,
+		w.StartedAt, w.LastHeartbeatAt,
+	)
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to register worker: %v", err))
+	}
+
+	return nil
+}
+
+// HeartbeatWorker implements WorkerRepository.HeartbeatWorker
+func (r *PostgresRepository) HeartbeatWorker(ctx context.Context, id string, inFlight int, drained bool, at time.Time) (bool, error) {
+	query := `
+		UPDATE workers SET in_flight = $2, drained = $3, last_heartbeat_at = $4
+		WHERE id = $1
+		RETURNING draining`
+
+	var draining bool
+	err := r.db.QueryRowContext(ctx, query, id, inFlight, drained, at).Scan(&draining)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, errors.NotFound("worker", id)
+		}
+		return false, errors.InternalError(fmt.Sprintf("failed to record worker heartbeat: %v", err))
+	}
+
+	return draining, nil
+}
+
+// DrainWorker implements WorkerRepository.DrainWorker
+func (r *PostgresRepository) DrainWorker(ctx context.Context, id string) error {
+	query := `UPDATE workers SET draining = TRUE WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to drain worker: %v", err))
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.NotFound("worker", id)
+	}
+
+	return nil
+}
+
+// DeregisterWorker implements WorkerRepository.DeregisterWorker
+func (r *PostgresRepository) DeregisterWorker(ctx context.Context, id string) error {
+	query := `DELETE FROM workers WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to deregister worker: %v", err))
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.NotFound("worker", id)
+	}
+
+	return nil
+}
+
+// GetWorker implements WorkerRepository.GetWorker
+func (r *PostgresRepository) GetWorker(ctx context.Context, id string) (*types.Worker, error) {
+	query := `SELECT ` + workerColumns + ` FROM workers WHERE id = $1`
+
+	w, err := scanWorker(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NotFound("worker", id)
+		}
+		return nil, errors.InternalError(fmt.Sprintf("failed to get worker: %v", err))
+	}
+
+	return w, nil
+}
+
+// ListWorkers implements WorkerRepository.ListWorkers, in the order they
+// started
+func (r *PostgresRepository) ListWorkers(ctx context.Context) ([]*types.Worker, error) {
+	query := `SELECT ` + workerColumns + ` FROM workers ORDER BY started_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, errors.InternalError(fmt.Sprintf("failed to list workers: %v", err))
+	}
+	defer rows.Close()
+
+	workers := make([]*types.Worker, 0)
+	for rows.Next() {
+		w, err := scanWorker(rows)
+		if err != nil {
+			return nil, errors.InternalError(fmt.Sprintf("failed to scan worker: %v", err))
+		}
+		workers = append(workers, w)
+	}
+
+	return workers, nil
+}
+
+// PruneWorkers implements WorkerRepository.PruneWorkers
+func (r *PostgresRepository) PruneWorkers(ctx context.Context, before time.Time) error {
+	query := `DELETE FROM workers WHERE last_heartbeat_at < $1`
+
+	if _, err := r.db.ExecContext(ctx, query, before); err != nil {
+		return errors.InternalError(fmt.Sprintf("failed to prune workers: %v", err))
+	}
+
+	return nil
+}
+
+// scanWorker reads a row selected with workerColumns
+func scanWorker(scanner rowScanner) (*types.Worker, error) {
+	var w types.Worker
+	var capabilitiesJSON, labelsJSON []byte
+
+	err := scanner.Scan(
+		&w.ID, &w.Hostname, &w.Runtime, &capabilitiesJSON, &w.Slots, &w.InFlight, &labelsJSON, &w.Draining,
+		&w.Drained, &w.StartedAt, &w.LastHeartbeatAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(capabilitiesJSON, &w.Capabilities)
+	json.Unmarshal(labelsJSON, &w.Labels)
+
+	return &w, nil
+}
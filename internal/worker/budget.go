@@ -0,0 +1,99 @@
+package worker
+
+import (
+	"context"
+	"sync"
+
+	"GoFaas/internal/worker/runtime"
+)
+
+// defaultCPUMillis is reserved for an execution without a CPU limit
+const defaultCPUMillis = 1000
+
+// budget accounts for the host CPU and memory reserved by the executions
+// running in a worker. An execution waits until its limits fit in what the
+// others left over.
+type budget struct {
+	cpuMillis   int64 // Zero for no CPU budget
+	memoryBytes int64 // Zero for no memory budget
+
+	mu         sync.Mutex
+	usedCPU    int64
+	usedMemory int64
+	released   chan struct{} // Closed and replaced whenever a reservation is released
+}
+
+// newBudget creates a budget of the given CPUs and memory
+func newBudget(cpus float64, memoryBytes int64) *budget {
+	return &budget{
+		cpuMillis:   int64(cpus * 1000),
+		memoryBytes: memoryBytes,
+		released:    make(chan struct{}),
+	}
+}
+
+// acquire reserves the resources of an execution with the given limits,
+// waiting until they are available or ctx is done. The returned function
+// releases them. Limits beyond the whole budget are capped to it, so an
+// execution always runs once the worker is otherwise idle.
+func (b *budget) acquire(ctx context.Context, limits runtime.ResourceLimits) (func(), error) {
+	cpu := limits.CPUShares
+	if cpu <= 0 {
+		cpu = defaultCPUMillis
+	}
+	cpu = capAt(cpu, b.cpuMillis)
+	memory := capAt(limits.MemoryBytes, b.memoryBytes)
+
+	for {
+		b.mu.Lock()
+		if b.fits(cpu, memory) {
+			b.usedCPU += cpu
+			b.usedMemory += memory
+			b.mu.Unlock()
+
+			var once sync.Once
+			return func() {
+				once.Do(func() { b.release(cpu, memory) })
+			}, nil
+		}
+		released := b.released
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+// fits reports whether a reservation fits in the unused budget. The caller
+// holds mu.
+func (b *budget) fits(cpu, memory int64) bool {
+	if b.cpuMillis > 0 && b.usedCPU+cpu > b.cpuMillis {
+		return false
+	}
+	if b.memoryBytes > 0 && b.usedMemory+memory > b.memoryBytes {
+		return false
+	}
+	return true
+}
+
+// release returns a reservation to the budget and wakes the executions
+// waiting for one
+func (b *budget) release(cpu, memory int64) {
+	b.mu.Lock()
+	b.usedCPU -= cpu
+	b.usedMemory -= memory
+	close(b.released)
+	b.released = make(chan struct{})
+	b.mu.Unlock()
+}
+
+// capAt caps n at limit, unless limit is zero for none
+func capAt(n, limit int64) int64 {
+	if limit > 0 && n > limit {
+		return limit
+	}
+	return n
+}
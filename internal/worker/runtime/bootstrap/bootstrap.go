@@ -0,0 +1,91 @@
+// Package bootstrap provides the per-language programs that sit between the
+// worker and a function's handler. A bootstrap reads one invocation request
+// as JSON from stdin, calls the handler, and writes one JSON response to
+// stdout. Anything the function prints is redirected to stderr, which the
+// worker collects as invocation logs.
+package bootstrap
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"GoFaas/pkg/types"
+)
+
+//go:embed faas_bootstrap.py
+var pythonBootstrap []byte
+
+//go:embed faas_bootstrap.js
+var nodeBootstrap []byte
+
+//go:embed faas_bootstrap.go.tmpl
+var goBootstrapSource string
+
+var goBootstrapTemplate = template.Must(template.New("bootstrap").Parse(goBootstrapSource))
+
+// goIdentifier matches a valid exported or unexported Go function name
+var goIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// File is a bootstrap source file to place next to the function code
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// Generate returns the bootstrap file for a runtime. Go bootstraps are
+// generated per handler because the handler is linked at compile time.
+func Generate(runtime types.RuntimeType, handler string) (*File, error) {
+	switch runtime {
+	case types.RuntimePython:
+		return &File{Name: "faas_bootstrap.py", Content: pythonBootstrap}, nil
+	case types.RuntimeNodeJS:
+		return &File{Name: "faas_bootstrap.js", Content: nodeBootstrap}, nil
+	case types.RuntimeGo:
+		if !goIdentifier.MatchString(handler) {
+			return nil, fmt.Errorf("go handler must be a function name, got %q", handler)
+		}
+		var buf bytes.Buffer
+		if err := goBootstrapTemplate.Execute(&buf, struct{ Handler string }{handler}); err != nil {
+			return nil, fmt.Errorf("failed to render go bootstrap: %w", err)
+		}
+		return &File{Name: "faas_bootstrap.go", Content: buf.Bytes()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported runtime: %s", runtime)
+	}
+}
+
+// ValidateHandler checks that handler is addressable by the runtime's
+// bootstrap: a function name for Go, or "[module.]function" for Python and
+// Node.js (the module defaults to "main"). In archives the module may be a
+// path relative to the archive root, e.g. "lib/app.handler".
+func ValidateHandler(runtime types.RuntimeType, handler string) error {
+	if runtime == types.RuntimeGo {
+		if !goIdentifier.MatchString(handler) {
+			return fmt.Errorf("go handler must be a function name, got %q", handler)
+		}
+		return nil
+	}
+
+	module, function := SplitHandler(handler)
+	if function == "" {
+		return fmt.Errorf("handler must name a function, got %q", handler)
+	}
+	if strings.HasPrefix(module, "/") || strings.Contains(module, "..") {
+		return fmt.Errorf("handler module must be a relative path, got %q", module)
+	}
+	return nil
+}
+
+// SplitHandler splits a Python or Node.js handler into its module and
+// function name, defaulting the module to "main"
+func SplitHandler(handler string) (module, function string) {
+	i := strings.LastIndex(handler, ".")
+	if i < 0 {
+		return "main", handler
+	}
+	return handler[:i], handler[i+1:]
+}
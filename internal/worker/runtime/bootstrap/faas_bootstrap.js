@@ -0,0 +1,65 @@
+#!/usr/bin/env node
+// FaaS runtime bootstrap for Node.js functions.
+//
+// Reads one invocation request as JSON from stdin, calls the configured
+// handler as handler(event, context) (which may return a promise) and writes
+// one JSON response to stdout. Everything the function prints goes to stderr
+// and is collected as logs.
+'use strict';
+
+const fs = require('fs');
+const path = require('path');
+
+// Send user output to stderr; the response is written straight to fd 1
+process.stdout.write = process.stderr.write.bind(process.stderr);
+
+async function invoke(request) {
+    const handler = request.handler || 'main.handler';
+    const dot = handler.lastIndexOf('.');
+    const moduleName = dot >= 0 ? handler.slice(0, dot) : 'main';
+    const functionName = dot >= 0 ? handler.slice(dot + 1) : handler;
+
+    const context = {
+        invocationId: request.invocation_id,
+        functionId: request.function_id,
+        deadlineMs: request.deadline_ms,
+    };
+
+    try {
+        const mod = require(path.join(__dirname, moduleName));
+        const fn = mod[functionName];
+        if (typeof fn !== 'function') {
+            throw new TypeError(`handler ${handler} is not a function`);
+        }
+        const result = await fn(request.payload, context);
+        return { result: result === undefined ? null : result };
+    } catch (e) {
+        return {
+            error: {
+                type: (e && e.name) || 'Error',
+                message: (e && e.message) || String(e),
+                stack: (e && e.stack) || '',
+            },
+        };
+    }
+}
+
+async function main() {
+    const request = JSON.parse(fs.readFileSync(0, 'utf8'));
+    const response = await invoke(request);
+
+    let data;
+    try {
+        data = JSON.stringify(response);
+    } catch (e) {
+        data = JSON.stringify({
+            error: {
+                type: 'SerializationError',
+                message: `handler result is not JSON serializable: ${e.message}`,
+            },
+        });
+    }
+    fs.writeSync(1, data);
+}
+
+main();
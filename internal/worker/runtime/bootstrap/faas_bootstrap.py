@@ -0,0 +1,67 @@
+#!/usr/bin/env python3
+"""FaaS runtime bootstrap for Python functions.
+
+Reads one invocation request as JSON from stdin, calls the configured
+handler as handler(event, context) and writes one JSON response to stdout.
+Everything the function prints goes to stderr and is collected as logs.
+"""
+import importlib
+import json
+import os
+import sys
+import traceback
+
+
+def main():
+    # Keep the real stdout for the response and send user output to stderr
+    response_out = os.fdopen(os.dup(1), "w")
+    os.dup2(2, 1)
+    sys.stdout = sys.stderr
+
+    request = json.load(sys.stdin)
+    response = invoke(request)
+
+    try:
+        data = json.dumps(response)
+    except (TypeError, ValueError) as e:
+        data = json.dumps({"error": {
+            "type": "SerializationError",
+            "message": "handler result is not JSON serializable: %s" % e,
+        }})
+
+    response_out.write(data)
+    response_out.flush()
+
+
+def invoke(request):
+    handler = request.get("handler") or "main.handler"
+    module_name, _, function_name = handler.rpartition(".")
+    # Modules inside archives may be given as paths, e.g. "lib/app.handler"
+    module_name = (module_name or "main").replace("/", ".")
+
+    context = {
+        "invocation_id": request.get("invocation_id"),
+        "function_id": request.get("function_id"),
+        "deadline_ms": request.get("deadline_ms"),
+    }
+
+    try:
+        function_dir = os.path.dirname(os.path.abspath(__file__))
+        sys.path.insert(0, function_dir)
+        # Dependencies installed by the build from requirements.txt
+        packages_dir = os.path.join(function_dir, ".packages")
+        if os.path.isdir(packages_dir):
+            sys.path.insert(1, packages_dir)
+        module = importlib.import_module(module_name)
+        function = getattr(module, function_name)
+        return {"result": function(request.get("payload"), context)}
+    except Exception as e:
+        return {"error": {
+            "type": type(e).__name__,
+            "message": str(e),
+            "stack": traceback.format_exc(),
+        }}
+
+
+if __name__ == "__main__":
+    main()
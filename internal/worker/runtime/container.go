@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
@@ -16,12 +17,13 @@ import (
 type ContainerRuntime struct {
 	dockerClient *docker.Client
 	imageManager *docker.ImageManager
+	pool         *ContainerPool
 	workDir      string
 	logger       logging.Logger
 }
 
 // NewContainerRuntime creates a new container-based runtime
-func NewContainerRuntime(workDir string, logger logging.Logger) (*ContainerRuntime, error) {
+func NewContainerRuntime(workDir string, poolCfg PoolConfig, logger logging.Logger) (*ContainerRuntime, error) {
 	// Create Docker client
 	dockerClient, err := docker.NewClient(logger)
 	if err != nil {
@@ -39,12 +41,13 @@ func NewContainerRuntime(workDir string, logger logging.Logger) (*ContainerRunti
 	return &ContainerRuntime{
 		dockerClient: dockerClient,
 		imageManager: imageManager,
+		pool:         NewContainerPool(dockerClient, workDir, poolCfg, logger),
 		workDir:      workDir,
 		logger:       logger,
 	}, nil
 }
 
-// Execute runs a function inside a Docker container
+// Execute runs a function inside a warm Docker container from the pool
 func (r *ContainerRuntime) Execute(ctx context.Context, spec ExecutionSpec) (*ExecutionResult, error) {
 	startTime := time.Now()
 
@@ -77,124 +80,110 @@ func (r *ContainerRuntime) Execute(ctx context.Context, spec ExecutionSpec) (*Ex
 		}, nil
 	}
 
-	// Create temporary directory for function code
-	execDir := filepath.Join(r.workDir, spec.FunctionID, fmt.Sprintf("%d", time.Now().UnixNano()))
-	if err := os.MkdirAll(execDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create execution directory: %w", err)
-	}
-	defer os.RemoveAll(execDir) // Cleanup after execution
-
-	// Write function code to file
-	_, err := r.writeCodeToFile(execDir, spec.Runtime, spec.Code)
-	if err != nil {
-		return nil, fmt.Errorf("failed to write function code: %w", err)
-	}
-
 	// Create execution context with timeout
 	execCtx, cancel := context.WithTimeout(ctx, spec.Timeout)
 	defer cancel()
 
-	// Prepare container configuration
-	containerCfg := docker.ContainerConfig{
-		Image:       imageName,
-		Handler:     spec.Handler,
-		Payload:     spec.Payload,
-		Environment: spec.Environment,
-		MemoryLimit: spec.Limits.MemoryBytes,
-		CPULimit:    spec.Limits.CPUShares * 1000000, // Convert to nanocpus
-		CodePath:    execDir,
+	// Check out a warm container (or start a new one)
+	fp, c, err := r.pool.Acquire(execCtx, spec, imageName)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create container
-	containerID, err := r.dockerClient.CreateContainer(execCtx, containerCfg)
+	request, err := encodeRequest(spec, startTime.Add(spec.Timeout))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create container: %w", err)
-	}
+		r.pool.Release(fp, c, true)
+		return nil, fmt.Errorf("failed to encode invocation request: %w", err)
+	}
+
+	// Environment is passed per execution so a warm container always sees
+	// the function's current configuration
+	env := make([]string, 0, len(spec.Environment))
+	for key, value := range spec.Environment {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	var stdout bytes.Buffer
+	logs := &logWriter{}
+	exitCode, err := r.dockerClient.Exec(execCtx, c.id, docker.ExecConfig{
+		Cmd:    []string{"/app/wrapper.sh"},
+		Env:    env,
+		Stdin:  request,
+		Stdout: &stdout,
+		Stderr: logs,
+	})
+	endTime := time.Now()
 
-	// Ensure container cleanup
-	defer func() {
-		cleanupCtx, cleanupCancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cleanupCancel()
-		r.dockerClient.RemoveContainer(cleanupCtx, containerID)
-	}()
+	// The invocation was cancelled; stop the function
+	if execCtx.Err() == context.Canceled {
+		r.dockerClient.KillContainer(context.Background(), c.id)
+		r.pool.Release(fp, c, false)
 
-	// Start container
-	if err := r.dockerClient.StartContainer(execCtx, containerID); err != nil {
-		return nil, fmt.Errorf("failed to start container: %w", err)
+		return &ExecutionResult{
+			Status: types.StatusCancelled,
+			Error: &types.ExecutionError{
+				Type:    "CancelledError",
+				Message: "Function execution was cancelled",
+			},
+			Metrics: types.ExecutionMetrics{
+				Duration: endTime.Sub(startTime),
+			},
+			Logs: logs.Entries(),
+		}, nil
 	}
 
-	r.logger.Debug("Container started",
-		logging.F("container_id", containerID),
-		logging.F("function_id", spec.FunctionID),
-		logging.F("runtime", spec.Runtime),
-	)
-
-	// Wait for container to finish
-	exitCode, err := r.dockerClient.WaitContainer(execCtx, containerID)
-	endTime := time.Now()
-
 	// Check for timeout
 	if execCtx.Err() == context.DeadlineExceeded {
 		r.logger.Warn("Container execution timed out",
-			logging.F("container_id", containerID),
+			logging.F("container_id", c.id),
 			logging.F("function_id", spec.FunctionID),
 		)
 
+		// The function may still be running inside the container
+		r.dockerClient.KillContainer(context.Background(), c.id)
+		r.pool.Release(fp, c, false)
+
 		return &ExecutionResult{
 			Status: types.StatusTimeout,
 			Error: &types.ExecutionError{
-				Type:    "TimeoutError",
+				Type:    types.ErrorTypeTimeout,
 				Message: "Function execution timed out",
 			},
 			Metrics: types.ExecutionMetrics{
 				Duration: endTime.Sub(startTime),
 			},
+			Logs: logs.Entries(),
 		}, nil
 	}
 
-	// Get container logs
-	logs, err := r.dockerClient.GetContainerLogs(context.Background(), containerID)
 	if err != nil {
-		r.logger.Error("Failed to get container logs",
-			logging.F("container_id", containerID),
-			logging.F("error", err),
-		)
-		logs = []byte{}
+		r.pool.Release(fp, c, false)
+		return nil, fmt.Errorf("failed to execute in container: %w", err)
 	}
 
-	// Get container stats (best effort)
-	stats, _ := r.dockerClient.GetContainerStats(context.Background(), containerID)
+	r.pool.Release(fp, c, true)
 
 	// Build execution result
 	result := &ExecutionResult{
 		Metrics: types.ExecutionMetrics{
-			Duration:   endTime.Sub(startTime),
-			MemoryPeak: stats.MemoryUsage,
-			NetworkIn:  stats.NetworkIn,
-			NetworkOut: stats.NetworkOut,
+			Duration: endTime.Sub(startTime),
 		},
+		Logs: logs.Entries(),
 	}
+	applyResponse(result, exitCode, stdout.Bytes())
 
-	// Check exit code
-	if exitCode == 0 {
-		result.Status = types.StatusCompleted
-		result.Result = logs
+	if result.Status == types.StatusCompleted {
 		r.logger.Info("Container execution completed successfully",
-			logging.F("container_id", containerID),
+			logging.F("container_id", c.id),
 			logging.F("function_id", spec.FunctionID),
 			logging.F("duration", result.Metrics.Duration),
 		)
 	} else {
-		result.Status = types.StatusFailed
-		result.Error = &types.ExecutionError{
-			Type:    "RuntimeError",
-			Message: fmt.Sprintf("Function exited with code %d", exitCode),
-			Stack:   string(logs),
-		}
 		r.logger.Warn("Container execution failed",
-			logging.F("container_id", containerID),
+			logging.F("container_id", c.id),
 			logging.F("function_id", spec.FunctionID),
 			logging.F("exit_code", exitCode),
+			logging.F("error_type", result.Error.Type),
 		)
 	}
 
@@ -211,13 +200,20 @@ func (r *ContainerRuntime) GetCapabilities() RuntimeCapabilities {
 	}
 }
 
+// InvalidateFunction removes the warm containers of a function whose code
+// was updated or deleted
+func (r *ContainerRuntime) InvalidateFunction(functionID string, allVersions bool) {
+	r.pool.Invalidate(functionID, allVersions)
+}
+
 // Close closes the container runtime and releases resources
 func (r *ContainerRuntime) Close() error {
+	r.pool.Close()
 	return r.dockerClient.Close()
 }
 
 // writeCodeToFile writes function code to a file based on runtime
-func (r *ContainerRuntime) writeCodeToFile(dir string, runtime types.RuntimeType, code []byte) (string, error) {
+func writeCodeToFile(dir string, runtime types.RuntimeType, code []byte) (string, error) {
 	var filename string
 
 	switch runtime {
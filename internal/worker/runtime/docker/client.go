@@ -9,6 +9,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 
 	"GoFaas/internal/observability/logging"
 )
@@ -40,9 +41,7 @@ func (c *Client) Close() error {
 // CreateContainer creates a new container with the specified configuration
 func (c *Client) CreateContainer(ctx context.Context, cfg ContainerConfig) (string, error) {
 	// Prepare environment variables
-	env := make([]string, 0, len(cfg.Environment)+2)
-	env = append(env, fmt.Sprintf("FUNCTION_HANDLER=%s", cfg.Handler))
-	env = append(env, fmt.Sprintf("FUNCTION_PAYLOAD=%s", string(cfg.Payload)))
+	env := make([]string, 0, len(cfg.Environment))
 	for key, value := range cfg.Environment {
 		env = append(env, fmt.Sprintf("%s=%s", key, value))
 	}
@@ -51,6 +50,8 @@ func (c *Client) CreateContainer(ctx context.Context, cfg ContainerConfig) (stri
 	containerConfig := &container.Config{
 		Image:        cfg.Image,
 		Env:          env,
+		Entrypoint:   cfg.Entrypoint,
+		Labels:       cfg.Labels,
 		WorkingDir:   "/app",
 		AttachStdout: true,
 		AttachStderr: true,
@@ -63,7 +64,7 @@ func (c *Client) CreateContainer(ctx context.Context, cfg ContainerConfig) (stri
 			Memory:   cfg.MemoryLimit,
 			NanoCPUs: cfg.CPULimit,
 		},
-		AutoRemove: false, // We'll remove manually after getting logs
+		AutoRemove:  false, // We'll remove manually after getting logs
 		NetworkMode: "bridge",
 	}
 
@@ -123,6 +124,69 @@ func (c *Client) WaitContainer(ctx context.Context, containerID string) (int64,
 	return -1, fmt.Errorf("unexpected wait completion")
 }
 
+// Exec runs a command inside a running container, feeding it cfg.Stdin and
+// streaming its output to cfg.Stdout and cfg.Stderr. It returns the
+// command's exit code.
+func (c *Client) Exec(ctx context.Context, containerID string, cfg ExecConfig) (int64, error) {
+	execResp, err := c.cli.ContainerExecCreate(ctx, containerID, types.ExecConfig{
+		Cmd:          cfg.Cmd,
+		Env:          cfg.Env,
+		AttachStdin:  cfg.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return -1, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	attach, err := c.cli.ContainerExecAttach(ctx, execResp.ID, types.ExecStartCheck{})
+	if err != nil {
+		return -1, fmt.Errorf("failed to attach to exec: %w", err)
+	}
+	defer attach.Close()
+
+	if cfg.Stdin != nil {
+		if _, err := attach.Conn.Write(cfg.Stdin); err != nil {
+			return -1, fmt.Errorf("failed to write exec stdin: %w", err)
+		}
+		if err := attach.CloseWrite(); err != nil {
+			return -1, fmt.Errorf("failed to close exec stdin: %w", err)
+		}
+	}
+
+	// Read output in the background so the context deadline is honored even
+	// if the process never closes its streams
+	stdout, stderr := cfg.Stdout, cfg.Stderr
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+
+	copyDone := make(chan error, 1)
+	go func() {
+		_, err := stdcopy.StdCopy(stdout, stderr, attach.Reader)
+		copyDone <- err
+	}()
+
+	select {
+	case err := <-copyDone:
+		if err != nil {
+			return -1, fmt.Errorf("failed to read exec output: %w", err)
+		}
+	case <-ctx.Done():
+		return -1, ctx.Err()
+	}
+
+	inspect, err := c.cli.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		return -1, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+
+	return int64(inspect.ExitCode), nil
+}
+
 // GetContainerLogs retrieves container logs
 func (c *Client) GetContainerLogs(ctx context.Context, containerID string) ([]byte, error) {
 	options := types.ContainerLogsOptions{
@@ -193,10 +257,10 @@ func stripDockerLogHeaders(logs []byte) []byte {
 	// Docker multiplexed stream format: [8 bytes header][payload]
 	// Header: [stream_type, 0, 0, 0, size1, size2, size3, size4]
 	// For simplicity, we'll strip headers by looking for the pattern
-	
+
 	result := make([]byte, 0, len(logs))
 	i := 0
-	
+
 	for i < len(logs) {
 		// Check if we have at least 8 bytes for header
 		if i+8 > len(logs) {
@@ -207,10 +271,10 @@ func stripDockerLogHeaders(logs []byte) []byte {
 
 		// Read payload size from header (big-endian uint32 at offset 4)
 		size := int(logs[i+4])<<24 | int(logs[i+5])<<16 | int(logs[i+6])<<8 | int(logs[i+7])
-		
+
 		// Skip header (8 bytes)
 		i += 8
-		
+
 		// Append payload
 		if i+size <= len(logs) {
 			result = append(result, logs[i:i+size]...)
@@ -228,14 +292,23 @@ func stripDockerLogHeaders(logs []byte) []byte {
 // ContainerConfig holds container creation configuration
 type ContainerConfig struct {
 	Image       string
-	Handler     string
-	Payload     []byte
+	Entrypoint  []string // Overrides the image entrypoint when set
+	Labels      map[string]string
 	Environment map[string]string
 	MemoryLimit int64
 	CPULimit    int64
 	CodePath    string // Path to function code on host
 }
 
+// ExecConfig holds the command and I/O for Exec
+type ExecConfig struct {
+	Cmd    []string
+	Env    []string
+	Stdin  []byte // Written to the process and then closed; nil for no stdin
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
 // ContainerStats holds container resource usage statistics
 type ContainerStats struct {
 	CPUUsage    int64
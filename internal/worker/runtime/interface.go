@@ -13,25 +13,38 @@ type Runtime interface {
 	GetCapabilities() RuntimeCapabilities
 }
 
+// Invalidator is implemented by runtimes that keep per-function state, such
+// as warm containers, between executions
+type Invalidator interface {
+	// InvalidateFunction drops the state kept for the function's current
+	// code, or for all of its versions if allVersions is set
+	InvalidateFunction(functionID string, allVersions bool)
+}
+
 // ExecutionSpec defines function execution parameters
 type ExecutionSpec struct {
-	FunctionID  string                 `json:"function_id"`
-	Code        []byte                 `json:"code"`
-	Runtime     types.RuntimeType      `json:"runtime"`
-	Handler     string                 `json:"handler"`
-	Payload     []byte                 `json:"payload"`
-	Environment map[string]string      `json:"environment"`
-	Timeout     time.Duration          `json:"timeout"`
-	Limits      ResourceLimits         `json:"limits"`
+	InvocationID string            `json:"invocation_id"`
+	FunctionID   string            `json:"function_id"`
+	Version      int               `json:"version,omitempty"` // Published version number; 0 for the function's current code
+	Code         []byte            `json:"code"`
+	CodeChecksum string            `json:"code_checksum"`
+	CodeFormat   types.CodeFormat  `json:"code_format"`
+	Artifact     []byte            `json:"artifact,omitempty"` // Deploy-time build output (see package build)
+	Runtime      types.RuntimeType `json:"runtime"`
+	Handler      string            `json:"handler"`
+	Payload      []byte            `json:"payload"`
+	Environment  map[string]string `json:"environment"`
+	Timeout      time.Duration     `json:"timeout"`
+	Limits       ResourceLimits    `json:"limits"`
 }
 
 // ExecutionResult represents function execution result
 type ExecutionResult struct {
-	Status  types.ExecutionStatus   `json:"status"`
-	Result  []byte                  `json:"result,omitempty"`
-	Error   *types.ExecutionError   `json:"error,omitempty"`
-	Metrics types.ExecutionMetrics  `json:"metrics"`
-	Logs    []LogEntry              `json:"logs,omitempty"`
+	Status  types.ExecutionStatus  `json:"status"`
+	Result  []byte                 `json:"result,omitempty"`
+	Error   *types.ExecutionError  `json:"error,omitempty"`
+	Metrics types.ExecutionMetrics `json:"metrics"`
+	Logs    []types.LogEntry       `json:"logs,omitempty"`
 }
 
 // ResourceLimits defines resource constraints
@@ -48,10 +61,3 @@ type RuntimeCapabilities struct {
 	MaxTimeout time.Duration `json:"max_timeout"`
 	MaxMemory  int64         `json:"max_memory"`
 }
-
-// LogEntry represents a log entry
-type LogEntry struct {
-	Timestamp time.Time `json:"timestamp"`
-	Level     string    `json:"level"`
-	Message   string    `json:"message"`
-}
@@ -0,0 +1,414 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"GoFaas/internal/observability/logging"
+	"GoFaas/internal/worker/runtime/docker"
+)
+
+// idleEntrypoint keeps a pooled container alive between invocations;
+// functions are started inside it with docker exec
+var idleEntrypoint = []string{"tail", "-f", "/dev/null"}
+
+// PoolConfig holds warm container pool configuration
+type PoolConfig struct {
+	MinIdle     int           // Containers kept pre-started per function once it has been invoked
+	MaxIdle     int           // Idle containers above this are removed on release
+	IdleTimeout time.Duration // Idle containers unused for this long are evicted
+}
+
+// poolKey identifies containers that can serve an invocation. Resource
+// limits are part of the key because they are fixed at container creation,
+// and the handler because compiled runtimes bind it into the bootstrap.
+// Prebuilt separates containers running a deploy-time build from ones that
+// compile on each call, so a finished build replaces the latter. Each
+// published version gets its own pools, so traffic to several versions of
+// a function does not evict one another.
+type poolKey struct {
+	FunctionID  string
+	Version     int
+	Checksum    string
+	Handler     string
+	Prebuilt    bool
+	MemoryBytes int64
+	CPULimit    int64
+}
+
+// pooledContainer is a started container waiting for work
+type pooledContainer struct {
+	id       string
+	lastUsed time.Time
+}
+
+// functionPool holds the warm containers for a single pool key
+type functionPool struct {
+	key      poolKey
+	codeDir  string
+	cfg      docker.ContainerConfig
+	idle     []*pooledContainer
+	active   int  // Containers currently checked out or being created
+	warming  int  // Containers being created by prewarm
+	retired  bool // Set when the function's code changed; drained on release
+	lastUsed time.Time
+}
+
+// ContainerPool keeps pre-started containers per function so repeat
+// invocations skip container creation
+type ContainerPool struct {
+	client  *docker.Client
+	workDir string
+	cfg     PoolConfig
+	logger  logging.Logger
+
+	mu    sync.Mutex
+	pools map[poolKey]*functionPool
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewContainerPool creates a new container pool and starts idle eviction
+func NewContainerPool(client *docker.Client, workDir string, cfg PoolConfig, logger logging.Logger) *ContainerPool {
+	if cfg.MaxIdle < cfg.MinIdle {
+		cfg.MaxIdle = cfg.MinIdle
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 5 * time.Minute
+	}
+
+	p := &ContainerPool{
+		client:  client,
+		workDir: workDir,
+		cfg:     cfg,
+		logger:  logger,
+		pools:   make(map[poolKey]*functionPool),
+		stopCh:  make(chan struct{}),
+	}
+
+	p.wg.Add(1)
+	go p.evictLoop()
+
+	return p
+}
+
+// Acquire checks out a running container for the given function spec,
+// creating one if no warm container is available
+func (p *ContainerPool) Acquire(ctx context.Context, spec ExecutionSpec, image string) (*functionPool, *pooledContainer, error) {
+	key := poolKey{
+		FunctionID:  spec.FunctionID,
+		Version:     spec.Version,
+		Checksum:    spec.CodeChecksum,
+		Handler:     spec.Handler,
+		Prebuilt:    len(spec.Artifact) > 0,
+		MemoryBytes: spec.Limits.MemoryBytes,
+		CPULimit:    spec.Limits.CPUShares * 1000000, // Convert to nanocpus
+	}
+
+	p.mu.Lock()
+	fp, err := p.getOrCreatePoolLocked(key, spec, image)
+	if err != nil {
+		p.mu.Unlock()
+		return nil, nil, err
+	}
+
+	fp.active++
+	fp.lastUsed = time.Now()
+
+	var c *pooledContainer
+	if n := len(fp.idle); n > 0 {
+		c = fp.idle[n-1]
+		fp.idle = fp.idle[:n-1]
+	}
+	p.mu.Unlock()
+
+	if c != nil {
+		p.logger.Debug("Reusing warm container",
+			logging.F("container_id", c.id),
+			logging.F("function_id", key.FunctionID),
+		)
+		p.prewarm(fp)
+		return fp, c, nil
+	}
+
+	c, err = p.startContainer(ctx, fp)
+	if err != nil {
+		p.mu.Lock()
+		fp.active--
+		p.mu.Unlock()
+		return nil, nil, err
+	}
+
+	p.prewarm(fp)
+	return fp, c, nil
+}
+
+// Release returns a checked out container to its pool. Unhealthy
+// containers (e.g. killed after a timeout) are removed instead.
+func (p *ContainerPool) Release(fp *functionPool, c *pooledContainer, healthy bool) {
+	p.mu.Lock()
+	fp.active--
+	keep := healthy && !fp.retired && len(fp.idle) < p.cfg.MaxIdle
+	if keep {
+		c.lastUsed = time.Now()
+		fp.idle = append(fp.idle, c)
+	}
+	cleanupDir := fp.retired && fp.active == 0 && len(fp.idle) == 0
+	p.mu.Unlock()
+
+	if !keep {
+		p.removeContainer(c.id)
+	}
+	if cleanupDir {
+		os.RemoveAll(fp.codeDir)
+	}
+}
+
+// Close stops idle eviction and removes all idle containers
+func (p *ContainerPool) Close() {
+	close(p.stopCh)
+	p.wg.Wait()
+
+	p.mu.Lock()
+	pools := p.pools
+	p.pools = make(map[poolKey]*functionPool)
+	for _, fp := range pools {
+		fp.retired = true
+	}
+	p.mu.Unlock()
+
+	for _, fp := range pools {
+		p.drain(fp)
+	}
+}
+
+// getOrCreatePoolLocked returns the pool for key, retiring pools that hold
+// an older version of the same function. Must be called with p.mu held.
+func (p *ContainerPool) getOrCreatePoolLocked(key poolKey, spec ExecutionSpec, image string) (*functionPool, error) {
+	if fp, ok := p.pools[key]; ok {
+		return fp, nil
+	}
+
+	// The function's code or limits changed; stale containers must not serve
+	// new invocations
+	for k, old := range p.pools {
+		if k.FunctionID == key.FunctionID && k.Version == key.Version {
+			p.logger.Info("Invalidating warm containers for updated function",
+				logging.F("function_id", key.FunctionID),
+				logging.F("old_checksum", k.Checksum),
+				logging.F("new_checksum", key.Checksum),
+			)
+			p.retireLocked(k, old)
+		}
+	}
+
+	codeDir := filepath.Join(p.workDir, key.FunctionID, fmt.Sprintf("%d", time.Now().UnixNano()))
+	if err := os.MkdirAll(codeDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create code directory: %w", err)
+	}
+	if err := prepareFunctionDir(codeDir, spec); err != nil {
+		os.RemoveAll(codeDir)
+		return nil, fmt.Errorf("failed to prepare function directory: %w", err)
+	}
+
+	fp := &functionPool{
+		key:     key,
+		codeDir: codeDir,
+		cfg: docker.ContainerConfig{
+			Image:       image,
+			Entrypoint:  idleEntrypoint,
+			Labels:      map[string]string{"faas.function_id": key.FunctionID},
+			MemoryLimit: key.MemoryBytes,
+			CPULimit:    key.CPULimit,
+			CodePath:    codeDir,
+		},
+	}
+	p.pools[key] = fp
+
+	return fp, nil
+}
+
+// Invalidate retires the pools of a function's current code, or of all its
+// versions if allVersions is set, removing their idle containers now
+// rather than on the function's next invocation. Containers checked out
+// finish their execution and are removed on release.
+func (p *ContainerPool) Invalidate(functionID string, allVersions bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	retired := 0
+	for k, fp := range p.pools {
+		if k.FunctionID == functionID && (allVersions || k.Version == 0) {
+			p.retireLocked(k, fp)
+			retired++
+		}
+	}
+
+	if retired > 0 {
+		p.logger.Info("Invalidated warm containers for changed function",
+			logging.F("function_id", functionID),
+			logging.F("pools", retired),
+		)
+	}
+}
+
+// retireLocked stops fp from serving invocations and drains it in the
+// background. Must be called with p.mu held.
+func (p *ContainerPool) retireLocked(key poolKey, fp *functionPool) {
+	fp.retired = true
+	delete(p.pools, key)
+	go p.drain(fp)
+}
+
+// startContainer creates and starts a container for fp
+func (p *ContainerPool) startContainer(ctx context.Context, fp *functionPool) (*pooledContainer, error) {
+	containerID, err := p.client.CreateContainer(ctx, fp.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+
+	if err := p.client.StartContainer(ctx, containerID); err != nil {
+		p.removeContainer(containerID)
+		return nil, fmt.Errorf("failed to start container: %w", err)
+	}
+
+	p.logger.Debug("Container started",
+		logging.F("container_id", containerID),
+		logging.F("function_id", fp.key.FunctionID),
+	)
+
+	return &pooledContainer{id: containerID, lastUsed: time.Now()}, nil
+}
+
+// prewarm starts containers in the background until fp has MinIdle idle
+// containers
+func (p *ContainerPool) prewarm(fp *functionPool) {
+	p.mu.Lock()
+	missing := p.cfg.MinIdle - len(fp.idle) - fp.warming
+	if fp.retired || missing <= 0 {
+		p.mu.Unlock()
+		return
+	}
+	fp.active += missing
+	fp.warming += missing
+	p.mu.Unlock()
+
+	for i := 0; i < missing; i++ {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+
+			c, err := p.startContainer(ctx, fp)
+
+			p.mu.Lock()
+			fp.warming--
+			if err != nil {
+				fp.active--
+			}
+			p.mu.Unlock()
+
+			if err != nil {
+				p.logger.Warn("Failed to prewarm container",
+					logging.F("function_id", fp.key.FunctionID),
+					logging.F("error", err),
+				)
+				return
+			}
+			p.Release(fp, c, true)
+		}()
+	}
+}
+
+// evictLoop periodically removes containers that have been idle too long
+func (p *ContainerPool) evictLoop() {
+	defer p.wg.Done()
+
+	interval := p.cfg.IdleTimeout / 2
+	if interval < 10*time.Second {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+			p.evictIdle()
+		}
+	}
+}
+
+// evictIdle removes idle containers unused for longer than IdleTimeout and
+// drops pools that are left empty
+func (p *ContainerPool) evictIdle() {
+	cutoff := time.Now().Add(-p.cfg.IdleTimeout)
+	var expired []string
+	var emptyDirs []string
+
+	p.mu.Lock()
+	for key, fp := range p.pools {
+		kept := fp.idle[:0]
+		for _, c := range fp.idle {
+			if c.lastUsed.Before(cutoff) {
+				expired = append(expired, c.id)
+			} else {
+				kept = append(kept, c)
+			}
+		}
+		fp.idle = kept
+
+		if len(fp.idle) == 0 && fp.active == 0 && fp.lastUsed.Before(cutoff) {
+			delete(p.pools, key)
+			emptyDirs = append(emptyDirs, fp.codeDir)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, id := range expired {
+		p.removeContainer(id)
+	}
+	for _, dir := range emptyDirs {
+		os.RemoveAll(dir)
+	}
+
+	if len(expired) > 0 {
+		p.logger.Debug("Evicted idle containers", logging.F("count", len(expired)))
+	}
+}
+
+// drain removes the idle containers of a retired pool. The code directory
+// is removed once no container is checked out.
+func (p *ContainerPool) drain(fp *functionPool) {
+	p.mu.Lock()
+	idle := fp.idle
+	fp.idle = nil
+	cleanupDir := fp.active == 0
+	p.mu.Unlock()
+
+	for _, c := range idle {
+		p.removeContainer(c.id)
+	}
+	if cleanupDir {
+		os.RemoveAll(fp.codeDir)
+	}
+}
+
+// removeContainer removes a container (best effort)
+func (p *ContainerPool) removeContainer(containerID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := p.client.RemoveContainer(ctx, containerID); err != nil {
+		p.logger.Warn("Failed to remove container",
+			logging.F("container_id", containerID),
+			logging.F("error", err),
+		)
+	}
+}
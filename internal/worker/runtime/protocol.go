@@ -0,0 +1,199 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"GoFaas/internal/build/archive"
+	"GoFaas/internal/worker/runtime/bootstrap"
+	"GoFaas/pkg/types"
+)
+
+// The worker talks to a function through its bootstrap (see package
+// bootstrap): one protocolRequest is written to the process's stdin, one
+// protocolResponse is read from its stdout, and stderr carries logs.
+
+const (
+	// maxLogBytes caps the log output kept per invocation
+	maxLogBytes = 256 * 1024
+
+	// artifactName is the file name of a pre-built function executable
+	artifactName = "handler"
+)
+
+// protocolRequest is the invocation request sent to a bootstrap
+type protocolRequest struct {
+	InvocationID string          `json:"invocation_id"`
+	FunctionID   string          `json:"function_id"`
+	Handler      string          `json:"handler"`
+	Payload      json.RawMessage `json:"payload"`
+	DeadlineMs   int64           `json:"deadline_ms"`
+}
+
+// protocolResponse is the invocation response written by a bootstrap
+type protocolResponse struct {
+	Result json.RawMessage       `json:"result,omitempty"`
+	Error  *types.ExecutionError `json:"error,omitempty"`
+}
+
+// encodeRequest builds the protocol request for spec
+func encodeRequest(spec ExecutionSpec, deadline time.Time) ([]byte, error) {
+	payload := json.RawMessage(spec.Payload)
+	if len(payload) == 0 {
+		payload = json.RawMessage("null")
+	}
+
+	return json.Marshal(protocolRequest{
+		InvocationID: spec.InvocationID,
+		FunctionID:   spec.FunctionID,
+		Handler:      spec.Handler,
+		Payload:      payload,
+		DeadlineMs:   deadline.UnixMilli(),
+	})
+}
+
+// applyResponse fills in result from the bootstrap's exit code and stdout.
+// A handler error reported through the protocol fails the invocation with
+// that error; a missing or malformed response means the bootstrap itself
+// crashed, so the tail of the logs is reported instead.
+func applyResponse(result *ExecutionResult, exitCode int64, stdout []byte) {
+	var resp protocolResponse
+	if err := json.Unmarshal(bytes.TrimSpace(stdout), &resp); err != nil {
+		result.Status = types.StatusFailed
+		result.Error = &types.ExecutionError{
+			Type:    types.ErrorTypeRuntime,
+			Message: fmt.Sprintf("Function exited with code %d without a valid response", exitCode),
+			Stack:   logTail(result.Logs, 50),
+		}
+		return
+	}
+
+	if resp.Error != nil {
+		result.Status = types.StatusFailed
+		result.Error = resp.Error
+		return
+	}
+
+	result.Status = types.StatusCompleted
+	result.Result = resp.Result
+}
+
+// prepareFunctionDir writes the function code and its bootstrap into dir.
+// Archives are extracted, preferring the build bundle (which includes the
+// installed dependencies) over the uploaded archive; single-file Go
+// functions get their pre-built executable next to the source.
+func prepareFunctionDir(dir string, spec ExecutionSpec) error {
+	switch {
+	case spec.CodeFormat.IsArchive() && len(spec.Artifact) > 0:
+		if err := archive.Extract(spec.Artifact, types.CodeFormatTarGz, dir); err != nil {
+			return fmt.Errorf("failed to extract build bundle: %w", err)
+		}
+	case spec.CodeFormat.IsArchive():
+		if err := archive.Extract(spec.Code, spec.CodeFormat, dir); err != nil {
+			return fmt.Errorf("failed to extract code archive: %w", err)
+		}
+	default:
+		if _, err := writeCodeToFile(dir, spec.Runtime, spec.Code); err != nil {
+			return err
+		}
+		if len(spec.Artifact) > 0 {
+			if err := os.WriteFile(filepath.Join(dir, artifactName), spec.Artifact, 0755); err != nil {
+				return fmt.Errorf("failed to write build artifact: %w", err)
+			}
+		}
+	}
+
+	file, err := bootstrap.Generate(spec.Runtime, spec.Handler)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, file.Name), file.Content, 0644); err != nil {
+		return fmt.Errorf("failed to write bootstrap: %w", err)
+	}
+
+	return nil
+}
+
+// logWriter turns a process's stderr into timestamped log entries, one per
+// line. It is safe for concurrent use.
+type logWriter struct {
+	mu      sync.Mutex
+	partial []byte
+	entries []types.LogEntry
+	size    int
+	dropped bool
+}
+
+// Write implements io.Writer
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.addLocked(string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Entries flushes any unterminated line and returns the collected entries
+func (w *logWriter) Entries() []types.LogEntry {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		w.addLocked(string(w.partial))
+		w.partial = nil
+	}
+
+	return w.entries
+}
+
+func (w *logWriter) addLocked(line string) {
+	if w.dropped {
+		return
+	}
+
+	if w.size+len(line) > maxLogBytes {
+		w.dropped = true
+		w.entries = append(w.entries, types.LogEntry{
+			Timestamp: time.Now(),
+			Level:     "warn",
+			Message:   "log output truncated",
+		})
+		return
+	}
+
+	w.size += len(line)
+	w.entries = append(w.entries, types.LogEntry{
+		Timestamp: time.Now(),
+		Level:     "info",
+		Message:   line,
+	})
+}
+
+// logTail joins the last n log messages
+func logTail(entries []types.LogEntry, n int) string {
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+
+	var buf bytes.Buffer
+	for _, e := range entries {
+		buf.WriteString(e.Message)
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
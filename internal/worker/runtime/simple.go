@@ -1,11 +1,12 @@
 package runtime
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"time"
 
 	"GoFaas/pkg/types"
@@ -37,39 +38,28 @@ func (r *SimpleRuntime) Execute(ctx context.Context, spec ExecutionSpec) (*Execu
 	execCtx, cancel := context.WithTimeout(ctx, spec.Timeout)
 	defer cancel()
 
-	// Create temporary directory for this execution
-	execDir := filepath.Join(r.workDir, spec.FunctionID)
-	if err := os.MkdirAll(execDir, 0755); err != nil {
+	// Create a directory of its own for this execution, as invocations of
+	// the same function may run at the same time
+	execDir, err := os.MkdirTemp(r.workDir, spec.FunctionID+"-")
+	if err != nil {
 		return nil, fmt.Errorf("failed to create execution directory: %w", err)
 	}
 	defer os.RemoveAll(execDir) // Cleanup after execution
 
-	// Write function code to file
-	var codePath string
+	// Write function code and bootstrap
 	var cmd *exec.Cmd
 
 	switch spec.Runtime {
 	case types.RuntimeGo:
-		codePath = filepath.Join(execDir, "main.go")
-		if err := os.WriteFile(codePath, spec.Code, 0644); err != nil {
-			return nil, fmt.Errorf("failed to write function code: %w", err)
+		if len(spec.Artifact) > 0 {
+			cmd = exec.CommandContext(execCtx, "./"+artifactName)
+		} else {
+			cmd = exec.CommandContext(execCtx, "go", "run", "main.go", "faas_bootstrap.go")
 		}
-		cmd = exec.CommandContext(execCtx, "go", "run", codePath)
-
 	case types.RuntimePython:
-		codePath = filepath.Join(execDir, "main.py")
-		if err := os.WriteFile(codePath, spec.Code, 0644); err != nil {
-			return nil, fmt.Errorf("failed to write function code: %w", err)
-		}
-		cmd = exec.CommandContext(execCtx, "python3", codePath)
-
+		cmd = exec.CommandContext(execCtx, "python3", "faas_bootstrap.py")
 	case types.RuntimeNodeJS:
-		codePath = filepath.Join(execDir, "main.js")
-		if err := os.WriteFile(codePath, spec.Code, 0644); err != nil {
-			return nil, fmt.Errorf("failed to write function code: %w", err)
-		}
-		cmd = exec.CommandContext(execCtx, "node", codePath)
-
+		cmd = exec.CommandContext(execCtx, "node", "faas_bootstrap.js")
 	default:
 		return &ExecutionResult{
 			Status: types.StatusFailed,
@@ -83,52 +73,71 @@ func (r *SimpleRuntime) Execute(ctx context.Context, spec ExecutionSpec) (*Execu
 		}, nil
 	}
 
+	if err := prepareFunctionDir(execDir, spec); err != nil {
+		return nil, fmt.Errorf("failed to prepare function directory: %w", err)
+	}
+
+	request, err := encodeRequest(spec, startTime.Add(spec.Timeout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode invocation request: %w", err)
+	}
+
 	// Set environment variables
 	cmd.Env = os.Environ()
 	for key, value := range spec.Environment {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
 	}
 
-	// Pass payload as environment variable (simplified approach)
-	cmd.Env = append(cmd.Env, fmt.Sprintf("FUNCTION_PAYLOAD=%s", string(spec.Payload)))
-	cmd.Env = append(cmd.Env, fmt.Sprintf("FUNCTION_HANDLER=%s", spec.Handler))
-
+	var stdout bytes.Buffer
+	logs := &logWriter{}
 	cmd.Dir = execDir
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = logs
 
 	// Execute function
-	output, err := cmd.CombinedOutput()
+	err = cmd.Run()
 	endTime := time.Now()
 
 	result := &ExecutionResult{
 		Metrics: types.ExecutionMetrics{
 			Duration: endTime.Sub(startTime),
 		},
+		Logs: logs.Entries(),
+	}
+
+	// The invocation was cancelled; the process has been killed
+	if execCtx.Err() == context.Canceled {
+		result.Status = types.StatusCancelled
+		result.Error = &types.ExecutionError{
+			Type:    "CancelledError",
+			Message: "Function execution was cancelled",
+		}
+		return result, nil
 	}
 
 	// Check for timeout
 	if execCtx.Err() == context.DeadlineExceeded {
 		result.Status = types.StatusTimeout
 		result.Error = &types.ExecutionError{
-			Type:    "TimeoutError",
+			Type:    types.ErrorTypeTimeout,
 			Message: "Function execution timed out",
 		}
 		return result, nil
 	}
 
-	// Check for execution error
-	if err != nil {
+	// The process could not be started at all
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
 		result.Status = types.StatusFailed
 		result.Error = &types.ExecutionError{
-			Type:    "RuntimeError",
+			Type:    types.ErrorTypeRuntime,
 			Message: fmt.Sprintf("Function execution failed: %v", err),
-			Stack:   string(output),
 		}
 		return result, nil
 	}
 
-	// Success
-	result.Status = types.StatusCompleted
-	result.Result = output
+	applyResponse(result, int64(cmd.ProcessState.ExitCode()), stdout.Bytes())
 
 	return result, nil
 }
@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"GoFaas/pkg/types"
+)
+
+func TestSimpleRuntimeConcurrentExecutions(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 is not installed")
+	}
+
+	workDir := t.TempDir()
+	rt, err := NewSimpleRuntime(workDir)
+	if err != nil {
+		t.Fatalf("NewSimpleRuntime: %v", err)
+	}
+
+	// The handler checks its files are still there after the executions
+	// that finish earlier have cleaned up
+	code := []byte("import os, time\n\ndef handler(event, context):\n    time.sleep(event['sleep'])\n    if not os.path.exists('main.py'):\n        raise RuntimeError('function directory was removed')\n    return event['n']\n")
+
+	tests := []struct {
+		sleep float64
+		want  string
+	}{
+		{sleep: 0.5, want: "1"},
+		{sleep: 0.1, want: "2"},
+		{sleep: 0.3, want: "3"},
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*ExecutionResult, len(tests))
+	errs := make([]error, len(tests))
+	for i, tt := range tests {
+		wg.Add(1)
+		go func(i int, sleep float64, n string) {
+			defer wg.Done()
+			results[i], errs[i] = rt.Execute(context.Background(), ExecutionSpec{
+				InvocationID: fmt.Sprintf("inv-%d", i),
+				FunctionID:   "fn",
+				Code:         code,
+				Runtime:      types.RuntimePython,
+				Handler:      "main.handler",
+				Payload:      []byte(fmt.Sprintf(`{"sleep": %v, "n": %s}`, sleep, n)),
+				Timeout:      10 * time.Second,
+			})
+		}(i, tt.sleep, tt.want)
+	}
+	wg.Wait()
+
+	for i, tt := range tests {
+		if errs[i] != nil {
+			t.Fatalf("execution %d: %v", i, errs[i])
+		}
+		if results[i].Status != types.StatusCompleted {
+			t.Errorf("execution %d: status = %s, error %+v", i, results[i].Status, results[i].Error)
+			continue
+		}
+		if string(results[i].Result) != tt.want {
+			t.Errorf("execution %d: result = %s, want %s", i, results[i].Result, tt.want)
+		}
+	}
+
+	// Every execution cleans up its own directory
+	entries, err := os.ReadDir(workDir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("work directory has %d entries left, want none", len(entries))
+	}
+}
@@ -4,69 +4,310 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
+
+	functions "GoFaas/internal/core/function"
 	"GoFaas/internal/core/invocation"
 	"GoFaas/internal/messaging"
 	"GoFaas/internal/observability/logging"
 	"GoFaas/internal/storage/function"
 	"GoFaas/internal/storage/metadata"
 	"GoFaas/internal/worker/runtime"
+	"GoFaas/pkg/errors"
 	"GoFaas/pkg/types"
 )
 
+// reapBatchSize bounds the expired messages claimed per reaper pass
+const reapBatchSize = 100
+
+// heartbeatInterval is how often the worker reports itself as a consumer
+// of the execution queues and to the registry, well within
+// messaging.ConsumerTimeout
+const heartbeatInterval = messaging.ConsumerTimeout / 3
+
+// staleWorkerAge is how long a worker stays in the registry after its last
+// heartbeat before a starting worker removes it
+const staleWorkerAge = 24 * time.Hour
+
+// defaultDeferDelay is used when Config.ConcurrencyDeferDelay is not
+// positive
+const defaultDeferDelay = time.Second
+
+// errConcurrencyLimit is returned by executeFunction when the function
+// already runs as many invocations as its concurrency allows
+var errConcurrencyLimit = fmt.Errorf("function is at its concurrency limit")
+
 // Worker processes function execution requests from the queue
 type Worker struct {
 	id             string
 	queue          messaging.Queue
+	notifier       messaging.Notifier
+	concurrency    messaging.Semaphore
+	registry       metadata.WorkerRepository
 	functionRepo   metadata.FunctionRepository
 	invocationRepo metadata.InvocationRepository
+	versionRepo    metadata.VersionRepository
 	functionStore  function.Storage
 	runtime        runtime.Runtime
 	invocationSvc  *invocation.Service
 	logger         logging.Logger
 	stopCh         chan struct{}
+	stopOnce       sync.Once
+	doneCh         chan struct{}
+	drainCh        chan struct{} // Closed when the registry asks the worker to drain
+	drainOnce      sync.Once
+	slotsDone      chan struct{} // Closed when every slot stopped taking messages
+	runtimeType    types.WorkerRuntime
+	labels         map[string]string
+	startedAt      time.Time
+
+	leaseRenewInterval time.Duration
+	reapInterval       time.Duration
+	slots              int
+	drainTimeout       time.Duration
+	deferDelay         time.Duration
+	budget             *budget
+
+	// Execution queue lanes, highest priority first, and the function
+	// placements the worker satisfies, whose queues it also takes from
+	lanesMu    sync.Mutex
+	lanes      []*lane
+	placements []*types.Placement
+
+	// Cancel functions of the executions in progress, by invocation ID
+	runningMu sync.Mutex
+	running   map[string]context.CancelFunc
 }
 
 // Config holds worker configuration
 type Config struct {
 	ID             string
 	Queue          messaging.Queue
+	Notifier       messaging.Notifier        // Receives cancellation and function change signals; optional
+	Concurrency    messaging.Semaphore       // Enforces functions' max_concurrency across workers; optional
+	Registry       metadata.WorkerRepository // Registers the worker and tells it to drain; optional
 	FunctionRepo   metadata.FunctionRepository
 	InvocationRepo metadata.InvocationRepository
+	VersionRepo    metadata.VersionRepository
 	FunctionStore  function.Storage
 	Runtime        runtime.Runtime
 	InvocationSvc  *invocation.Service
 	Logger         logging.Logger
+	RuntimeType    types.WorkerRuntime // How Runtime runs functions; matched against function placements
+	Labels         map[string]string   // Reported to the registry and matched against function placements
+
+	// Leases of messages being executed are renewed this often; it must be
+	// well below the queue's visibility timeout
+	LeaseRenewInterval time.Duration
+	// Executions whose lease expired are looked for this often; zero
+	// disables reaping in this worker
+	ReapInterval time.Duration
+	// Relative share of dequeues per priority lane while every lane has
+	// messages; missing or non-positive weights count as 1
+	LaneWeights map[types.Priority]int
+	// Executions run concurrently, each slot dequeuing on its own; fewer
+	// than 1 means 1
+	Slots int
+	// Host resources the running executions may reserve by their limits;
+	// zero for no budget
+	CPUBudget   float64 // CPUs
+	MemoryBytes int64
+	// How long Stop waits for executions in progress before abandoning
+	// them to be redelivered; zero waits without a deadline
+	DrainTimeout time.Duration
+	// Invocations of a function at its concurrency limit are put back in
+	// the queue for about this long; defaults to a second
+	ConcurrencyDeferDelay time.Duration
+}
+
+// lane is a priority's execution queue and its share of dequeues
+type lane struct {
+	priority types.Priority
+	queue    string
+	weight   int
+	current  int // Credit of the smooth weighted round-robin
 }
 
 // NewWorker creates a new worker
 func NewWorker(cfg Config) *Worker {
+	lanes := make([]*lane, 0, len(types.Priorities))
+	for _, priority := range types.Priorities {
+		weight := cfg.LaneWeights[priority]
+		if weight <= 0 {
+			weight = 1
+		}
+		lanes = append(lanes, &lane{priority: priority, queue: invocation.ExecutionQueue(priority), weight: weight})
+	}
+
+	slots := cfg.Slots
+	if slots < 1 {
+		slots = 1
+	}
+
+	deferDelay := cfg.ConcurrencyDeferDelay
+	if deferDelay <= 0 {
+		deferDelay = defaultDeferDelay
+	}
+
 	return &Worker{
 		id:             cfg.ID,
 		queue:          cfg.Queue,
+		notifier:       cfg.Notifier,
+		concurrency:    cfg.Concurrency,
+		registry:       cfg.Registry,
 		functionRepo:   cfg.FunctionRepo,
 		invocationRepo: cfg.InvocationRepo,
+		versionRepo:    cfg.VersionRepo,
 		functionStore:  cfg.FunctionStore,
 		runtime:        cfg.Runtime,
 		invocationSvc:  cfg.InvocationSvc,
 		logger:         cfg.Logger.WithFields(logging.F("worker_id", cfg.ID)),
 		stopCh:         make(chan struct{}),
+		doneCh:         make(chan struct{}),
+		drainCh:        make(chan struct{}),
+		slotsDone:      make(chan struct{}),
+		runtimeType:    cfg.RuntimeType,
+		labels:         cfg.Labels,
+		running:        make(map[string]context.CancelFunc),
+
+		leaseRenewInterval: cfg.LeaseRenewInterval,
+		reapInterval:       cfg.ReapInterval,
+		slots:              slots,
+		drainTimeout:       cfg.DrainTimeout,
+		deferDelay:         deferDelay,
+		budget:             newBudget(cfg.CPUBudget, cfg.MemoryBytes),
+		lanes:              lanes,
 	}
 }
 
-// Start starts the worker
+// Start starts the worker and runs until it is stopped. Cancelling ctx
+// abandons the executions in progress; Stop lets them finish first.
 func (w *Worker) Start(ctx context.Context) error {
-	w.logger.Info("Worker starting")
+	defer close(w.doneCh)
+
+	w.startedAt = time.Now()
+	w.logger.Info("Worker starting", logging.F("slots", w.slots))
+
+	if w.notifier != nil {
+		sub, err := w.notifier.Subscribe(ctx, invocation.CancelChannel)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to cancellations: %w", err)
+		}
+		defer sub.Close()
+
+		go w.watchCancellations(sub)
+
+		// Runtimes that keep warm state per function drop it as soon as
+		// the function changes
+		if invalidator, ok := w.runtime.(runtime.Invalidator); ok {
+			changes, err := w.notifier.Subscribe(ctx, functions.ChangedChannel)
+			if err != nil {
+				return fmt.Errorf("failed to subscribe to function changes: %w", err)
+			}
+			defer changes.Close()
+
+			go w.watchFunctionChanges(changes, invalidator)
+		}
+	}
+
+	w.refreshPlacements(ctx)
+
+	if w.reapInterval > 0 {
+		go w.reapLoop(ctx)
+	}
+
+	w.register(ctx)
+
+	heartbeatCtx, stopHeartbeats := context.WithCancel(ctx)
+	defer stopHeartbeats()
+
+	var heartbeats sync.WaitGroup
+	heartbeats.Add(1)
+	go func() {
+		defer heartbeats.Done()
+		w.heartbeatLoop(heartbeatCtx)
+	}()
+
+	// Executions outlive a stop until the drain deadline, when they are
+	// abandoned
+	execCtx, abandon := context.WithCancel(ctx)
+	defer abandon()
+
+	var slots sync.WaitGroup
+	for i := 0; i < w.slots; i++ {
+		slots.Add(1)
+		go func() {
+			defer slots.Done()
+			w.slotLoop(execCtx)
+		}()
+	}
+
+	go func() {
+		slots.Wait()
+		close(w.slotsDone)
+	}()
+
+	select {
+	case <-ctx.Done():
+		w.logger.Info("Worker stopping due to context cancellation")
+		<-w.slotsDone
+		return ctx.Err()
+	case <-w.stopCh:
+	}
 
+	w.logger.Info("Worker draining", logging.F("in_flight", w.inFlight()))
+
+	var deadline <-chan time.Time
+	if w.drainTimeout > 0 {
+		timer := time.NewTimer(w.drainTimeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	select {
+	case <-w.slotsDone:
+		w.logger.Info("Worker stopping")
+	case <-deadline:
+		w.logger.Warn("Drain deadline reached, abandoning executions", logging.F("in_flight", w.inFlight()))
+		abandon()
+		<-w.slotsDone
+	case <-ctx.Done():
+		<-w.slotsDone
+		return ctx.Err()
+	}
+
+	// Stop heartbeats first, so they do not register the worker again
+	stopHeartbeats()
+	heartbeats.Wait()
+	w.deregister(ctx)
+
+	return nil
+}
+
+// Stop stops taking messages and waits for the executions in progress to
+// finish, up to the drain deadline
+func (w *Worker) Stop() {
+	w.stopOnce.Do(func() { close(w.stopCh) })
+	<-w.doneCh
+}
+
+// slotLoop processes one message at a time until the worker is stopped or
+// drained
+func (w *Worker) slotLoop(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
-			w.logger.Info("Worker stopping due to context cancellation")
-			return ctx.Err()
+			return
 		case <-w.stopCh:
-			w.logger.Info("Worker stopping")
-			return nil
+			return
+		case <-w.drainCh:
+			return
 		default:
 			if err := w.processNextMessage(ctx); err != nil {
 				w.logger.Error("Failed to process message", logging.F("error", err))
@@ -77,15 +318,17 @@ func (w *Worker) Start(ctx context.Context) error {
 	}
 }
 
-// Stop stops the worker
-func (w *Worker) Stop() {
-	close(w.stopCh)
+// inFlight returns the number of executions in progress
+func (w *Worker) inFlight() int {
+	w.runningMu.Lock()
+	defer w.runningMu.Unlock()
+	return len(w.running)
 }
 
 // processNextMessage dequeues and processes a single message
 func (w *Worker) processNextMessage(ctx context.Context) error {
 	// Dequeue message with timeout
-	msg, err := w.queue.Dequeue(ctx, invocation.ExecutionQueueName, 5*time.Second)
+	msg, err := w.queue.DequeueFirst(ctx, w.laneOrder(), 5*time.Second)
 	if err != nil {
 		return fmt.Errorf("failed to dequeue message: %w", err)
 	}
@@ -112,8 +355,56 @@ func (w *Worker) processNextMessage(ctx context.Context) error {
 		return nil
 	}
 
+	// Register before checking for an earlier cancellation, so a
+	// cancellation signalled in between is not missed
+	execCtx, cancel := context.WithCancel(ctx)
+	w.trackExecution(execReq.InvocationID, cancel)
+	defer w.untrackExecution(execReq.InvocationID)
+	defer cancel()
+
+	var leaseLost atomic.Bool
+	go w.keepLease(execCtx, msg, func() {
+		leaseLost.Store(true)
+		cancel()
+	})
+
+	if w.isCancelled(ctx, execReq.InvocationID) {
+		w.logger.Info("Skipping cancelled invocation", logging.F("invocation_id", execReq.InvocationID))
+		w.ackMessage(ctx, msg)
+		return nil
+	}
+
 	// Execute function
-	result, err := w.executeFunction(ctx, execReq)
+	startedAt := time.Now()
+	result, err := w.executeFunction(execCtx, execReq)
+
+	if err == errConcurrencyLimit {
+		w.deferExecution(ctx, msg, execReq)
+		return nil
+	}
+
+	// Another worker owns the message now and decides what happens to the
+	// invocation
+	if leaseLost.Load() {
+		w.logger.Warn("Abandoned execution after losing its lease", logging.F("invocation_id", execReq.InvocationID))
+		return nil
+	}
+
+	// Abandoned when the worker stopped; the message is redelivered once
+	// its lease expires
+	if ctx.Err() != nil {
+		w.logger.Warn("Abandoned execution at shutdown", logging.F("invocation_id", execReq.InvocationID))
+		return nil
+	}
+
+	// Cancelled while running: the invocation is already recorded as
+	// cancelled and must not be retried
+	if execCtx.Err() == context.Canceled && ctx.Err() == nil {
+		w.logger.Info("Execution cancelled", logging.F("invocation_id", execReq.InvocationID))
+		w.ackMessage(ctx, msg)
+		return nil
+	}
+
 	if err != nil {
 		w.logger.Error("Failed to execute function",
 			logging.F("invocation_id", execReq.InvocationID),
@@ -121,17 +412,42 @@ func (w *Worker) processNextMessage(ctx context.Context) error {
 			logging.F("error", err),
 		)
 
-		// Retry logic: if attempts < 3, nack and retry
-		if msg.Attempts < 3 {
-			w.queue.Nack(ctx, msg)
-			return nil
+		// The platform could not run the function; treat it as a failed
+		// execution so the retry policy decides what happens next
+		result = &invocation.ExecutionResult{
+			Status: types.StatusFailed,
+			Error: &types.ExecutionError{
+				Type:    types.ErrorTypeSystem,
+				Message: err.Error(),
+			},
 		}
+	}
 
-		// Max retries exceeded, dead letter
-		w.queue.DeadLetter(ctx, msg, fmt.Sprintf("max retries exceeded: %v", err))
-		return nil
+	attempt := &types.InvocationAttempt{
+		ID:           uuid.New().String(),
+		InvocationID: execReq.InvocationID,
+		Attempt:      msg.Attempts,
+		WorkerID:     w.id,
+		Status:       result.Status,
+		Error:        result.Error,
+		Duration:     time.Since(startedAt),
+		StartedAt:    startedAt,
+		CompletedAt:  time.Now(),
 	}
 
+	if result.Status != types.StatusCompleted && result.Error != nil {
+		policy := w.retryPolicy(ctx, execReq)
+		if msg.Attempts < policy.MaxAttempts && policy.Retryable(result.Error.Type) {
+			retryAt := time.Now().Add(policy.Backoff(msg.Attempts))
+			attempt.RetryAt = &retryAt
+			w.recordAttempt(ctx, attempt)
+			w.retry(ctx, msg, attempt)
+			return nil
+		}
+	}
+
+	w.recordAttempt(ctx, attempt)
+
 	// Update invocation result
 	if err := w.invocationSvc.UpdateInvocationResult(ctx, execReq.InvocationID, *result); err != nil {
 		w.logger.Error("Failed to update invocation result",
@@ -141,25 +457,622 @@ func (w *Worker) processNextMessage(ctx context.Context) error {
 		// Still ack the message to avoid reprocessing
 	}
 
-	// Acknowledge message
-	if err := w.queue.Ack(ctx, msg); err != nil {
-		w.logger.Error("Failed to acknowledge message",
-			logging.F("message_id", msg.ID),
-			logging.F("error", err),
-		)
+	if err != nil {
+		// Out of attempts; keep the message for inspection
+		w.queue.DeadLetter(ctx, msg, fmt.Sprintf("max retries exceeded: %v", err))
+	} else {
+		w.ackMessage(ctx, msg)
 	}
 
 	w.logger.Info("Execution completed",
 		logging.F("invocation_id", execReq.InvocationID),
 		logging.F("status", result.Status),
-		logging.F("duration", result.Metrics.Duration),
+		logging.F("attempt", msg.Attempts),
+		logging.F("duration", attempt.Duration),
 	)
 
 	return nil
 }
 
-// executeFunction executes a function
+// keepLease renews the lease of a message while it is executed, until ctx
+// is done. If the lease is lost, the message was claimed by a reaper and
+// lost is called.
+func (w *Worker) keepLease(ctx context.Context, msg *messaging.Message, lost func()) {
+	if w.leaseRenewInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(w.leaseRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := w.queue.ExtendLease(ctx, msg)
+			if err == messaging.ErrLeaseLost {
+				lost()
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				// Try again on the next tick; the lease outlives several
+				w.logger.Warn("Failed to extend message lease",
+					logging.F("message_id", msg.ID),
+					logging.F("error", err),
+				)
+			}
+		}
+	}
+}
+
+// reapLoop recovers executions whose lease expired, until the worker is
+// stopped. Every worker reaps; claiming a message is atomic, so each
+// expired message is recovered by one of them.
+func (w *Worker) reapLoop(ctx context.Context) {
+	ticker := time.NewTicker(w.reapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			for _, queue := range w.executionQueues() {
+				messages, err := w.queue.ClaimExpired(ctx, queue, reapBatchSize)
+				if err != nil {
+					w.logger.Error("Failed to claim expired messages",
+						logging.F("queue", queue),
+						logging.F("error", err),
+					)
+					continue
+				}
+
+				for _, msg := range messages {
+					w.recoverExecution(ctx, msg)
+				}
+			}
+		}
+	}
+}
+
+// heartbeatLoop reports the worker as an active consumer of the execution
+// queues until it is stopped, picking up the queues of new placements it
+// satisfies on the way
+func (w *Worker) heartbeatLoop(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		w.refreshPlacements(ctx)
+
+		// Only a worker taking messages is a consumer
+		if !w.stopping() {
+			for _, queue := range w.executionQueues() {
+				if err := w.queue.Heartbeat(ctx, queue, w.id); err != nil {
+					w.logger.Error("Failed to send queue heartbeat",
+						logging.F("queue", queue),
+						logging.F("error", err),
+					)
+				}
+			}
+		}
+
+		w.heartbeatRegistry(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// stopping reports whether the worker stopped taking messages
+func (w *Worker) stopping() bool {
+	select {
+	case <-w.stopCh:
+		return true
+	case <-w.drainCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// register adds the worker to the registry, with its runtime's
+// capabilities, slots and labels, and removes workers long gone
+func (w *Worker) register(ctx context.Context) {
+	if w.registry == nil {
+		return
+	}
+
+	hostname, _ := os.Hostname()
+	capabilities := w.runtime.GetCapabilities()
+	now := time.Now()
+
+	err := w.registry.RegisterWorker(ctx, &types.Worker{
+		ID:       w.id,
+		Hostname: hostname,
+		Runtime:  w.runtimeType,
+		Capabilities: types.WorkerCapabilities{
+			Language:   capabilities.Language,
+			Version:    capabilities.Version,
+			MaxTimeout: capabilities.MaxTimeout,
+			MaxMemory:  capabilities.MaxMemory,
+		},
+		Slots:           w.slots,
+		InFlight:        w.inFlight(),
+		Labels:          w.labels,
+		StartedAt:       w.startedAt,
+		LastHeartbeatAt: now,
+	})
+	if err != nil {
+		// Heartbeats try again
+		w.logger.Error("Failed to register worker", logging.F("error", err))
+		return
+	}
+
+	if err := w.registry.PruneWorkers(ctx, now.Add(-staleWorkerAge)); err != nil {
+		w.logger.Warn("Failed to prune stale workers", logging.F("error", err))
+	}
+}
+
+// heartbeatRegistry reports the worker as alive and starts draining it if
+// the registry asks to. A worker missing from the registry, because it
+// was pruned or never registered, registers again.
+func (w *Worker) heartbeatRegistry(ctx context.Context) {
+	if w.registry == nil {
+		return
+	}
+
+	// Executions run in the slots, so none are left once every slot stopped
+	drained := false
+	select {
+	case <-w.slotsDone:
+		drained = true
+	default:
+	}
+
+	draining, err := w.registry.HeartbeatWorker(ctx, w.id, w.inFlight(), drained, time.Now())
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrCodeNotFound {
+			w.register(ctx)
+			return
+		}
+		w.logger.Error("Failed to send worker heartbeat", logging.F("error", err))
+		return
+	}
+
+	if draining {
+		w.drainOnce.Do(func() {
+			w.logger.Info("Worker draining on request", logging.F("in_flight", w.inFlight()))
+			close(w.drainCh)
+		})
+	}
+}
+
+// deregister removes the worker from the registry
+func (w *Worker) deregister(ctx context.Context) {
+	if w.registry == nil {
+		return
+	}
+
+	if err := w.registry.DeregisterWorker(ctx, w.id); err != nil {
+		w.logger.Warn("Failed to deregister worker", logging.F("error", err))
+	}
+}
+
+// refreshPlacements looks up the placements of functions and published
+// versions and keeps those the worker satisfies. On failure the worker
+// keeps the placements it knew.
+func (w *Worker) refreshPlacements(ctx context.Context) {
+	placements, err := w.functionRepo.ListPlacements(ctx)
+	if err != nil {
+		w.logger.Error("Failed to list function placements", logging.F("error", err))
+		return
+	}
+
+	matched := make([]*types.Placement, 0, len(placements))
+	for _, placement := range placements {
+		if placement.Matches(w.runtimeType, w.labels) {
+			matched = append(matched, placement)
+		}
+	}
+
+	w.lanesMu.Lock()
+	defer w.lanesMu.Unlock()
+
+	if len(matched) != len(w.placements) {
+		w.logger.Info("Function placements updated", logging.F("placements", len(matched)))
+	}
+	w.placements = matched
+}
+
+// executionQueues returns every execution queue the worker takes messages
+// from
+func (w *Worker) executionQueues() []string {
+	w.lanesMu.Lock()
+	defer w.lanesMu.Unlock()
+
+	queues := make([]string, 0, len(w.lanes)*(len(w.placements)+1))
+	for _, l := range w.lanes {
+		queues = append(queues, w.laneQueues(l)...)
+	}
+	return queues
+}
+
+// laneQueues returns the execution queues of a lane: those of the
+// placements the worker satisfies first, as few workers may take them,
+// then the lane's own queue. The caller holds lanesMu.
+func (w *Worker) laneQueues(l *lane) []string {
+	queues := make([]string, 0, len(w.placements)+1)
+	for _, placement := range w.placements {
+		queues = append(queues, invocation.PlacementQueue(l.priority, placement))
+	}
+	return append(queues, l.queue)
+}
+
+// laneOrder returns the execution queues in the order the next dequeue
+// looks at them. The first lane is chosen by smooth weighted round-robin,
+// so while every lane has messages each gets dequeues in proportion to its
+// weight and low priority still progresses. The others follow from the
+// highest priority, so the worker does not idle while any lane has
+// messages.
+func (w *Worker) laneOrder() []string {
+	w.lanesMu.Lock()
+	defer w.lanesMu.Unlock()
+
+	total := 0
+	var chosen *lane
+	for _, l := range w.lanes {
+		l.current += l.weight
+		total += l.weight
+		if chosen == nil || l.current > chosen.current {
+			chosen = l
+		}
+	}
+	chosen.current -= total
+
+	order := make([]string, 0, len(w.lanes)*(len(w.placements)+1))
+	order = append(order, w.laneQueues(chosen)...)
+	for _, l := range w.lanes {
+		if l != chosen {
+			order = append(order, w.laneQueues(l)...)
+		}
+	}
+
+	return order
+}
+
+// recoverExecution handles a message whose worker stopped renewing its
+// lease, most likely because it crashed. The lost execution counts as an
+// attempt failed with SystemError, so the function's retry policy decides
+// whether it is retried or the invocation fails and the message is
+// dead-lettered.
+func (w *Worker) recoverExecution(ctx context.Context, msg *messaging.Message) {
+	var execReq invocation.ExecutionRequest
+	if err := json.Unmarshal(msg.Payload, &execReq); err != nil {
+		w.queue.DeadLetter(ctx, msg, fmt.Sprintf("invalid payload: %v", err))
+		return
+	}
+
+	inv, err := w.invocationRepo.GetInvocationByID(ctx, execReq.InvocationID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrCodeNotFound {
+			// Deleted along with its function
+			w.ackMessage(ctx, msg)
+			return
+		}
+		// The claim expires again and a later pass retries
+		w.logger.Error("Failed to load invocation with expired lease",
+			logging.F("invocation_id", execReq.InvocationID),
+			logging.F("error", err),
+		)
+		return
+	}
+
+	if inv.Status.IsTerminal() {
+		// Finished or cancelled before the worker was lost
+		w.ackMessage(ctx, msg)
+		return
+	}
+
+	w.logger.Warn("Recovering execution with expired lease",
+		logging.F("invocation_id", inv.ID),
+		logging.F("status", inv.Status),
+		logging.F("attempt", msg.Attempts),
+	)
+
+	now := time.Now()
+	startedAt := now
+	if inv.StartedAt != nil {
+		startedAt = *inv.StartedAt
+	}
+
+	execErr := &types.ExecutionError{
+		Type:    types.ErrorTypeSystem,
+		Message: "worker stopped renewing its lease",
+	}
+	attempt := &types.InvocationAttempt{
+		ID:           uuid.New().String(),
+		InvocationID: inv.ID,
+		Attempt:      msg.Attempts,
+		Status:       types.StatusFailed,
+		Error:        execErr,
+		Duration:     now.Sub(startedAt),
+		StartedAt:    startedAt,
+		CompletedAt:  now,
+	}
+
+	policy := w.retryPolicy(ctx, execReq)
+	if msg.Attempts < policy.MaxAttempts && policy.Retryable(execErr.Type) {
+		retryAt := now.Add(policy.Backoff(msg.Attempts))
+		attempt.RetryAt = &retryAt
+		w.recordAttempt(ctx, attempt)
+		w.retry(ctx, msg, attempt)
+		return
+	}
+
+	w.recordAttempt(ctx, attempt)
+
+	result := invocation.ExecutionResult{Status: types.StatusFailed, Error: execErr}
+	if err := w.invocationSvc.UpdateInvocationResult(ctx, inv.ID, result); err != nil {
+		w.logger.Error("Failed to update invocation result",
+			logging.F("invocation_id", inv.ID),
+			logging.F("error", err),
+		)
+	}
+
+	w.queue.DeadLetter(ctx, msg, fmt.Sprintf("lease expired after %d attempts", msg.Attempts))
+}
+
+// retryPolicy returns the retry policy of the function an execution
+// request runs, or the default policy if it cannot be loaded
+func (w *Worker) retryPolicy(ctx context.Context, req invocation.ExecutionRequest) types.RetryPolicy {
+	fn, _, err := w.loadFunction(ctx, req)
+	if err != nil {
+		return types.DefaultRetryPolicy
+	}
+	return fn.Config.EffectiveRetryPolicy()
+}
+
+// retry marks an invocation as waiting for its next attempt and requeues
+// its message to be delivered at the attempt's retry time. An invocation
+// cancelled in the meantime is dropped instead.
+func (w *Worker) retry(ctx context.Context, msg *messaging.Message, attempt *types.InvocationAttempt) {
+	updated, err := w.invocationRepo.RetryInvocation(ctx, attempt.InvocationID, *attempt.RetryAt)
+	if err != nil {
+		// Retry anyway; the next attempt sets the status again
+		w.logger.Warn("Failed to mark invocation for retry",
+			logging.F("invocation_id", attempt.InvocationID),
+			logging.F("error", err),
+		)
+	} else if !updated {
+		w.logger.Info("Not retrying cancelled invocation", logging.F("invocation_id", attempt.InvocationID))
+		w.ackMessage(ctx, msg)
+		return
+	}
+
+	if err := w.queue.Requeue(ctx, msg, time.Until(*attempt.RetryAt)); err != nil {
+		w.logger.Error("Failed to requeue invocation for retry",
+			logging.F("invocation_id", attempt.InvocationID),
+			logging.F("error", err),
+		)
+		return
+	}
+
+	w.logger.Info("Retrying invocation",
+		logging.F("invocation_id", attempt.InvocationID),
+		logging.F("attempt", attempt.Attempt),
+		logging.F("error_type", attempt.Error.Type),
+		logging.F("retry_at", attempt.RetryAt),
+	)
+}
+
+// holdConcurrencySlot takes one of a function's concurrency slots for an
+// invocation and renews it until the returned function releases it. It
+// returns errConcurrencyLimit if every slot is taken. Functions without a
+// limit, or workers without a semaphore, need no slot.
+func (w *Worker) holdConcurrencySlot(ctx context.Context, fn *types.Function, invocationID string) (func(), error) {
+	limit := fn.Config.Concurrency
+	if w.concurrency == nil || limit <= 0 {
+		return func() {}, nil
+	}
+
+	held, err := w.concurrency.Acquire(ctx, fn.ID, invocationID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire concurrency slot: %w", err)
+	}
+	if !held {
+		return nil, errConcurrencyLimit
+	}
+
+	renewCtx, stopRenewing := context.WithCancel(ctx)
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		w.renewConcurrencySlot(renewCtx, fn.ID, invocationID, limit)
+	}()
+
+	return func() {
+		stopRenewing()
+		<-renewed
+
+		if err := w.concurrency.Release(ctx, fn.ID, invocationID); err != nil {
+			// The slot expires on its own
+			w.logger.Warn("Failed to release concurrency slot",
+				logging.F("invocation_id", invocationID),
+				logging.F("error", err),
+			)
+		}
+	}, nil
+}
+
+// renewConcurrencySlot keeps a concurrency slot from expiring, renewing it
+// as often as message leases, until ctx is done
+func (w *Worker) renewConcurrencySlot(ctx context.Context, functionID, invocationID string, limit int) {
+	if w.leaseRenewInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(w.leaseRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			held, err := w.concurrency.Acquire(ctx, functionID, invocationID, limit)
+			if err != nil && ctx.Err() == nil {
+				w.logger.Warn("Failed to renew concurrency slot",
+					logging.F("invocation_id", invocationID),
+					logging.F("error", err),
+				)
+			} else if err == nil && !held {
+				// The slot expired and was taken; the execution carries on
+				// over the limit
+				w.logger.Warn("Lost concurrency slot", logging.F("invocation_id", invocationID))
+			}
+		}
+	}
+}
+
+// deferExecution puts the message of an invocation whose function is at
+// its concurrency limit back in the queue, after a jittered delay. The
+// delivery does not count as an attempt.
+func (w *Worker) deferExecution(ctx context.Context, msg *messaging.Message, execReq invocation.ExecutionRequest) {
+	delay := w.deferDelay/2 + time.Duration(rand.Int63n(int64(w.deferDelay)+1))
+
+	msg.Attempts--
+	if err := w.queue.Requeue(ctx, msg, delay); err != nil {
+		w.logger.Error("Failed to defer invocation",
+			logging.F("invocation_id", execReq.InvocationID),
+			logging.F("error", err),
+		)
+		return
+	}
+
+	w.logger.Info("Deferred invocation at function concurrency limit",
+		logging.F("invocation_id", execReq.InvocationID),
+		logging.F("function_id", execReq.FunctionID),
+		logging.F("delay", delay),
+	)
+}
+
+// recordAttempt stores an execution attempt, logging failures
+func (w *Worker) recordAttempt(ctx context.Context, attempt *types.InvocationAttempt) {
+	if err := w.invocationRepo.CreateAttempt(ctx, attempt); err != nil {
+		w.logger.Warn("Failed to record invocation attempt",
+			logging.F("invocation_id", attempt.InvocationID),
+			logging.F("attempt", attempt.Attempt),
+			logging.F("error", err),
+		)
+	}
+}
+
+// ackMessage acknowledges a processed message, logging failures
+func (w *Worker) ackMessage(ctx context.Context, msg *messaging.Message) {
+	if err := w.queue.Ack(ctx, msg); err != nil {
+		w.logger.Error("Failed to acknowledge message",
+			logging.F("message_id", msg.ID),
+			logging.F("error", err),
+		)
+	}
+}
+
+// isCancelled reports whether an invocation was cancelled while queued
+func (w *Worker) isCancelled(ctx context.Context, invocationID string) bool {
+	inv, err := w.invocationRepo.GetInvocationByID(ctx, invocationID)
+	if err != nil {
+		return false
+	}
+	return inv.Status == types.StatusCancelled
+}
+
+// watchCancellations stops executions whose invocation is cancelled, until
+// the subscription is closed
+func (w *Worker) watchCancellations(sub messaging.Subscription) {
+	for payload := range sub.Events() {
+		invocationID := string(payload)
+
+		w.runningMu.Lock()
+		cancel, ok := w.running[invocationID]
+		w.runningMu.Unlock()
+
+		if ok {
+			w.logger.Info("Cancelling execution", logging.F("invocation_id", invocationID))
+			cancel()
+		}
+	}
+}
+
+// watchFunctionChanges invalidates the runtime's state for updated and
+// deleted functions, until the subscription is closed
+func (w *Worker) watchFunctionChanges(sub messaging.Subscription, invalidator runtime.Invalidator) {
+	for payload := range sub.Events() {
+		var event functions.ChangeEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			w.logger.Warn("Ignoring malformed function change", logging.F("error", err))
+			continue
+		}
+
+		invalidator.InvalidateFunction(event.FunctionID, event.Deleted)
+	}
+}
+
+// trackExecution registers the cancel function of an execution in progress
+func (w *Worker) trackExecution(invocationID string, cancel context.CancelFunc) {
+	w.runningMu.Lock()
+	w.running[invocationID] = cancel
+	w.runningMu.Unlock()
+}
+
+// untrackExecution removes a finished execution
+func (w *Worker) untrackExecution(invocationID string) {
+	w.runningMu.Lock()
+	delete(w.running, invocationID)
+	w.runningMu.Unlock()
+}
+
+// executeFunction executes a function. It returns errConcurrencyLimit
+// without running it if the function is at its concurrency limit.
 func (w *Worker) executeFunction(ctx context.Context, req invocation.ExecutionRequest) (*invocation.ExecutionResult, error) {
+	// Get function metadata
+	fn, version, err := w.loadFunction(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Determine timeout
+	timeout := fn.Config.Timeout
+	if req.Timeout != nil {
+		timeout = *req.Timeout
+	}
+	limits := runtime.ResourceLimits{
+		MemoryBytes: int64(fn.Config.Memory) * 1024 * 1024, // Convert MB to bytes
+		Timeout:     timeout,
+	}
+
+	// Wait for the host resources the function is limited to before taking
+	// a concurrency slot or marking the invocation running, so an execution
+	// waiting here holds no slot other workers could use
+	release, err := w.budget.acquire(ctx, limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve resources: %w", err)
+	}
+	defer release()
+
+	// Take one of the function's concurrency slots for the duration
+	releaseSlot, err := w.holdConcurrencySlot(ctx, fn, req.InvocationID)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseSlot()
+
 	// Update invocation status to running
 	if err := w.invocationSvc.UpdateInvocationStatus(ctx, req.InvocationID, types.StatusRunning); err != nil {
 		w.logger.Warn("Failed to update invocation status to running",
@@ -168,37 +1081,37 @@ func (w *Worker) executeFunction(ctx context.Context, req invocation.ExecutionRe
 		)
 	}
 
-	// Get function metadata
-	fn, err := w.functionRepo.GetByID(ctx, req.FunctionID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get function: %w", err)
-	}
-
 	// Retrieve function code
 	code, err := w.functionStore.Retrieve(ctx, fn.Code.Source)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve function code: %w", err)
 	}
 
-	// Determine timeout
-	timeout := fn.Config.Timeout
-	if req.Timeout != nil {
-		timeout = *req.Timeout
+	// Retrieve the deploy-time build, if any; without it the runtime
+	// compiles the code itself
+	var artifact []byte
+	if fn.Code.Artifact != "" {
+		artifact, err = w.functionStore.Retrieve(ctx, fn.Code.Artifact)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve function build: %w", err)
+		}
 	}
 
 	// Prepare execution spec
 	spec := runtime.ExecutionSpec{
-		FunctionID:  req.FunctionID,
-		Code:        code,
-		Runtime:     fn.Runtime,
-		Handler:     fn.Handler,
-		Payload:     req.Payload,
-		Environment: fn.Config.Environment,
-		Timeout:     timeout,
-		Limits: runtime.ResourceLimits{
-			MemoryBytes: int64(fn.Config.Memory) * 1024 * 1024, // Convert MB to bytes
-			Timeout:     timeout,
-		},
+		InvocationID: req.InvocationID,
+		FunctionID:   req.FunctionID,
+		Version:      version,
+		Code:         code,
+		CodeChecksum: fn.Code.Checksum,
+		CodeFormat:   fn.Code.Format,
+		Artifact:     artifact,
+		Runtime:      fn.Runtime,
+		Handler:      fn.Handler,
+		Payload:      req.Payload,
+		Environment:  fn.Config.Environment,
+		Timeout:      timeout,
+		Limits:       limits,
 	}
 
 	// Execute function
@@ -213,7 +1126,37 @@ func (w *Worker) executeFunction(ctx context.Context, req invocation.ExecutionRe
 		Result:  json.RawMessage(runtimeResult.Result),
 		Error:   runtimeResult.Error,
 		Metrics: &runtimeResult.Metrics,
+		Logs:    runtimeResult.Logs,
 	}
 
 	return result, nil
 }
+
+// loadFunction returns the function metadata to execute for req, along with
+// the published version number (0 for the function's current code). A
+// published version is presented as a function with the version's code and
+// configuration.
+func (w *Worker) loadFunction(ctx context.Context, req invocation.ExecutionRequest) (*types.Function, int, error) {
+	if req.VersionID == "" {
+		fn, err := w.functionRepo.GetByID(ctx, req.FunctionID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get function: %w", err)
+		}
+		return fn, 0, nil
+	}
+
+	v, err := w.versionRepo.GetVersionByID(ctx, req.VersionID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get function version: %w", err)
+	}
+
+	return &types.Function{
+		ID:      v.FunctionID,
+		Name:    v.FunctionName,
+		Runtime: v.Runtime,
+		Handler: v.Handler,
+		Code:    v.Code,
+		Config:  v.Config,
+		Status:  types.FunctionReady,
+	}, v.Version, nil
+}
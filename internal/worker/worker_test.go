@@ -0,0 +1,248 @@
+package worker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"GoFaas/internal/core/invocation"
+	"GoFaas/internal/messaging"
+	"GoFaas/internal/observability/logging"
+	"GoFaas/internal/worker/runtime"
+	"GoFaas/pkg/types"
+)
+
+func TestDeferExecutionDelay(t *testing.T) {
+	tests := []struct {
+		name  string
+		delay time.Duration
+		want  time.Duration
+	}{
+		{name: "configured delay", delay: time.Minute, want: time.Minute},
+		{name: "zero uses the default", want: defaultDeferDelay},
+		{name: "negative uses the default", delay: -time.Second, want: defaultDeferDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			q := messaging.NewMemoryQueue(time.Minute)
+			w := NewWorker(Config{
+				ID:                    "w1",
+				Queue:                 q,
+				Logger:                logging.NewSimpleLogger(),
+				ConcurrencyDeferDelay: tt.delay,
+			})
+			if w.deferDelay != tt.want {
+				t.Errorf("deferDelay = %s, want %s", w.deferDelay, tt.want)
+			}
+
+			if err := q.Enqueue(ctx, "q", []byte(`{}`), nil); err != nil {
+				t.Fatalf("Enqueue: %v", err)
+			}
+			msg, err := q.Dequeue(ctx, "q", time.Second)
+			if err != nil || msg == nil {
+				t.Fatalf("Dequeue = %v, %v", msg, err)
+			}
+
+			w.deferExecution(ctx, msg, invocation.ExecutionRequest{InvocationID: "i1"})
+
+			// The deferral puts the message back without counting an attempt
+			if msg.Attempts != 0 {
+				t.Errorf("Attempts = %d, want 0", msg.Attempts)
+			}
+			stats, err := q.GetStats(ctx, "q")
+			if err != nil {
+				t.Fatalf("GetStats: %v", err)
+			}
+			if stats.Delayed != 1 {
+				t.Fatalf("Delayed = %d, want 1", stats.Delayed)
+			}
+		})
+	}
+}
+
+func TestLaneOrder(t *testing.T) {
+	high := invocation.ExecutionQueue(types.PriorityHigh)
+	normal := invocation.ExecutionQueue(types.PriorityNormal)
+	low := invocation.ExecutionQueue(types.PriorityLow)
+
+	tests := []struct {
+		name    string
+		weights map[types.Priority]int
+		want    []string // First queue of each dequeue
+	}{
+		{
+			name: "equal weights by default",
+			want: []string{high, normal, low, high, normal, low},
+		},
+		{
+			name:    "weighted",
+			weights: map[types.Priority]int{types.PriorityHigh: 3, types.PriorityNormal: 2, types.PriorityLow: 1},
+			want:    []string{high, normal, high, low, normal, high, high, normal, high, low, normal, high},
+		},
+		{
+			name:    "non-positive weights count as one",
+			weights: map[types.Priority]int{types.PriorityHigh: 2, types.PriorityNormal: 0, types.PriorityLow: -1},
+			want:    []string{high, normal, low, high, high, normal, low, high},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWorker(Config{
+				ID:          "w1",
+				Queue:       messaging.NewMemoryQueue(time.Minute),
+				Logger:      logging.NewSimpleLogger(),
+				LaneWeights: tt.weights,
+			})
+
+			for i, want := range tt.want {
+				order := w.laneOrder()
+				if order[0] != want {
+					t.Fatalf("dequeue %d starts at %s, want %s", i, order[0], want)
+				}
+
+				// The other lanes follow from the highest priority
+				rest := make([]string, 0, 2)
+				for _, queue := range []string{high, normal, low} {
+					if queue != want {
+						rest = append(rest, queue)
+					}
+				}
+				if !reflect.DeepEqual(order[1:], rest) {
+					t.Fatalf("dequeue %d order = %v, want %s then %v", i, order, want, rest)
+				}
+			}
+		})
+	}
+}
+
+func TestBudgetAcquire(t *testing.T) {
+	const mb = 1 << 20
+
+	tests := []struct {
+		name       string
+		cpus       float64
+		memory     int64
+		held       runtime.ResourceLimits
+		limits     runtime.ResourceLimits
+		wantBlocks bool
+	}{
+		{
+			name:   "fits",
+			cpus:   2,
+			memory: 1024 * mb,
+			held:   runtime.ResourceLimits{CPUShares: 1000, MemoryBytes: 512 * mb},
+			limits: runtime.ResourceLimits{CPUShares: 1000, MemoryBytes: 512 * mb},
+		},
+		{
+			name:       "cpu exhausted",
+			cpus:       2,
+			memory:     1024 * mb,
+			held:       runtime.ResourceLimits{CPUShares: 2000},
+			limits:     runtime.ResourceLimits{CPUShares: 500},
+			wantBlocks: true,
+		},
+		{
+			name:       "no cpu limit reserves a cpu",
+			cpus:       2,
+			held:       runtime.ResourceLimits{CPUShares: 1500},
+			limits:     runtime.ResourceLimits{},
+			wantBlocks: true,
+		},
+		{
+			name:       "memory exhausted",
+			cpus:       2,
+			memory:     1024 * mb,
+			held:       runtime.ResourceLimits{CPUShares: 500, MemoryBytes: 768 * mb},
+			limits:     runtime.ResourceLimits{CPUShares: 500, MemoryBytes: 512 * mb},
+			wantBlocks: true,
+		},
+		{
+			name:   "limits beyond the budget are capped",
+			cpus:   2,
+			memory: 1024 * mb,
+			limits: runtime.ResourceLimits{CPUShares: 8000, MemoryBytes: 4096 * mb},
+		},
+		{
+			name:   "no budget",
+			held:   runtime.ResourceLimits{CPUShares: 64000, MemoryBytes: 64 << 30},
+			limits: runtime.ResourceLimits{CPUShares: 64000, MemoryBytes: 64 << 30},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBudget(tt.cpus, tt.memory)
+			if tt.held != (runtime.ResourceLimits{}) {
+				if _, err := b.acquire(context.Background(), tt.held); err != nil {
+					t.Fatalf("acquire held: %v", err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			release, err := b.acquire(ctx, tt.limits)
+			if tt.wantBlocks {
+				if err != context.DeadlineExceeded {
+					t.Fatalf("acquire = %v, want %v", err, context.DeadlineExceeded)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("acquire: %v", err)
+			}
+			release()
+		})
+	}
+}
+
+func TestBudgetRelease(t *testing.T) {
+	b := newBudget(1, 0)
+
+	first, err := b.acquire(context.Background(), runtime.ResourceLimits{})
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		release, err := b.acquire(context.Background(), runtime.ResourceLimits{})
+		if err != nil {
+			t.Errorf("acquire: %v", err)
+		}
+		acquired <- release
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second execution ran before the first released its cpu")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Releasing twice returns the reservation once
+	first()
+	first()
+
+	var second func()
+	select {
+	case second = <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second execution did not run after the release")
+	}
+
+	b.mu.Lock()
+	used := b.usedCPU
+	b.mu.Unlock()
+	if used != defaultCPUMillis {
+		t.Fatalf("usedCPU = %d, want %d", used, defaultCPUMillis)
+	}
+
+	second()
+	if b.usedCPU != 0 {
+		t.Fatalf("usedCPU = %d after releasing everything, want 0", b.usedCPU)
+	}
+}
@@ -0,0 +1 @@
+ALTER TABLE functions DROP COLUMN IF EXISTS created_by;
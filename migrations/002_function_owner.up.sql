@@ -0,0 +1,3 @@
+-- Functions record the user who created them, so only that user (or an
+-- admin) may change them
+ALTER TABLE functions ADD COLUMN IF NOT EXISTS created_by VARCHAR(255);
@@ -0,0 +1,2 @@
+-- Function log output collected separately from the result
+ALTER TABLE invocations ADD COLUMN IF NOT EXISTS logs JSONB;
@@ -0,0 +1,8 @@
+ALTER TABLE functions DROP CONSTRAINT IF EXISTS check_function_status_valid;
+
+ALTER TABLE functions DROP COLUMN IF EXISTS build_completed_at;
+ALTER TABLE functions DROP COLUMN IF EXISTS build_started_at;
+ALTER TABLE functions DROP COLUMN IF EXISTS build_error;
+ALTER TABLE functions DROP COLUMN IF EXISTS build_logs;
+ALTER TABLE functions DROP COLUMN IF EXISTS code_artifact;
+ALTER TABLE functions DROP COLUMN IF EXISTS status;
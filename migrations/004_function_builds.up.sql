@@ -0,0 +1,10 @@
+-- Deploy-time builds: functions report a status and keep the built artifact
+ALTER TABLE functions ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'ready';
+ALTER TABLE functions ADD COLUMN IF NOT EXISTS code_artifact TEXT;
+ALTER TABLE functions ADD COLUMN IF NOT EXISTS build_logs TEXT;
+ALTER TABLE functions ADD COLUMN IF NOT EXISTS build_error TEXT;
+ALTER TABLE functions ADD COLUMN IF NOT EXISTS build_started_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE functions ADD COLUMN IF NOT EXISTS build_completed_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE functions ADD CONSTRAINT check_function_status_valid
+    CHECK (status IN ('building', 'ready', 'failed'));
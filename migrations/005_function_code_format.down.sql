@@ -0,0 +1,3 @@
+ALTER TABLE functions DROP CONSTRAINT IF EXISTS check_code_format_valid;
+
+ALTER TABLE functions DROP COLUMN IF EXISTS code_format;
@@ -0,0 +1,5 @@
+-- Function code may be a single source file or a zip/tar.gz archive
+ALTER TABLE functions ADD COLUMN IF NOT EXISTS code_format VARCHAR(10) NOT NULL DEFAULT 'file';
+
+ALTER TABLE functions ADD CONSTRAINT check_code_format_valid
+    CHECK (code_format IN ('file', 'zip', 'tar.gz'));
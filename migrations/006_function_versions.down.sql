@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS function_aliases;
+DROP TABLE IF EXISTS function_versions;
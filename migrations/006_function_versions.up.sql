@@ -0,0 +1,40 @@
+-- Immutable function versions, numbered per function name
+CREATE TABLE IF NOT EXISTS function_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    function_id UUID NOT NULL REFERENCES functions(id) ON DELETE CASCADE,
+    function_name VARCHAR(255) NOT NULL,
+    version INTEGER NOT NULL,
+    runtime VARCHAR(50) NOT NULL,
+    handler VARCHAR(255) NOT NULL,
+    code_source TEXT NOT NULL,
+    code_source_type VARCHAR(20) NOT NULL DEFAULT 'local',
+    code_checksum VARCHAR(64) NOT NULL,
+    code_size BIGINT NOT NULL,
+    code_format VARCHAR(10) NOT NULL DEFAULT 'file',
+    code_artifact TEXT,
+    timeout_seconds INTEGER NOT NULL,
+    memory_mb INTEGER NOT NULL,
+    max_concurrency INTEGER NOT NULL,
+    environment JSONB DEFAULT '{}',
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    CONSTRAINT unique_function_version UNIQUE(function_name, version),
+    CONSTRAINT check_version_positive CHECK (version > 0)
+);
+
+CREATE INDEX idx_function_versions_function_id ON function_versions(function_id);
+
+-- Aliases point at a version of the function with the same name
+CREATE TABLE IF NOT EXISTS function_aliases (
+    function_name VARCHAR(255) NOT NULL,
+    name VARCHAR(63) NOT NULL,
+    version INTEGER NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (function_name, name),
+    FOREIGN KEY (function_name, version)
+        REFERENCES function_versions(function_name, version) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+ALTER TABLE function_aliases DROP CONSTRAINT IF EXISTS fk_alias_routing_version;
+ALTER TABLE function_aliases DROP CONSTRAINT IF EXISTS check_routing_weight;
+
+ALTER TABLE function_aliases DROP COLUMN IF EXISTS routing_rollback_reason;
+ALTER TABLE function_aliases DROP COLUMN IF EXISTS routing_rolled_back_at;
+ALTER TABLE function_aliases DROP COLUMN IF EXISTS routing_started_at;
+ALTER TABLE function_aliases DROP COLUMN IF EXISTS routing_weight;
+ALTER TABLE function_aliases DROP COLUMN IF EXISTS routing_version;
+
+DROP INDEX IF EXISTS idx_invocations_function_version;
+ALTER TABLE invocations DROP COLUMN IF EXISTS function_version;
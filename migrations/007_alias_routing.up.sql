@@ -0,0 +1,20 @@
+-- Record which published version an invocation ran
+ALTER TABLE invocations ADD COLUMN IF NOT EXISTS function_version INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_invocations_function_version
+    ON invocations(function_id, function_version, created_at)
+    WHERE function_version IS NOT NULL;
+
+-- Aliases can route a percentage of their traffic to a second version
+ALTER TABLE function_aliases ADD COLUMN IF NOT EXISTS routing_version INTEGER;
+ALTER TABLE function_aliases ADD COLUMN IF NOT EXISTS routing_weight INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE function_aliases ADD COLUMN IF NOT EXISTS routing_started_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE function_aliases ADD COLUMN IF NOT EXISTS routing_rolled_back_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE function_aliases ADD COLUMN IF NOT EXISTS routing_rollback_reason TEXT;
+
+ALTER TABLE function_aliases ADD CONSTRAINT check_routing_weight
+    CHECK (routing_weight >= 0 AND routing_weight <= 100);
+
+ALTER TABLE function_aliases ADD CONSTRAINT fk_alias_routing_version
+    FOREIGN KEY (function_name, routing_version)
+    REFERENCES function_versions(function_name, version) ON DELETE CASCADE;
@@ -0,0 +1,18 @@
+-- Cron schedules that invoke a function with a static payload
+CREATE TABLE IF NOT EXISTS function_schedules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    function_id UUID NOT NULL REFERENCES functions(id) ON DELETE CASCADE,
+    cron VARCHAR(255) NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    payload JSONB,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMP WITH TIME ZONE,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    last_invocation_id UUID,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_function_schedules_function_id ON function_schedules(function_id);
+CREATE INDEX idx_function_schedules_due ON function_schedules(next_run_at) WHERE enabled;
@@ -0,0 +1,8 @@
+UPDATE invocations SET status = 'pending' WHERE status = 'delayed';
+UPDATE invocations SET status = 'failed' WHERE status = 'cancelled';
+
+ALTER TABLE invocations DROP CONSTRAINT IF EXISTS check_status_valid;
+ALTER TABLE invocations ADD CONSTRAINT check_status_valid
+    CHECK (status IN ('pending', 'running', 'completed', 'failed', 'timeout'));
+
+ALTER TABLE invocations DROP COLUMN IF EXISTS run_at;
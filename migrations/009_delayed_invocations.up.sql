@@ -0,0 +1,7 @@
+-- Delayed invocations wait in the 'delayed' status until run_at, and can be
+-- cancelled before they start
+ALTER TABLE invocations ADD COLUMN IF NOT EXISTS run_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE invocations DROP CONSTRAINT IF EXISTS check_status_valid;
+ALTER TABLE invocations ADD CONSTRAINT check_status_valid
+    CHECK (status IN ('pending', 'delayed', 'running', 'completed', 'failed', 'timeout', 'cancelled'));
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_invocations_idempotency_key;
+
+ALTER TABLE invocations DROP COLUMN IF EXISTS idempotency_hash;
+ALTER TABLE invocations DROP COLUMN IF EXISTS idempotency_key;
@@ -0,0 +1,8 @@
+-- Invocations can carry a client-supplied idempotency key; a retried
+-- request with the same key returns the original invocation
+ALTER TABLE invocations ADD COLUMN IF NOT EXISTS idempotency_key TEXT;
+ALTER TABLE invocations ADD COLUMN IF NOT EXISTS idempotency_hash TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invocations_idempotency_key
+    ON invocations(function_id, idempotency_key)
+    WHERE idempotency_key IS NOT NULL;
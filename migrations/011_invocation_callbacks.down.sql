@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS callback_deliveries;
+
+ALTER TABLE invocations DROP COLUMN IF EXISTS callback_secret;
+ALTER TABLE invocations DROP COLUMN IF EXISTS callback_url;
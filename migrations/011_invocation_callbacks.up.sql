@@ -0,0 +1,20 @@
+-- Invocations can name a URL that receives the finished invocation
+ALTER TABLE invocations ADD COLUMN IF NOT EXISTS callback_url TEXT;
+ALTER TABLE invocations ADD COLUMN IF NOT EXISTS callback_secret TEXT;
+
+-- One row per attempt to deliver an invocation to its callback URL
+CREATE TABLE IF NOT EXISTS callback_deliveries (
+    id UUID PRIMARY KEY,
+    invocation_id UUID NOT NULL REFERENCES invocations(id) ON DELETE CASCADE,
+    attempt INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    status_code INTEGER,
+    error TEXT,
+    succeeded BOOLEAN NOT NULL DEFAULT FALSE,
+    duration_ns BIGINT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_callback_deliveries_invocation
+    ON callback_deliveries(invocation_id, attempt);
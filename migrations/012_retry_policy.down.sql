@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS invocation_attempts;
+
+ALTER TABLE function_versions DROP COLUMN IF EXISTS retry_policy;
+ALTER TABLE functions DROP COLUMN IF EXISTS retry_policy;
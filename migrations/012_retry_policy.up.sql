@@ -0,0 +1,21 @@
+-- Functions and their published versions can carry a retry policy
+ALTER TABLE functions ADD COLUMN IF NOT EXISTS retry_policy JSONB;
+ALTER TABLE function_versions ADD COLUMN IF NOT EXISTS retry_policy JSONB;
+
+-- One row per execution attempt of an invocation
+CREATE TABLE IF NOT EXISTS invocation_attempts (
+    id UUID PRIMARY KEY,
+    invocation_id UUID NOT NULL REFERENCES invocations(id) ON DELETE CASCADE,
+    attempt INTEGER NOT NULL,
+    worker_id VARCHAR(255),
+    status VARCHAR(20) NOT NULL,
+    error_type VARCHAR(100),
+    error_message TEXT,
+    duration_ns BIGINT NOT NULL DEFAULT 0,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    retry_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_invocation_attempts_invocation
+    ON invocation_attempts(invocation_id, attempt);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_invocations_replay_of;
+
+ALTER TABLE invocations DROP COLUMN IF EXISTS replay_of;
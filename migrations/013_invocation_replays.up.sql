@@ -0,0 +1,7 @@
+-- A replayed dead-lettered invocation runs as a new invocation linked to
+-- the original
+ALTER TABLE invocations ADD COLUMN IF NOT EXISTS replay_of UUID REFERENCES invocations(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_invocations_replay_of
+    ON invocations(replay_of)
+    WHERE replay_of IS NOT NULL;
@@ -0,0 +1,22 @@
+-- Messages of the PostgreSQL queue backend (QUEUE_TYPE=postgres). A message
+-- is ready until it is delivered, delivered until it is acknowledged, and
+-- dead once dead-lettered. visible_at is when a ready message may be
+-- delivered, or when the lease of a delivered message expires.
+CREATE TABLE IF NOT EXISTS queue_messages (
+    seq BIGSERIAL PRIMARY KEY,
+    queue VARCHAR(255) NOT NULL,
+    message_id VARCHAR(255) NOT NULL,
+    payload BYTEA NOT NULL,
+    headers JSONB,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    state VARCHAR(20) NOT NULL DEFAULT 'ready',
+    consumer VARCHAR(255),
+    enqueued_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    visible_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    dead_lettered_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_queue_messages_visible
+    ON queue_messages(queue, state, visible_at);
+CREATE INDEX IF NOT EXISTS idx_queue_messages_message_id
+    ON queue_messages(queue, message_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS queue_counters;
+DROP TABLE IF EXISTS queue_consumers;
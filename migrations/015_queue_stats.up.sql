@@ -0,0 +1,18 @@
+-- Heartbeats of the consumers of the PostgreSQL queue backend. A consumer
+-- counts as active while its last heartbeat is recent.
+CREATE TABLE IF NOT EXISTS queue_consumers (
+    queue VARCHAR(255) NOT NULL,
+    consumer VARCHAR(255) NOT NULL,
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (queue, consumer)
+);
+
+-- Messages enqueued and dequeued per queue, counted in short buckets from
+-- which the queue's rates are computed
+CREATE TABLE IF NOT EXISTS queue_counters (
+    queue VARCHAR(255) NOT NULL,
+    bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+    enqueued BIGINT NOT NULL DEFAULT 0,
+    dequeued BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (queue, bucket)
+);
@@ -0,0 +1 @@
+ALTER TABLE invocations DROP COLUMN IF EXISTS priority;
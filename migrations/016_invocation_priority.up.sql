@@ -0,0 +1,2 @@
+-- Invocations are queued in the execution lane of their priority
+ALTER TABLE invocations ADD COLUMN IF NOT EXISTS priority VARCHAR(10) NOT NULL DEFAULT 'normal';
@@ -0,0 +1,13 @@
+-- Worker processes registered with the platform, kept alive by heartbeats
+CREATE TABLE IF NOT EXISTS workers (
+    id VARCHAR(255) PRIMARY KEY,
+    hostname VARCHAR(255) NOT NULL,
+    capabilities JSONB NOT NULL,
+    slots INTEGER NOT NULL,
+    in_flight INTEGER NOT NULL DEFAULT 0,
+    labels JSONB,
+    draining BOOLEAN NOT NULL DEFAULT FALSE,
+    drained BOOLEAN NOT NULL DEFAULT FALSE,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_heartbeat_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,4 @@
+ALTER TABLE workers DROP COLUMN IF EXISTS runtime;
+ALTER TABLE invocations DROP COLUMN IF EXISTS placement;
+ALTER TABLE function_versions DROP COLUMN IF EXISTS placement;
+ALTER TABLE functions DROP COLUMN IF EXISTS placement;
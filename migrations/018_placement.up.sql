@@ -0,0 +1,11 @@
+-- Functions and their published versions may be constrained to workers
+-- with a given runtime and labels
+ALTER TABLE functions ADD COLUMN IF NOT EXISTS placement JSONB;
+ALTER TABLE function_versions ADD COLUMN IF NOT EXISTS placement JSONB;
+
+-- Invocations keep the placement they were queued with, so workers still
+-- serve their queue after the function's placement changes
+ALTER TABLE invocations ADD COLUMN IF NOT EXISTS placement JSONB;
+
+-- Workers advertise how they run functions
+ALTER TABLE workers ADD COLUMN IF NOT EXISTS runtime VARCHAR(20) NOT NULL DEFAULT 'simple';
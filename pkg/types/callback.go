@@ -0,0 +1,18 @@
+package types
+
+import "time"
+
+// CallbackDelivery records one attempt to POST a finished invocation to
+// its callback URL
+type CallbackDelivery struct {
+	ID            string        `json:"id" db:"id"`
+	InvocationID  string        `json:"invocation_id" db:"invocation_id"`
+	Attempt       int           `json:"attempt" db:"attempt"`
+	URL           string        `json:"url" db:"url"`
+	StatusCode    int           `json:"status_code,omitempty" db:"status_code"` // Unset if no response was received
+	Error         string        `json:"error,omitempty" db:"error"`
+	Succeeded     bool          `json:"succeeded" db:"succeeded"`
+	Duration      time.Duration `json:"duration" db:"duration_ns"`
+	NextAttemptAt *time.Time    `json:"next_attempt_at,omitempty" db:"next_attempt_at"` // When a failed delivery is retried; unset after the last attempt
+	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
+}
@@ -7,16 +7,40 @@ type ExecutionStatus string
 
 const (
 	StatusPending   ExecutionStatus = "pending"
+	StatusDelayed   ExecutionStatus = "delayed" // Waiting for its run_at time before being queued
 	StatusRunning   ExecutionStatus = "running"
 	StatusCompleted ExecutionStatus = "completed"
 	StatusFailed    ExecutionStatus = "failed"
 	StatusTimeout   ExecutionStatus = "timeout"
+	StatusCancelled ExecutionStatus = "cancelled"
 )
 
 // IsTerminal returns true if the status represents a terminal state
 func (s ExecutionStatus) IsTerminal() bool {
 	switch s {
-	case StatusCompleted, StatusFailed, StatusTimeout:
+	case StatusCompleted, StatusFailed, StatusTimeout, StatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// Priority selects the execution queue lane of an invocation
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityNormal Priority = "normal"
+	PriorityLow    Priority = "low"
+)
+
+// Priorities lists the priorities from highest to lowest
+var Priorities = []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+// IsValid returns true if the priority is one of Priorities
+func (p Priority) IsValid() bool {
+	switch p {
+	case PriorityHigh, PriorityNormal, PriorityLow:
 		return true
 	default:
 		return false
@@ -38,3 +62,10 @@ type ExecutionError struct {
 	Message string `json:"message" db:"error_message"`
 	Stack   string `json:"stack,omitempty" db:"error_stack"`
 }
+
+// LogEntry represents a line of function log output
+type LogEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Level     string    `json:"level"`
+	Message   string    `json:"message"`
+}
@@ -2,6 +2,9 @@ package types
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -15,16 +18,63 @@ type Function struct {
 	Code      FunctionCode      `json:"code"`
 	Config    FunctionConfig    `json:"config"`
 	Metadata  map[string]string `json:"metadata" db:"metadata"`
+	Status    FunctionStatus    `json:"status" db:"status"`
+	Build     *BuildInfo        `json:"build,omitempty"`
+	InFlight  *int              `json:"in_flight,omitempty"` // Invocations running now; only reported for a single function
+	CreatedBy string            `json:"created_by,omitempty" db:"created_by"`
 	CreatedAt time.Time         `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
 }
 
+// FunctionStatus represents whether a function can be invoked
+type FunctionStatus string
+
+const (
+	FunctionBuilding FunctionStatus = "building"
+	FunctionReady    FunctionStatus = "ready"
+	FunctionFailed   FunctionStatus = "failed"
+)
+
+// BuildInfo describes the most recent deploy-time build of a function
+type BuildInfo struct {
+	Logs        string     `json:"logs,omitempty" db:"build_logs"`
+	Error       string     `json:"error,omitempty" db:"build_error"`
+	StartedAt   *time.Time `json:"started_at,omitempty" db:"build_started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"build_completed_at"`
+}
+
 // FunctionCode represents function source code
 type FunctionCode struct {
-	Source     string `json:"source" db:"code_source"`           // Base64 encoded or storage URL
-	SourceType string `json:"source_type" db:"code_source_type"` // "inline", "s3", "git"
-	Checksum   string `json:"checksum" db:"code_checksum"`       // SHA256 hash
-	Size       int64  `json:"size" db:"code_size"`               // Size in bytes
+	Source     string     `json:"source" db:"code_source"`               // Base64 encoded or storage URL
+	SourceType string     `json:"source_type" db:"code_source_type"`     // "inline", "s3", "git"
+	Checksum   string     `json:"checksum" db:"code_checksum"`           // SHA256 hash
+	Size       int64      `json:"size" db:"code_size"`                   // Size in bytes
+	Format     CodeFormat `json:"format" db:"code_format"`               // Single source file or archive
+	Artifact   string     `json:"artifact,omitempty" db:"code_artifact"` // Storage location of the build output
+}
+
+// CodeFormat describes how function code is packaged
+type CodeFormat string
+
+const (
+	CodeFormatFile  CodeFormat = "file"   // A single source file
+	CodeFormatZip   CodeFormat = "zip"    // A zip archive of the function directory
+	CodeFormatTarGz CodeFormat = "tar.gz" // A gzipped tarball of the function directory
+)
+
+// IsValid checks if the code format is supported
+func (f CodeFormat) IsValid() bool {
+	switch f {
+	case CodeFormatFile, CodeFormatZip, CodeFormatTarGz:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsArchive returns true if the code is a multi-file archive
+func (f CodeFormat) IsArchive() bool {
+	return f == CodeFormatZip || f == CodeFormatTarGz
 }
 
 // FunctionConfig represents function configuration
@@ -33,19 +83,126 @@ type FunctionConfig struct {
 	Memory      int               `json:"memory_mb" db:"memory_mb"`
 	Environment map[string]string `json:"environment" db:"environment"`
 	Concurrency int               `json:"max_concurrency" db:"max_concurrency"`
+	Retry       *RetryPolicy      `json:"retry_policy,omitempty" db:"retry_policy"` // Unset for DefaultRetryPolicy
+	Placement   *Placement        `json:"placement,omitempty" db:"placement"`       // Unset to run on any worker
+}
+
+// Placement constrains the workers a function runs on
+type Placement struct {
+	Runtime WorkerRuntime     `json:"runtime,omitempty"` // Runtime the worker must use; any if empty
+	Labels  map[string]string `json:"labels,omitempty"`  // Labels the worker must have, with these values
+}
+
+// IsZero reports whether the placement allows every worker
+func (p *Placement) IsZero() bool {
+	return p == nil || (p.Runtime == "" && len(p.Labels) == 0)
+}
+
+// Key returns a canonical form of the placement, such as
+// "runtime:container,disk=ssd,zone=eu-1", that is equal for equal
+// placements. It is empty for a placement that allows every worker.
+func (p *Placement) Key() string {
+	if p.IsZero() {
+		return ""
+	}
+
+	parts := make([]string, 0, len(p.Labels)+1)
+	if p.Runtime != "" {
+		parts = append(parts, "runtime:"+string(p.Runtime))
+	}
+
+	labels := make([]string, 0, len(p.Labels))
+	for name, value := range p.Labels {
+		labels = append(labels, name+"="+value)
+	}
+	sort.Strings(labels)
+
+	return strings.Join(append(parts, labels...), ",")
+}
+
+// Validate checks that the placement names a known runtime and that its
+// labels are made of letters, digits, '-', '_' and '.'
+func (p Placement) Validate() error {
+	if p.Runtime != "" && !p.Runtime.IsValid() {
+		return fmt.Errorf("placement runtime must be %s or %s, got %q", WorkerRuntimeSimple, WorkerRuntimeContainer, p.Runtime)
+	}
+
+	for name, value := range p.Labels {
+		if !isLabelToken(name) || !isLabelToken(value) {
+			return fmt.Errorf("placement label %q=%q must be non-empty letters, digits, '-', '_' or '.'", name, value)
+		}
+	}
+
+	return nil
+}
+
+// isLabelToken reports whether s is a valid label name or value
+func isLabelToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Matches reports whether a worker with the given runtime and labels
+// satisfies the placement
+func (p *Placement) Matches(runtime WorkerRuntime, labels map[string]string) bool {
+	if p.IsZero() {
+		return true
+	}
+	if p.Runtime != "" && p.Runtime != runtime {
+		return false
+	}
+	for name, value := range p.Labels {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
 }
 
 // Invocation represents a function invocation request
 type Invocation struct {
-	ID          string           `json:"id" db:"id"`
-	FunctionID  string           `json:"function_id" db:"function_id"`
-	Payload     json.RawMessage  `json:"payload" db:"payload"`
-	Headers     map[string]string `json:"headers" db:"headers"`
-	Status      ExecutionStatus  `json:"status" db:"status"`
-	Result      json.RawMessage  `json:"result,omitempty" db:"result"`
-	Error       *ExecutionError  `json:"error,omitempty"`
-	Metrics     *ExecutionMetrics `json:"metrics,omitempty"`
-	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
-	StartedAt   *time.Time       `json:"started_at,omitempty" db:"started_at"`
-	CompletedAt *time.Time       `json:"completed_at,omitempty" db:"completed_at"`
+	ID              string            `json:"id" db:"id"`
+	FunctionID      string            `json:"function_id" db:"function_id"`
+	FunctionVersion int               `json:"function_version,omitempty" db:"function_version"` // Published version that ran; 0 for the function's current code
+	Payload         json.RawMessage   `json:"payload" db:"payload"`
+	Headers         map[string]string `json:"headers" db:"headers"`
+	Status          ExecutionStatus   `json:"status" db:"status"`
+	Priority        Priority          `json:"priority" db:"priority"`             // Execution queue lane
+	Placement       *Placement        `json:"placement,omitempty" db:"placement"` // Placement of the function when invoked; selects its execution queue
+	Result          json.RawMessage   `json:"result,omitempty" db:"result"`
+	Error           *ExecutionError   `json:"error,omitempty"`
+	Metrics         *ExecutionMetrics `json:"metrics,omitempty"`
+	Logs            []LogEntry        `json:"logs,omitempty" db:"logs"`
+	CreatedAt       time.Time         `json:"created_at" db:"created_at"`
+	RunAt           *time.Time        `json:"run_at,omitempty" db:"run_at"` // Earliest start of a delayed invocation
+	IdempotencyKey  string            `json:"idempotency_key,omitempty" db:"idempotency_key"`
+	IdempotencyHash string            `json:"-" db:"idempotency_hash"`                  // Hash of the payload submitted with the idempotency key
+	CallbackURL     string            `json:"callback_url,omitempty" db:"callback_url"` // Receives the invocation once it is finished
+	CallbackSecret  string            `json:"-" db:"callback_secret"`                   // Key for signing callback deliveries
+	ReplayOf        string            `json:"replay_of,omitempty" db:"replay_of"`       // Earlier invocation this one re-runs
+	StartedAt       *time.Time        `json:"started_at,omitempty" db:"started_at"`
+	CompletedAt     *time.Time        `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// InvocationAttempt records one execution attempt of an invocation
+type InvocationAttempt struct {
+	ID           string          `json:"id" db:"id"`
+	InvocationID string          `json:"invocation_id" db:"invocation_id"`
+	Attempt      int             `json:"attempt" db:"attempt"` // 1 for the first execution
+	WorkerID     string          `json:"worker_id,omitempty" db:"worker_id"`
+	Status       ExecutionStatus `json:"status" db:"status"`
+	Error        *ExecutionError `json:"error,omitempty"`
+	Duration     time.Duration   `json:"duration" db:"duration_ns"`
+	StartedAt    time.Time       `json:"started_at" db:"started_at"`
+	CompletedAt  time.Time       `json:"completed_at" db:"completed_at"`
+	RetryAt      *time.Time      `json:"retry_at,omitempty" db:"retry_at"` // When the next attempt runs, if the attempt is retried
 }
@@ -0,0 +1,113 @@
+package types
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Error types of failed executions that a retry policy can select
+const (
+	ErrorTypeRuntime = "RuntimeError" // The function returned an error or exited abnormally
+	ErrorTypeTimeout = "TimeoutError" // The function exceeded its timeout
+	ErrorTypeSystem  = "SystemError"  // The platform failed to run the function
+)
+
+// DefaultRetryPolicy applies to functions without a retry policy: failures
+// of the platform are retried, failures of the function are not
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+	Multiplier:     2,
+	Jitter:         0.2,
+	RetryOn:        []string{ErrorTypeSystem},
+}
+
+// RetryPolicy controls how failed executions of a function are retried.
+// The wait before retry n is InitialBackoff * Multiplier^(n-1), capped at
+// MaxBackoff, of which a Jitter fraction is randomized.
+type RetryPolicy struct {
+	MaxAttempts    int           `json:"max_attempts"`       // Attempts including the first; 1 disables retries
+	InitialBackoff time.Duration `json:"initial_backoff"`    // Wait before the first retry
+	MaxBackoff     time.Duration `json:"max_backoff"`        // Upper bound on the wait between attempts
+	Multiplier     float64       `json:"multiplier"`         // Growth of the wait per attempt; defaults to 2
+	Jitter         float64       `json:"jitter"`             // Fraction (0-1) of each wait that is randomized
+	RetryOn        []string      `json:"retry_on,omitempty"` // Error types that are retried; defaults to SystemError
+}
+
+// Validate checks that the policy's values are within range
+func (p RetryPolicy) Validate() error {
+	if p.MaxAttempts < 1 {
+		return fmt.Errorf("max_attempts must be at least 1")
+	}
+	if p.InitialBackoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("backoff must not be negative")
+	}
+	if p.MaxBackoff < p.InitialBackoff {
+		return fmt.Errorf("max_backoff must not be less than initial_backoff")
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return fmt.Errorf("multiplier must be at least 1")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("jitter must be between 0 and 1")
+	}
+
+	for _, errorType := range p.RetryOn {
+		switch errorType {
+		case ErrorTypeRuntime, ErrorTypeTimeout, ErrorTypeSystem:
+		default:
+			return fmt.Errorf("retry_on must contain only %s, %s or %s, got %q",
+				ErrorTypeRuntime, ErrorTypeTimeout, ErrorTypeSystem, errorType)
+		}
+	}
+
+	return nil
+}
+
+// Retryable reports whether a failed execution with the given error type
+// may be retried
+func (p RetryPolicy) Retryable(errorType string) bool {
+	retryOn := p.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = DefaultRetryPolicy.RetryOn
+	}
+
+	for _, t := range retryOn {
+		if t == errorType {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the wait after the given failed attempt (1 for the first)
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+
+	wait := float64(p.InitialBackoff)
+	for i := 1; i < attempt && wait < float64(p.MaxBackoff); i++ {
+		wait *= multiplier
+	}
+	if wait > float64(p.MaxBackoff) {
+		wait = float64(p.MaxBackoff)
+	}
+
+	// Spread retries of failures that happened together
+	wait -= wait * p.Jitter * rand.Float64()
+
+	return time.Duration(wait)
+}
+
+// EffectiveRetryPolicy returns the function's retry policy, or the default
+// if it has none
+func (c FunctionConfig) EffectiveRetryPolicy() RetryPolicy {
+	if c.Retry == nil {
+		return DefaultRetryPolicy
+	}
+	return *c.Retry
+}
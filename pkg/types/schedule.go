@@ -0,0 +1,23 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Schedule invokes a function with a static payload whenever its cron
+// expression matches
+type Schedule struct {
+	ID               string          `json:"id" db:"id"`
+	FunctionID       string          `json:"function_id" db:"function_id"`
+	Cron             string          `json:"cron" db:"cron"`
+	Timezone         string          `json:"timezone" db:"timezone"` // IANA name the cron expression is evaluated in
+	Payload          json.RawMessage `json:"payload,omitempty" db:"payload"`
+	Enabled          bool            `json:"enabled" db:"enabled"`
+	NextRunAt        *time.Time      `json:"next_run_at,omitempty" db:"next_run_at"` // Unset while disabled
+	LastRunAt        *time.Time      `json:"last_run_at,omitempty" db:"last_run_at"`
+	LastInvocationID string          `json:"last_invocation_id,omitempty" db:"last_invocation_id"`
+	LastError        string          `json:"last_error,omitempty" db:"last_error"` // Why the last run could not be enqueued
+	CreatedAt        time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,41 @@
+package types
+
+import "time"
+
+// FunctionVersion is an immutable snapshot of a function's code and
+// configuration, numbered per function name
+type FunctionVersion struct {
+	ID           string         `json:"id" db:"id"`
+	FunctionID   string         `json:"function_id" db:"function_id"` // Function the version was published from
+	FunctionName string         `json:"function_name" db:"function_name"`
+	Version      int            `json:"version" db:"version"`
+	Runtime      RuntimeType    `json:"runtime" db:"runtime"`
+	Handler      string         `json:"handler" db:"handler"`
+	Code         FunctionCode   `json:"code"`
+	Config       FunctionConfig `json:"config"`
+	Description  string         `json:"description,omitempty" db:"description"`
+	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
+}
+
+// FunctionAlias is a named, movable pointer to a function version
+type FunctionAlias struct {
+	FunctionName string        `json:"function_name" db:"function_name"`
+	Name         string        `json:"name" db:"name"`
+	Version      int           `json:"version" db:"version"`
+	Description  string        `json:"description,omitempty" db:"description"`
+	Routing      *AliasRouting `json:"routing,omitempty"` // Optional split of traffic to a second version
+	CreatedAt    time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// AliasRouting sends a percentage of an alias's invocations to an
+// additional version, e.g. a canary. The weight drops to zero when the
+// canary is rolled back; the routing is kept so the rollback can be
+// inspected.
+type AliasRouting struct {
+	Version        int        `json:"version" db:"routing_version"`
+	Weight         int        `json:"weight" db:"routing_weight"` // Percentage of invocations routed to Version
+	StartedAt      time.Time  `json:"started_at" db:"routing_started_at"`
+	RolledBackAt   *time.Time `json:"rolled_back_at,omitempty" db:"routing_rolled_back_at"`
+	RollbackReason string     `json:"rollback_reason,omitempty" db:"routing_rollback_reason"`
+}
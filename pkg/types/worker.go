@@ -0,0 +1,52 @@
+package types
+
+import "time"
+
+// Worker is a worker process registered with the platform. Workers
+// register when they start, send heartbeats while they run and
+// deregister when they stop.
+type Worker struct {
+	ID              string             `json:"id" db:"id"`
+	Hostname        string             `json:"hostname" db:"hostname"`
+	Runtime         WorkerRuntime      `json:"runtime" db:"runtime"`
+	Capabilities    WorkerCapabilities `json:"capabilities" db:"capabilities"`
+	Slots           int                `json:"slots" db:"slots"`         // Executions it runs concurrently
+	InFlight        int                `json:"in_flight" db:"in_flight"` // Executions in progress at the last heartbeat
+	Labels          map[string]string  `json:"labels,omitempty" db:"labels"`
+	Draining        bool               `json:"draining" db:"draining"` // Asked to stop taking messages
+	Drained         bool               `json:"drained" db:"drained"`   // Stopped taking messages and finished its executions
+	Status          WorkerStatus       `json:"status"`                 // Derived from the drain flags and LastHeartbeatAt
+	StartedAt       time.Time          `json:"started_at" db:"started_at"`
+	LastHeartbeatAt time.Time          `json:"last_heartbeat_at" db:"last_heartbeat_at"`
+}
+
+// WorkerRuntime is how a worker runs functions
+type WorkerRuntime string
+
+const (
+	WorkerRuntimeSimple    WorkerRuntime = "simple"    // Processes on the worker's host
+	WorkerRuntimeContainer WorkerRuntime = "container" // Docker containers
+)
+
+// IsValid checks if the worker runtime is supported
+func (r WorkerRuntime) IsValid() bool {
+	return r == WorkerRuntimeSimple || r == WorkerRuntimeContainer
+}
+
+// WorkerCapabilities describes the runtime a worker executes functions with
+type WorkerCapabilities struct {
+	Language   string        `json:"language"`
+	Version    string        `json:"version"`
+	MaxTimeout time.Duration `json:"max_timeout"`
+	MaxMemory  int64         `json:"max_memory"` // Bytes
+}
+
+// WorkerStatus represents whether a worker takes messages
+type WorkerStatus string
+
+const (
+	WorkerActive   WorkerStatus = "active"
+	WorkerDraining WorkerStatus = "draining" // Finishing its executions without taking new ones
+	WorkerDrained  WorkerStatus = "drained"  // Draining with no executions left; safe to stop
+	WorkerOffline  WorkerStatus = "offline"  // No heartbeat within the timeout
+)
@@ -1,14 +1,21 @@
 #!/bin/sh
 set -e
 
-# Function code is mounted at /app/function
+# Function code and the generated bootstrap are mounted at /app/function.
+# The invocation request arrives on stdin and the response is written to
+# stdout by the bootstrap; function output goes to stderr.
 cd /app/function
 
+# Functions compiled at deploy time ship a static binary
+if [ -x "handler" ]; then
+    exec ./handler
+fi
+
 # Check if main.go exists
-if [ ! -f "main.go" ]; then
-    echo "Error: main.go not found in /app/function"
+if [ ! -f "main.go" ] || [ ! -f "faas_bootstrap.go" ]; then
+    echo "Error: main.go or faas_bootstrap.go not found in /app/function" >&2
     exit 1
 fi
 
-# Execute the Go function
-exec go run main.go
+# Execute the Go function through its bootstrap
+exec go run main.go faas_bootstrap.go
@@ -1,14 +1,17 @@
 #!/bin/sh
 set -e
 
-# Function code is mounted at /app/function
+# Function code and the bootstrap are mounted at /app/function.
+# The invocation request arrives on stdin and the response is written to
+# stdout by the bootstrap; function output goes to stderr.
 cd /app/function
 
-# Check if main.js exists
-if [ ! -f "main.js" ]; then
-    echo "Error: main.js not found in /app/function"
+# Check the bootstrap exists. Archives may keep the handler module
+# anywhere, so a missing module is reported by the bootstrap.
+if [ ! -f "faas_bootstrap.js" ]; then
+    echo "Error: faas_bootstrap.js not found in /app/function" >&2
     exit 1
 fi
 
-# Execute the Node.js function
-exec node main.js
+# Execute the Node.js function through its bootstrap
+exec node faas_bootstrap.js
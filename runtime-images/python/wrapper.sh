@@ -1,14 +1,17 @@
 #!/bin/sh
 set -e
 
-# Function code is mounted at /app/function
+# Function code and the bootstrap are mounted at /app/function.
+# The invocation request arrives on stdin and the response is written to
+# stdout by the bootstrap; function output goes to stderr.
 cd /app/function
 
-# Check if main.py exists
-if [ ! -f "main.py" ]; then
-    echo "Error: main.py not found in /app/function"
+# Check the bootstrap exists. Archives may keep the handler module
+# anywhere, so a missing module is reported by the bootstrap.
+if [ ! -f "faas_bootstrap.py" ]; then
+    echo "Error: faas_bootstrap.py not found in /app/function" >&2
     exit 1
 fi
 
-# Execute the Python function
-exec python3 main.py
+# Execute the Python function through its bootstrap
+exec python3 faas_bootstrap.py